@@ -76,6 +76,18 @@ func WithNoSyncingTask() SegmentFilter {
 	}
 }
 
+// WithAnyOf returns a filter matching segments which satisfy any of provided filters.
+func WithAnyOf(filters ...SegmentFilter) SegmentFilter {
+	return func(info *SegmentInfo) bool {
+		for _, filter := range filters {
+			if filter(info) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
 type SegmentAction func(info *SegmentInfo)
 
 func UpdateState(state commonpb.SegmentState) SegmentAction {
@@ -58,6 +58,16 @@ func (s *SegmentFilterSuite) TestFilters() {
 	s.False(filter(info))
 	info.startPosRecorded = false
 	s.True(filter(info))
+
+	filter = WithAnyOf(WithSegmentState(commonpb.SegmentState_Growing), WithImporting())
+	info.state = commonpb.SegmentState_Flushed
+	info.importing = false
+	s.False(filter(info))
+	info.importing = true
+	s.True(filter(info))
+	info.state = commonpb.SegmentState_Growing
+	info.importing = false
+	s.True(filter(info))
 }
 
 func TestFilters(t *testing.T) {
@@ -74,6 +74,14 @@ func (bfs *BloomFilterSet) Roll() {
 	}
 }
 
+// AddHistory appends loaded pk statistics into history entries.
+func (bfs *BloomFilterSet) AddHistory(entries ...*storage.PkStatistics) {
+	bfs.mut.Lock()
+	defer bfs.mut.Unlock()
+
+	bfs.history = append(bfs.history, entries...)
+}
+
 func (bfs *BloomFilterSet) GetHistory() []*storage.PkStatistics {
 	bfs.mut.Lock()
 	defer bfs.mut.Unlock()
@@ -19,6 +19,7 @@ package metacache
 import (
 	"testing"
 
+	"github.com/bits-and-blooms/bloom/v3"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
@@ -88,6 +89,21 @@ func (s *BloomFilterSetSuite) TestRoll() {
 	s.Equal(1, len(history), "history shall have one entry after empty roll")
 }
 
+func (s *BloomFilterSetSuite) TestAddHistory() {
+	ids := []int64{1, 2, 3, 4, 5}
+	stats := &storage.PkStatistics{
+		PkFilter: bloom.NewWithEstimates(storage.BloomFilterSize, storage.MaxBloomFalsePositive),
+	}
+	s.Require().NoError(stats.UpdatePKRange(s.GetFieldData(ids)))
+
+	s.bfs.AddHistory(stats)
+
+	s.Equal(1, len(s.bfs.GetHistory()))
+	for _, id := range ids {
+		s.True(s.bfs.PkExists(storage.NewInt64PrimaryKey(id)), "pk shall exist after history added")
+	}
+}
+
 func TestBloomFilterSet(t *testing.T) {
 	suite.Run(t, new(BloomFilterSetSuite))
 }
@@ -35,6 +35,8 @@ type MetaCache interface {
 	Collection() int64
 	// Schema returns collection schema.
 	Schema() *schemapb.CollectionSchema
+	// UpdateSchema replaces collection schema.
+	UpdateSchema(schema *schemapb.CollectionSchema)
 	// AddSegment adds a segment from segment info.
 	AddSegment(segInfo *datapb.SegmentInfo, factory PkStatsFactory, actions ...SegmentAction)
 	// UpdateSegments applies action to segment(s) satisfy the provided filters.
@@ -95,9 +97,20 @@ func (c *metaCacheImpl) Collection() int64 {
 
 // Schema returns collection schema.
 func (c *metaCacheImpl) Schema() *schemapb.CollectionSchema {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	return c.schema
 }
 
+// UpdateSchema replaces collection schema.
+func (c *metaCacheImpl) UpdateSchema(schema *schemapb.CollectionSchema) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.schema = schema
+}
+
 // AddSegment adds a segment from segment info.
 func (c *metaCacheImpl) AddSegment(segInfo *datapb.SegmentInfo, factory PkStatsFactory, actions ...SegmentAction) {
 	segment := NewSegmentInfo(segInfo, factory(segInfo))
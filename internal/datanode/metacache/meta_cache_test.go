@@ -100,6 +100,15 @@ func (s *MetaCacheSuite) TestMetaInfo() {
 	s.Equal(s.collSchema, s.cache.Schema())
 }
 
+func (s *MetaCacheSuite) TestUpdateSchema() {
+	schema := &schemapb.CollectionSchema{
+		Name:   "updated_collection",
+		Fields: append([]*schemapb.FieldSchema{{FieldID: 102, DataType: schemapb.DataType_Int64, Name: "extra"}}, s.collSchema.GetFields()...),
+	}
+	s.cache.UpdateSchema(schema)
+	s.Same(schema, s.cache.Schema())
+}
+
 func (s *MetaCacheSuite) TestCompactSegments() {
 	for i, seg := range s.newSegments {
 		// compaction from flushed[i], unflushed[i] and invalidSeg to new[i]
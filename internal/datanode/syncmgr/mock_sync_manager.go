@@ -3,13 +3,12 @@
 package syncmgr
 
 import (
-	context "context"
-
 	conc "github.com/milvus-io/milvus/pkg/util/conc"
 
-	mock "github.com/stretchr/testify/mock"
+	context "context"
 
 	msgpb "github.com/milvus-io/milvus-proto/go-api/v2/msgpb"
+	mock "github.com/stretchr/testify/mock"
 )
 
 // MockSyncManager is an autogenerated mock type for the SyncManager type
@@ -112,6 +111,50 @@ func (_c *MockSyncManager_GetEarliestPosition_Call) RunAndReturn(run func(string
 	return _c
 }
 
+// GetSegmentPositions provides a mock function with given fields: channel
+func (_m *MockSyncManager) GetSegmentPositions(channel string) map[int64]*msgpb.MsgPosition {
+	ret := _m.Called(channel)
+
+	var r0 map[int64]*msgpb.MsgPosition
+	if rf, ok := ret.Get(0).(func(string) map[int64]*msgpb.MsgPosition); ok {
+		r0 = rf(channel)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[int64]*msgpb.MsgPosition)
+		}
+	}
+
+	return r0
+}
+
+// MockSyncManager_GetSegmentPositions_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetSegmentPositions'
+type MockSyncManager_GetSegmentPositions_Call struct {
+	*mock.Call
+}
+
+// GetSegmentPositions is a helper method to define mock.On call
+//   - channel string
+func (_e *MockSyncManager_Expecter) GetSegmentPositions(channel interface{}) *MockSyncManager_GetSegmentPositions_Call {
+	return &MockSyncManager_GetSegmentPositions_Call{Call: _e.mock.On("GetSegmentPositions", channel)}
+}
+
+func (_c *MockSyncManager_GetSegmentPositions_Call) Run(run func(channel string)) *MockSyncManager_GetSegmentPositions_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *MockSyncManager_GetSegmentPositions_Call) Return(_a0 map[int64]*msgpb.MsgPosition) *MockSyncManager_GetSegmentPositions_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockSyncManager_GetSegmentPositions_Call) RunAndReturn(run func(string) map[int64]*msgpb.MsgPosition) *MockSyncManager_GetSegmentPositions_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // SyncData provides a mock function with given fields: ctx, task
 func (_m *MockSyncManager) SyncData(ctx context.Context, task Task) *conc.Future[error] {
 	ret := _m.Called(ctx, task)
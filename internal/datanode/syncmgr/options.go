@@ -91,6 +91,11 @@ func (t *SyncTask) WithDrop() *SyncTask {
 	return t
 }
 
+func (t *SyncTask) WithPathPrefix(prefix string) *SyncTask {
+	t.pathPrefix = prefix
+	return t
+}
+
 func (t *SyncTask) WithMetaCache(metacache metacache.MetaCache) *SyncTask {
 	t.metacache = metacache
 	return t
@@ -111,6 +116,11 @@ func (t *SyncTask) WithFailureCallback(callback func(error)) *SyncTask {
 	return t
 }
 
+func (t *SyncTask) WithSuccessCallback(callback func()) *SyncTask {
+	t.successCallback = callback
+	return t
+}
+
 func (t *SyncTask) WithBatchSize(batchSize int64) *SyncTask {
 	t.batchSize = batchSize
 	return t
@@ -120,3 +130,14 @@ func (t *SyncTask) WithLevel(level datapb.SegmentLevel) *SyncTask {
 	t.level = level
 	return t
 }
+
+func (t *SyncTask) WithPriority(priority int) *SyncTask {
+	t.priority = priority
+	return t
+}
+
+// WithTraceID tags the task with trace id of the operation buffering synced data.
+func (t *SyncTask) WithTraceID(traceID string) *SyncTask {
+	t.traceID = traceID
+	return t
+}
@@ -46,6 +46,8 @@ type SyncManager interface {
 	SyncData(ctx context.Context, task Task) *conc.Future[error]
 	// GetEarliestPosition returns the earliest position (normally start position) of the processing sync task of provided channel.
 	GetEarliestPosition(channel string) (int64, *msgpb.MsgPosition)
+	// GetSegmentPositions returns the earliest start position of processing sync tasks of each segment of provided channel.
+	GetSegmentPositions(channel string) map[int64]*msgpb.MsgPosition
 	// Block allows caller to block tasks of provided segment id.
 	// normally used by compaction task.
 	// if levelzero delta policy is enabled, this shall be an empty operation.
@@ -140,6 +142,20 @@ func (mgr *syncManager) GetEarliestPosition(channel string) (int64, *msgpb.MsgPo
 	return segmentID, cp
 }
 
+func (mgr *syncManager) GetSegmentPositions(channel string) map[int64]*msgpb.MsgPosition {
+	positions := make(map[int64]*msgpb.MsgPosition)
+	mgr.tasks.Range(func(_ string, task Task) bool {
+		if task.StartPosition() == nil || task.ChannelName() != channel {
+			return true
+		}
+		if cp, ok := positions[task.SegmentID()]; !ok || task.StartPosition().GetTimestamp() < cp.GetTimestamp() {
+			positions[task.SegmentID()] = task.StartPosition()
+		}
+		return true
+	})
+	return positions
+}
+
 func (mgr *syncManager) Block(segmentID int64) {
 	mgr.keyLock.Lock(segmentID)
 }
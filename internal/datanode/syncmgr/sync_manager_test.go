@@ -2,6 +2,7 @@ package syncmgr
 
 import (
 	"context"
+	"fmt"
 	"math/rand"
 	"strconv"
 	"testing"
@@ -255,6 +256,31 @@ func (s *SyncManagerSuite) TestBlock() {
 	<-sig
 }
 
+func (s *SyncManagerSuite) TestGetSegmentPositions() {
+	manager, err := NewSyncManager(s.chunkManager, s.allocator)
+	s.NoError(err)
+	syncMgr, ok := manager.(*syncManager)
+	s.Require().True(ok)
+
+	addTask := func(segmentID int64, channel string, ts uint64) {
+		task := NewSyncTask().WithSegmentID(segmentID).
+			WithChannelName(channel).
+			WithStartPosition(&msgpb.MsgPosition{ChannelName: channel, Timestamp: ts})
+		syncMgr.tasks.Insert(fmt.Sprintf("%d-%d", segmentID, ts), task)
+	}
+	addTask(1001, s.channelName, 200)
+	addTask(1001, s.channelName, 100)
+	addTask(1002, s.channelName, 300)
+	addTask(1003, "other_channel", 50)
+	// tasks without start position, e.g. delete only, are ignored
+	syncMgr.tasks.Insert("1004-0", NewSyncTask().WithSegmentID(1004).WithChannelName(s.channelName))
+
+	positions := manager.GetSegmentPositions(s.channelName)
+	s.Len(positions, 2)
+	s.EqualValues(100, positions[1001].GetTimestamp())
+	s.EqualValues(300, positions[1002].GetTimestamp())
+}
+
 func (s *SyncManagerSuite) TestResizePool() {
 	manager, err := NewSyncManager(s.chunkManager, s.allocator)
 	s.NoError(err)
@@ -43,12 +43,18 @@ type SyncTask struct {
 	// not the total num of rows of segemnt
 	batchSize int64
 	level     datapb.SegmentLevel
+	// priority is the flush urgency of this task, larger value shall be submitted earlier.
+	priority int
 
 	tsFrom typeutil.Timestamp
 	tsTo   typeutil.Timestamp
 
 	isFlush bool
 	isDrop  bool
+	// pathPrefix overrides chunk manager root path of written logs if not empty.
+	pathPrefix string
+	// traceID is the trace id of the operation buffering synced data, empty if unknown.
+	traceID string
 
 	metacache  metacache.MetaCache
 	metaWriter MetaWriter
@@ -62,15 +68,20 @@ type SyncTask struct {
 	writeRetryOpts []retry.Option
 
 	failureCallback func(err error)
+	successCallback func()
 }
 
 func (t *SyncTask) getLogger() *log.MLogger {
-	return log.Ctx(context.Background()).With(
+	logger := log.Ctx(context.Background()).With(
 		zap.Int64("collectionID", t.collectionID),
 		zap.Int64("partitionID", t.partitionID),
 		zap.Int64("segmentID", t.segmentID),
 		zap.String("channel", t.channelName),
 	)
+	if t.traceID != "" {
+		logger = logger.With(zap.String("traceID", t.traceID))
+	}
+	return logger
 }
 
 func (t *SyncTask) handleError(err error) {
@@ -144,6 +155,10 @@ func (t *SyncTask) Run() error {
 
 	t.metacache.UpdateSegments(metacache.MergeSegmentAction(actions...), metacache.WithSegmentIDs(t.segment.SegmentID()))
 
+	if t.successCallback != nil {
+		t.successCallback()
+	}
+
 	log.Info("task done")
 	return nil
 }
@@ -183,7 +198,7 @@ func (t *SyncTask) serializeDeleteData() error {
 	data := &datapb.Binlog{}
 
 	blobKey := metautil.JoinIDPath(t.collectionID, t.partitionID, t.segmentID, logID)
-	blobPath := path.Join(t.chunkManager.RootPath(), common.SegmentDeltaLogPath, blobKey)
+	blobPath := path.Join(t.rootPath(), common.SegmentDeltaLogPath, blobKey)
 
 	t.segmentData[blobPath] = value
 	data.LogSize = int64(len(blob.Value))
@@ -228,7 +243,7 @@ func (t *SyncTask) serializeBinlog() error {
 
 		k := metautil.JoinIDPath(t.collectionID, t.partitionID, t.segmentID, fieldID, logidx)
 		// [rootPath]/[insert_log]/key
-		key := path.Join(t.chunkManager.RootPath(), common.SegmentInsertLogPath, k)
+		key := path.Join(t.rootPath(), common.SegmentInsertLogPath, k)
 		t.segmentData[key] = blob.GetValue()
 		t.appendBinlog(fieldID, &datapb.Binlog{
 			EntriesNum:    blob.RowNum,
@@ -299,7 +314,7 @@ func (t *SyncTask) serializeMergedPkStats(fieldID int64, pkType schemapb.DataTyp
 
 func (t *SyncTask) convertBlob2StatsBinlog(blob *storage.Blob, fieldID, logID int64, rowNum int64) {
 	key := metautil.JoinIDPath(t.collectionID, t.partitionID, t.segmentID, fieldID, logID)
-	key = path.Join(t.chunkManager.RootPath(), common.SegmentStatslogPath, key)
+	key = path.Join(t.rootPath(), common.SegmentStatslogPath, key)
 
 	value := blob.GetValue()
 	t.segmentData[key] = value
@@ -375,6 +390,14 @@ func (t *SyncTask) writeMeta() error {
 	return t.metaWriter.UpdateSync(t)
 }
 
+// rootPath returns the root path of written logs.
+func (t *SyncTask) rootPath() string {
+	if t.pathPrefix != "" {
+		return t.pathPrefix
+	}
+	return t.chunkManager.RootPath()
+}
+
 func (t *SyncTask) getInCodec() *storage.InsertCodec {
 	meta := &etcdpb.CollectionMeta{
 		Schema: t.schema,
@@ -399,3 +422,24 @@ func (t *SyncTask) StartPosition() *msgpb.MsgPosition {
 func (t *SyncTask) ChannelName() string {
 	return t.channelName
 }
+
+func (t *SyncTask) TimeRange() (typeutil.Timestamp, typeutil.Timestamp) {
+	return t.tsFrom, t.tsTo
+}
+
+func (t *SyncTask) Priority() int {
+	return t.priority
+}
+
+func (t *SyncTask) PathPrefix() string {
+	return t.pathPrefix
+}
+
+func (t *SyncTask) TraceID() string {
+	return t.traceID
+}
+
+// InsertBinlogs returns insert binlogs written by task, keyed by field id.
+func (t *SyncTask) InsertBinlogs() map[int64]*datapb.FieldBinlog {
+	return t.insertBinlogs
+}
@@ -2,6 +2,7 @@ package syncmgr
 
 import (
 	"math/rand"
+	"strings"
 	"testing"
 	"time"
 
@@ -216,6 +217,44 @@ func (s *SyncTaskSuite) TestRunNormal() {
 		s.NoError(err)
 	})
 
+	s.Run("with_success_callback", func() {
+		var called bool
+		task := s.getSuiteSyncTask()
+		task.WithInsertData(s.getInsertBuffer()).WithDeleteData(s.getDeleteBuffer())
+		task.WithTimeRange(50, 100)
+		task.WithMetaWriter(BrokerMetaWriter(s.broker))
+		task.WithCheckpoint(&msgpb.MsgPosition{
+			ChannelName: s.channelName,
+			MsgID:       []byte{1, 2, 3, 4},
+			Timestamp:   100,
+		})
+		task.WithSuccessCallback(func() { called = true })
+
+		err := task.Run()
+		s.NoError(err)
+		s.True(called)
+	})
+
+	s.Run("with_path_prefix", func() {
+		task := s.getSuiteSyncTask()
+		task.WithInsertData(s.getInsertBuffer()).WithDeleteData(s.getDeleteBuffer())
+		task.WithTimeRange(50, 100)
+		task.WithMetaWriter(BrokerMetaWriter(s.broker))
+		task.WithCheckpoint(&msgpb.MsgPosition{
+			ChannelName: s.channelName,
+			MsgID:       []byte{1, 2, 3, 4},
+			Timestamp:   100,
+		})
+		task.WithPathPrefix("cold-bucket")
+
+		err := task.Run()
+		s.NoError(err)
+		s.NotEmpty(task.segmentData)
+		for key := range task.segmentData {
+			s.True(strings.HasPrefix(key, "cold-bucket/"), key)
+		}
+	})
+
 	s.Run("with_zero_numrow_insertdata", func() {
 		task := s.getSuiteSyncTask()
 		task.WithInsertData(s.getEmptyInsertBuffer())
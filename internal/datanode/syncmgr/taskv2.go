@@ -47,6 +47,7 @@ import (
 type SyncTaskV2 struct {
 	*SyncTask
 	arrowSchema    *arrow.Schema
+	record         arrow.Record
 	reader         array.RecordReader
 	statsBlob      *storage.Blob
 	deleteReader   array.RecordReader
@@ -54,6 +55,7 @@ type SyncTaskV2 struct {
 	space          *milvus_storage.Space
 
 	failureCallback func(err error)
+	successCallback func()
 }
 
 func (t *SyncTaskV2) getLogger() *log.MLogger {
@@ -125,6 +127,10 @@ func (t *SyncTaskV2) Run() error {
 
 	t.metacache.UpdateSegments(metacache.MergeSegmentAction(actions...), metacache.WithSegmentIDs(t.segmentID))
 
+	if t.successCallback != nil {
+		t.successCallback()
+	}
+
 	return nil
 }
 
@@ -133,14 +139,17 @@ func (t *SyncTaskV2) serializeInsertData() error {
 		return nil
 	}
 
-	b := array.NewRecordBuilder(memory.DefaultAllocator, t.arrowSchema)
-	defer b.Release()
+	// record built while buffering could be written directly
+	rec := t.record
+	if rec == nil {
+		b := array.NewRecordBuilder(memory.DefaultAllocator, t.arrowSchema)
+		defer b.Release()
 
-	if err := buildRecord(b, t.insertData, t.schema.Fields); err != nil {
-		return err
+		if err := BuildRecord(b, t.insertData, t.schema.Fields); err != nil {
+			return err
+		}
+		rec = b.NewRecord()
 	}
-
-	rec := b.NewRecord()
 	defer rec.Release()
 
 	itr, err := array.NewRecordReader(t.arrowSchema, []arrow.Record{rec})
@@ -337,7 +346,8 @@ func (t *SyncTaskV2) writeMeta() error {
 	return t.metaWriter.UpdateSyncV2(t)
 }
 
-func buildRecord(b *array.RecordBuilder, data *storage.InsertData, fields []*schemapb.FieldSchema) error {
+// BuildRecord appends insert data into record builder, fields shall be in the same order as arrow schema of builder.
+func BuildRecord(b *array.RecordBuilder, data *storage.InsertData, fields []*schemapb.FieldSchema) error {
 	if data == nil {
 		log.Info("no buffer data to flush")
 		return nil
@@ -563,6 +573,11 @@ func (t *SyncTaskV2) WithFailureCallback(callback func(error)) *SyncTaskV2 {
 	return t
 }
 
+func (t *SyncTaskV2) WithSuccessCallback(callback func()) *SyncTaskV2 {
+	t.successCallback = callback
+	return t
+}
+
 func (t *SyncTaskV2) WithBatchSize(batchSize int64) *SyncTaskV2 {
 	t.batchSize = batchSize
 	return t
@@ -573,6 +588,13 @@ func (t *SyncTaskV2) WithSpace(space *milvus_storage.Space) *SyncTaskV2 {
 	return t
 }
 
+// WithRecord sets the arrow record of insert data, which is written instead of converting insert data.
+// The task takes ownership of the record.
+func (t *SyncTaskV2) WithRecord(rec arrow.Record) *SyncTaskV2 {
+	t.record = rec
+	return t
+}
+
 func (t *SyncTaskV2) WithArrowSchema(arrowSchema *arrow.Schema) *SyncTaskV2 {
 	t.arrowSchema = arrowSchema
 	return t
@@ -582,3 +604,13 @@ func (t *SyncTaskV2) WithLevel(level datapb.SegmentLevel) *SyncTaskV2 {
 	t.level = level
 	return t
 }
+
+func (t *SyncTaskV2) WithPriority(priority int) *SyncTaskV2 {
+	t.priority = priority
+	return t
+}
+
+func (t *SyncTaskV2) WithTraceID(traceID string) *SyncTaskV2 {
+	t.traceID = traceID
+	return t
+}
@@ -319,7 +319,7 @@ func (s *SyncTaskSuiteV2) TestBuildRecord() {
 		},
 	}
 
-	err = buildRecord(b, data, fieldSchemas)
+	err = BuildRecord(b, data, fieldSchemas)
 	s.NoError(err)
 	s.EqualValues(2, b.NewRecord().NumRows())
 }
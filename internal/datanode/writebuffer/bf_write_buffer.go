@@ -1,6 +1,10 @@
 package writebuffer
 
 import (
+	"context"
+
+	"go.uber.org/atomic"
+
 	"github.com/milvus-io/milvus-proto/go-api/v2/commonpb"
 	"github.com/milvus-io/milvus-proto/go-api/v2/msgpb"
 	"github.com/milvus-io/milvus/internal/datanode/metacache"
@@ -15,40 +19,83 @@ type bfWriteBuffer struct {
 
 	syncMgr   syncmgr.SyncManager
 	metacache metacache.MetaCache
+
+	// falsePositiveDeletes counts buffered deletes matching bloom filter without corresponding insert.
+	falsePositiveDeletes atomic.Int64
 }
 
 func NewBFWriteBuffer(channel string, metacache metacache.MetaCache, storageV2Cache *metacache.StorageV2Cache, syncMgr syncmgr.SyncManager, option *writeBufferOption) (WriteBuffer, error) {
-	return &bfWriteBuffer{
+	wb := &bfWriteBuffer{
 		writeBufferBase: newWriteBufferBase(channel, metacache, storageV2Cache, syncMgr, option),
 		syncMgr:         syncMgr,
-	}, nil
+	}
+	wb.startAutoSync(wb.syncAndCleanup)
+	return wb, nil
 }
 
 func (wb *bfWriteBuffer) BufferData(insertMsgs []*msgstream.InsertMsg, deleteMsgs []*msgstream.DeleteMsg, startPos, endPos *msgpb.MsgPosition) error {
+	return wb.BufferDataGrouped(groupInsertMsgs(insertMsgs), map[int64][]*msgstream.DeleteMsg{0: deleteMsgs}, startPos, endPos)
+}
+
+func (wb *bfWriteBuffer) BufferDataGrouped(insertBySegment map[int64][]*msgstream.InsertMsg, deleteBySegment map[int64][]*msgstream.DeleteMsg, startPos, endPos *msgpb.MsgPosition) error {
+	// acquire quota before lock, blocking mode shall not block other operations
+	if err := wb.ingestLimit.acquire(ingestBytes(insertBySegment, deleteBySegment), wb.closeCh); err != nil {
+		return err
+	}
+
 	wb.mut.Lock()
 	defer wb.mut.Unlock()
 
+	if wb.closed {
+		return ErrBufferClosed
+	}
+
+	return wb.bufferDataGrouped(insertBySegment, deleteBySegment, startPos, endPos)
+}
+
+func (wb *bfWriteBuffer) BufferDataSeq(insertMsgs []*msgstream.InsertMsg, deleteMsgs []*msgstream.DeleteMsg, startPos, endPos *msgpb.MsgPosition, seq uint64) error {
+	insertBySegment := groupInsertMsgs(insertMsgs)
+	if err := wb.ingestLimit.acquire(ingestBytes(insertBySegment, map[int64][]*msgstream.DeleteMsg{0: deleteMsgs}), wb.closeCh); err != nil {
+		return err
+	}
+
+	wb.mut.Lock()
+	defer wb.mut.Unlock()
+
+	if wb.closed {
+		return ErrBufferClosed
+	}
+
+	if err := wb.checkSequence(insertBySegment, seq); err != nil {
+		return err
+	}
+	err := wb.bufferDataGrouped(insertBySegment, map[int64][]*msgstream.DeleteMsg{0: deleteMsgs}, startPos, endPos)
+	if err != nil {
+		return err
+	}
+	wb.acceptSequence(insertBySegment, seq)
+	return nil
+}
+
+// bufferDataGrouped buffers grouped dml data, caller shall hold the lock.
+func (wb *bfWriteBuffer) bufferDataGrouped(insertBySegment map[int64][]*msgstream.InsertMsg, deleteBySegment map[int64][]*msgstream.DeleteMsg, startPos, endPos *msgpb.MsgPosition) error {
+	ctx, sp := wb.startSpan(msgsTraceCtx(insertBySegment), "WriteBuffer-BufferData")
+	defer sp.End()
+
 	// process insert msgs
-	pkData, err := wb.bufferInsert(insertMsgs, startPos, endPos)
+	pkData, err := wb.bufferInsert(insertBySegment, startPos, endPos)
 	if err != nil {
 		return err
 	}
 
 	// update pk oracle
-	for segmentID, dataList := range pkData {
-		segments := wb.metaCache.GetSegmentsBy(metacache.WithSegmentIDs(segmentID))
-		for _, segment := range segments {
-			for _, fieldData := range dataList {
-				err := segment.GetBloomFilterSet().UpdatePKRange(fieldData)
-				if err != nil {
-					return err
-				}
-			}
-		}
+	if err := wb.updatePKOracle(pkData); err != nil {
+		return err
 	}
 
 	// distribute delete msg
-	for _, delMsg := range deleteMsgs {
+	bufferedPks := make(map[int64]typeutil.Set[any])
+	for _, delMsg := range flattenDeleteMsgs(deleteBySegment) {
 		pks := storage.ParseIDs2PrimaryKeys(delMsg.GetPrimaryKeys())
 		segments := wb.metaCache.GetSegmentsBy(metacache.WithPartitionID(delMsg.PartitionID),
 			metacache.WithSegmentState(commonpb.SegmentState_Growing, commonpb.SegmentState_Flushing, commonpb.SegmentState_Flushed))
@@ -62,6 +109,9 @@ func (wb *bfWriteBuffer) BufferData(insertMsgs []*msgstream.InsertMsg, deleteMsg
 				if segment.GetBloomFilterSet().PkExists(pk) {
 					deletePks = append(deletePks, pk)
 					deleteTss = append(deleteTss, delMsg.GetTimestamps()[idx])
+					if wb.isFalsePositive(segment, pk, bufferedPks) {
+						wb.falsePositiveDeletes.Inc()
+					}
 				}
 			}
 			if len(deletePks) > 0 {
@@ -73,8 +123,69 @@ func (wb *bfWriteBuffer) BufferData(insertMsgs []*msgstream.InsertMsg, deleteMsg
 	// update buffer last checkpoint
 	wb.checkpoint = endPos
 
-	_ = wb.triggerSync()
+	return wb.syncAndCleanup(ctx)
+}
+
+func (wb *bfWriteBuffer) BufferRows(segmentID int64, fill func(builder InsertBuilder) error, startPos, endPos *msgpb.MsgPosition) error {
+	ctx, sp := wb.startSpan(context.Background(), "WriteBuffer-BufferRows")
+	defer sp.End()
+
+	// rows are buffered with segment lock, only checkpoint update & sync require the write lock
+	if err := wb.bufferRows(segmentID, fill, startPos, endPos); err != nil {
+		return err
+	}
+
+	wb.mut.Lock()
+	defer wb.mut.Unlock()
+
+	if wb.closed {
+		return ErrBufferClosed
+	}
+
+	// update buffer last checkpoint
+	wb.advanceCheckpoint(endPos)
+
+	return wb.syncAndCleanup(ctx)
+}
+
+// syncAndCleanup triggers sync and removes compacted segments, caller shall hold the lock.
+func (wb *bfWriteBuffer) syncAndCleanup(ctx context.Context) error {
+	if _, err := wb.triggerSync(ctx); err != nil {
+		return err
+	}
 
 	wb.cleanupCompactedSegments()
 	return nil
 }
+
+// BFFalsePositiveDeletes returns the number of buffered deletes which matched bloom filter
+// but found no corresponding insert.
+func (wb *bfWriteBuffer) BFFalsePositiveDeletes() int64 {
+	return wb.falsePositiveDeletes.Load()
+}
+
+// isFalsePositive checks whether pk matching bloom filter is absent from inserted data of segment.
+// Only segments without any synced or syncing data are checked, since buffered insert data holds all of their pks.
+// cache holds the buffered pk values of checked segments, caller shall hold the lock.
+func (wb *bfWriteBuffer) isFalsePositive(segment *metacache.SegmentInfo, pk storage.PrimaryKey, cache map[int64]typeutil.Set[any]) bool {
+	// data yielded to unfinished sync task is neither buffered nor counted as flushed rows
+	if segment.FlushedRows() > 0 || len(segment.GetHistory()) > 0 || wb.pendingSyncs.Pending(segment.SegmentID()) {
+		return false
+	}
+
+	pks, ok := cache[segment.SegmentID()]
+	if !ok {
+		pks = typeutil.NewSet[any]()
+		if buffer, ok := wb.buffers[segment.SegmentID()]; ok && !buffer.insertBuffer.IsEmpty() {
+			pkData, err := buffer.insertBuffer.getPkData(buffer.insertBuffer.buffer)
+			if err != nil {
+				return false
+			}
+			for i := 0; i < pkData.RowNum(); i++ {
+				pks.Insert(pkData.GetRow(i))
+			}
+		}
+		cache[segment.SegmentID()] = pks
+	}
+	return !pks.Contain(pk.GetValue())
+}
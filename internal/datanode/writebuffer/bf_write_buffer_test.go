@@ -1,14 +1,25 @@
 package writebuffer
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
+	"runtime"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/apache/arrow/go/v12/arrow"
+	"github.com/cockroachdb/errors"
 	"github.com/samber/lo"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/atomic"
 
 	"github.com/milvus-io/milvus-proto/go-api/v2/commonpb"
 	"github.com/milvus-io/milvus-proto/go-api/v2/msgpb"
@@ -16,15 +27,20 @@ import (
 	milvus_storage "github.com/milvus-io/milvus-storage/go/storage"
 	"github.com/milvus-io/milvus-storage/go/storage/options"
 	"github.com/milvus-io/milvus-storage/go/storage/schema"
+	"github.com/milvus-io/milvus/internal/allocator"
 	"github.com/milvus-io/milvus/internal/datanode/broker"
 	"github.com/milvus-io/milvus/internal/datanode/metacache"
 	"github.com/milvus-io/milvus/internal/datanode/syncmgr"
+	"github.com/milvus-io/milvus/internal/mocks"
 	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/milvus-io/milvus/internal/proto/etcdpb"
 	"github.com/milvus-io/milvus/internal/querycoordv2/params"
 	"github.com/milvus-io/milvus/internal/storage"
 	"github.com/milvus-io/milvus/internal/util/typeutil"
 	"github.com/milvus-io/milvus/pkg/common"
 	"github.com/milvus-io/milvus/pkg/mq/msgstream"
+	"github.com/milvus-io/milvus/pkg/util/conc"
+	"github.com/milvus-io/milvus/pkg/util/merr"
 	"github.com/milvus-io/milvus/pkg/util/paramtable"
 	"github.com/milvus-io/milvus/pkg/util/tsoutil"
 )
@@ -159,7 +175,8 @@ func (s *BFWriteBufferSuite) TestBufferData() {
 
 	seg := metacache.NewSegmentInfo(&datapb.SegmentInfo{ID: 1000}, metacache.NewBloomFilterSet())
 	s.metacache.EXPECT().GetSegmentsBy(mock.Anything, mock.Anything).Return([]*metacache.SegmentInfo{seg})
-	s.metacache.EXPECT().GetSegmentByID(int64(1000)).Return(nil, false)
+	s.metacache.EXPECT().GetSegmentByID(int64(1000)).Return(nil, false).Once()
+	s.metacache.EXPECT().GetSegmentByID(int64(1000)).Return(seg, true)
 	s.metacache.EXPECT().AddSegment(mock.Anything, mock.Anything, mock.Anything).Return()
 	s.metacache.EXPECT().UpdateSegments(mock.Anything, mock.Anything).Return()
 	s.metacache.EXPECT().GetSegmentIDsBy(mock.Anything, mock.Anything).Return([]int64{})
@@ -171,6 +188,20 @@ func (s *BFWriteBufferSuite) TestBufferData() {
 	s.NoError(err)
 }
 
+func (s *BFWriteBufferSuite) TestBufferDataSegmentMissing() {
+	wb, err := NewBFWriteBuffer(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+	s.Require().NoError(err)
+
+	// segment add takes no effect in metacache
+	s.metacache.EXPECT().GetSegmentByID(int64(1000)).Return(nil, false)
+	s.metacache.EXPECT().AddSegment(mock.Anything, mock.Anything, mock.Anything).Return()
+	s.metacache.EXPECT().UpdateSegments(mock.Anything, mock.Anything).Return()
+
+	_, msg := s.composeInsertMsg(1000, 10, 128)
+	err = wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	s.ErrorIs(err, ErrSegmentMetaUpdate)
+}
+
 func (s *BFWriteBufferSuite) TestAutoSync() {
 	paramtable.Get().Save(paramtable.Get().DataNodeCfg.FlushInsertBufferSize.Key, "1")
 
@@ -186,7 +217,8 @@ func (s *BFWriteBufferSuite) TestAutoSync() {
 
 		seg := metacache.NewSegmentInfo(&datapb.SegmentInfo{ID: 1000}, metacache.NewBloomFilterSet())
 		s.metacache.EXPECT().GetSegmentsBy(mock.Anything, mock.Anything).Return([]*metacache.SegmentInfo{seg})
-		s.metacache.EXPECT().GetSegmentByID(int64(1000)).Return(nil, false)
+		s.metacache.EXPECT().GetSegmentByID(int64(1000)).Return(nil, false).Once()
+		s.metacache.EXPECT().GetSegmentByID(int64(1000)).Return(seg, true)
 		s.metacache.EXPECT().GetSegmentByID(int64(1002)).Return(seg, true)
 		s.metacache.EXPECT().GetSegmentIDsBy(mock.Anything).Return([]int64{1002})
 		s.metacache.EXPECT().GetSegmentIDsBy(mock.Anything, mock.Anything).Return([]int64{})
@@ -220,7 +252,8 @@ func (s *BFWriteBufferSuite) TestBufferDataWithStorageV2() {
 
 	seg := metacache.NewSegmentInfo(&datapb.SegmentInfo{ID: 1000}, metacache.NewBloomFilterSet())
 	s.metacache.EXPECT().GetSegmentsBy(mock.Anything, mock.Anything).Return([]*metacache.SegmentInfo{seg})
-	s.metacache.EXPECT().GetSegmentByID(int64(1000)).Return(nil, false)
+	s.metacache.EXPECT().GetSegmentByID(int64(1000)).Return(nil, false).Once()
+	s.metacache.EXPECT().GetSegmentByID(int64(1000)).Return(seg, true)
 	s.metacache.EXPECT().GetSegmentIDsBy(mock.Anything, mock.Anything).Return([]int64{})
 	s.metacache.EXPECT().AddSegment(mock.Anything, mock.Anything, mock.Anything).Return()
 	s.metacache.EXPECT().UpdateSegments(mock.Anything, mock.Anything).Return()
@@ -244,6 +277,7 @@ func (s *BFWriteBufferSuite) TestAutoSyncWithStorageV2() {
 			PrimaryColumn: "pk", VectorColumn: "vector", VersionColumn: common.TimeStampFieldName,
 		})).Build())
 	s.Require().NoError(err)
+	s.storageV2Cache.SetSpace(1000, space)
 	s.storageV2Cache.SetSpace(1002, space)
 
 	s.Run("normal_auto_sync", func() {
@@ -261,7 +295,8 @@ func (s *BFWriteBufferSuite) TestAutoSyncWithStorageV2() {
 		metacache.CompactTo(2001)(segCompacted)
 
 		s.metacache.EXPECT().GetSegmentsBy(mock.Anything, mock.Anything).Return([]*metacache.SegmentInfo{seg, segCompacted})
-		s.metacache.EXPECT().GetSegmentByID(int64(1000)).Return(nil, false)
+		s.metacache.EXPECT().GetSegmentByID(int64(1000)).Return(nil, false).Once()
+		s.metacache.EXPECT().GetSegmentByID(int64(1000)).Return(seg, true)
 		s.metacache.EXPECT().GetSegmentByID(int64(1002)).Return(seg, true)
 		s.metacache.EXPECT().GetSegmentIDsBy(mock.Anything).Return([]int64{1002})
 		s.metacache.EXPECT().GetSegmentIDsBy(mock.Anything, mock.Anything).Return([]int64{1003}) // mocked compacted
@@ -279,6 +314,2110 @@ func (s *BFWriteBufferSuite) TestAutoSyncWithStorageV2() {
 	})
 }
 
-func TestBFWriteBuffer(t *testing.T) {
-	suite.Run(t, new(BFWriteBufferSuite))
+func (s *BFWriteBufferSuite) TestTotalFlushedRows() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{})
+	s.Require().NoError(err)
+	bfWb := wb.(*bfWriteBuffer)
+
+	s.syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).Return(nil)
+
+	_, msg1 := s.composeInsertMsg(1000, 10, 128)
+	_, msg2 := s.composeInsertMsg(1001, 20, 128)
+	err = wb.BufferData([]*msgstream.InsertMsg{msg1, msg2}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	s.Require().NoError(err)
+	s.EqualValues(0, wb.TotalFlushedRows())
+
+	bfWb.mut.Lock()
+	bfWb.syncSegments(context.Background(), []int64{1000})
+	bfWb.mut.Unlock()
+	s.EqualValues(10, wb.TotalFlushedRows())
+
+	_, msg3 := s.composeInsertMsg(1000, 5, 128)
+	err = wb.BufferData([]*msgstream.InsertMsg{msg3}, nil, &msgpb.MsgPosition{Timestamp: 200}, &msgpb.MsgPosition{Timestamp: 300})
+	s.Require().NoError(err)
+
+	bfWb.mut.Lock()
+	bfWb.syncSegments(context.Background(), []int64{1000, 1001})
+	bfWb.mut.Unlock()
+	s.EqualValues(35, wb.TotalFlushedRows())
+}
+
+func (s *BFWriteBufferSuite) TestSegmentIDAllocator() {
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	nextID := int64(5000)
+	allocCount := 0
+	option := &writeBufferOption{}
+	WithSegmentIDAllocator(func() int64 {
+		allocCount++
+		nextID++
+		return nextID
+	})(option)
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, option)
+	s.Require().NoError(err)
+	bfWb := wb.(*bfWriteBuffer)
+
+	_, msg1 := s.composeInsertMsg(1000, 10, 128)
+	_, msg2 := s.composeInsertMsg(1001, 10, 128)
+	err = wb.BufferData([]*msgstream.InsertMsg{msg1, msg2}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	s.Require().NoError(err)
+
+	_, msg3 := s.composeInsertMsg(1000, 10, 128)
+	err = wb.BufferData([]*msgstream.InsertMsg{msg3}, nil, &msgpb.MsgPosition{Timestamp: 200}, &msgpb.MsgPosition{Timestamp: 300})
+	s.Require().NoError(err)
+
+	s.Equal(2, allocCount)
+	s.ElementsMatch([]int64{5001, 5002}, lo.Keys(bfWb.buffers))
+	s.False(wb.HasSegment(1000))
+	s.False(wb.HasSegment(1001))
+	s.ElementsMatch([]int64{5001, 5002}, metaCache.GetSegmentIDsBy())
+	s.EqualValues(20, bfWb.buffers[bfWb.allocatedSegments[1000]].insertBuffer.rows)
+}
+
+func (s *BFWriteBufferSuite) TestSegmentIDAllocatorMappings() {
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	nextID := int64(5000)
+	option := &writeBufferOption{}
+	WithSegmentIDAllocator(func() int64 {
+		nextID++
+		return nextID
+	})(option)
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, option)
+	s.Require().NoError(err)
+	bfWb := wb.(*bfWriteBuffer)
+	bufferSeq := func(seq uint64, segmentIDs ...int64) error {
+		msgs := lo.Map(segmentIDs, func(segmentID int64, _ int) *msgstream.InsertMsg {
+			_, msg := s.composeInsertMsg(segmentID, 10, 128)
+			return msg
+		})
+		return wb.BufferDataSeq(msgs, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200}, seq)
+	}
+
+	s.Require().NoError(bufferSeq(2, 1000))
+	s.Equal(map[int64]int64{1000: 5001}, bfWb.allocatedSegments)
+
+	// rejected sequence allocates no segment id
+	s.ErrorIs(bufferSeq(1, 1000, 1001), merr.ErrParameterInvalid)
+	s.Equal(map[int64]int64{1000: 5001}, bfWb.allocatedSegments)
+	s.EqualValues(5001, nextID)
+
+	// mapping is removed once allocated segment flushes
+	s.Require().NoError(wb.FlushSegments(context.Background(), []int64{5001}))
+	s.Empty(bfWb.allocatedSegments)
+	s.Require().NoError(bufferSeq(3, 1000))
+	s.Equal(map[int64]int64{1000: 5002}, bfWb.allocatedSegments)
+
+	// rows of unknown segment are rejected without allocating segment id
+	err = wb.BufferRows(2000, func(InsertBuilder) error { return nil }, &msgpb.MsgPosition{Timestamp: 200}, &msgpb.MsgPosition{Timestamp: 300})
+	s.ErrorIs(err, merr.ErrSegmentNotFound)
+	s.Equal(map[int64]int64{1000: 5002}, bfWb.allocatedSegments)
+	s.EqualValues(5002, nextID)
+}
+
+func (s *BFWriteBufferSuite) TestSkipEmptySegment() {
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+
+	for _, skip := range []bool{true, false} {
+		s.Run(fmt.Sprintf("skip_%t", skip), func() {
+			option := &writeBufferOption{}
+			WithSkipEmptyInserts(skip)(option)
+			wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, option)
+			s.Require().NoError(err)
+			defer metaCache.RemoveSegments(metacache.WithSegmentIDs(1000))
+
+			// all rows filtered
+			_, msg := s.composeInsertMsg(1000, 0, 128)
+			err = wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+			s.NoError(err)
+
+			s.Equal(!skip, wb.HasSegment(1000))
+			_, ok := metaCache.GetSegmentByID(1000)
+			s.Equal(!skip, ok)
+		})
+	}
+
+	// skipped by default
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{})
+	s.Require().NoError(err)
+	_, msg := s.composeInsertMsg(1000, 0, 128)
+	s.NoError(wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200}))
+	s.False(wb.HasSegment(1000))
+	s.Empty(metaCache.GetSegmentIDsBy())
+}
+
+func (s *BFWriteBufferSuite) TestPKExtractor() {
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	option := &writeBufferOption{}
+	WithPKExtractor(func(data *storage.InsertData) []storage.PrimaryKey {
+		pkData := data.Data[common.StartOfUserFieldID].(*storage.Int64FieldData)
+		return lo.Map(pkData.Data, func(pk int64, _ int) storage.PrimaryKey {
+			return storage.NewInt64PrimaryKey(pk + 1)
+		})
+	})(option)
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, option)
+	s.Require().NoError(err)
+	bfWb := wb.(*bfWriteBuffer)
+
+	pks, msg := s.composeInsertMsg(1000, 10, 128)
+	pkData, err := bfWb.bufferInsert(groupInsertMsgs([]*msgstream.InsertMsg{msg}), &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	s.Require().NoError(err)
+	s.Require().Len(pkData[1000], 1)
+	s.Equal(lo.Map(pks, func(pk int64, _ int) int64 { return pk + 1 }), pkData[1000][0].(*storage.Int64FieldData).Data)
+}
+
+func (s *BFWriteBufferSuite) TestTracing() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	paramtable.Get().Save(paramtable.Get().DataNodeCfg.WriteBufferTracingEnable.Key, "true")
+	defer paramtable.Get().Reset(paramtable.Get().DataNodeCfg.WriteBufferTracingEnable.Key)
+
+	recorder := tracetest.NewSpanRecorder()
+	prevProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(prevProvider)
+
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	// sync all buffered segments
+	policy := wrapSelectSegmentFuncPolicy(func(buffers []*segmentBuffer, _ uint64) []int64 {
+		return lo.Map(buffers, func(buf *segmentBuffer, _ int) int64 { return buf.segmentID })
+	}, "test policy")
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{
+		syncPolicies: []SyncPolicy{policy},
+	})
+	s.Require().NoError(err)
+	s.syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).Return(nil)
+
+	_, msg := s.composeInsertMsg(1000, 10, 128)
+	err = wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	s.Require().NoError(err)
+
+	spans := lo.SliceToMap(recorder.Ended(), func(span sdktrace.ReadOnlySpan) (string, sdktrace.ReadOnlySpan) {
+		return span.Name(), span
+	})
+	s.Require().Contains(spans, "WriteBuffer-BufferData")
+	s.Require().Contains(spans, "WriteBuffer-SyncSegments")
+	s.Require().Contains(spans, "WriteBuffer-GetSyncTask")
+
+	s.Contains(spans["WriteBuffer-BufferData"].Attributes(), attribute.String("channel", s.channelName))
+	s.Contains(spans["WriteBuffer-BufferData"].Attributes(), attribute.Int64("collectionID", s.collID))
+	s.Contains(spans["WriteBuffer-GetSyncTask"].Attributes(), attribute.Int64("segmentID", 1000))
+	// sync spans are children of buffer span
+	s.Equal(spans["WriteBuffer-BufferData"].SpanContext().TraceID(), spans["WriteBuffer-GetSyncTask"].SpanContext().TraceID())
+}
+
+func (s *BFWriteBufferSuite) TestSyncTaskTraceID() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	traceID := trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	traceCtx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: trace.FlagsSampled,
+	}))
+	// sync all buffered segments
+	policy := wrapSelectSegmentFuncPolicy(func(buffers []*segmentBuffer, _ uint64) []int64 {
+		return lo.Map(buffers, func(buf *segmentBuffer, _ int) int64 { return buf.segmentID })
+	}, "test policy")
+
+	for _, withTrace := range []bool{true, false} {
+		s.Run(fmt.Sprintf("with_trace_%t", withTrace), func() {
+			metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+				Schema: s.collSchema,
+				Vchan: &datapb.VchannelInfo{
+					CollectionID: s.collID,
+					ChannelName:  s.channelName,
+				},
+			}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+			syncMgr := syncmgr.NewMockSyncManager(s.T())
+			var tasks []*syncmgr.SyncTask
+			syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, task syncmgr.Task) *conc.Future[error] {
+				tasks = append(tasks, task.(*syncmgr.SyncTask))
+				return conc.Go(func() (error, error) { return nil, nil })
+			})
+			wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, syncMgr, &writeBufferOption{
+				syncPolicies: []SyncPolicy{policy},
+			})
+			s.Require().NoError(err)
+
+			_, msg := s.composeInsertMsg(1000, 10, 128)
+			if withTrace {
+				msg.SetTraceCtx(traceCtx)
+			}
+			s.Require().NoError(wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200}))
+
+			s.Require().Len(tasks, 1)
+			if withTrace {
+				s.Equal(traceID.String(), tasks[0].TraceID())
+			} else {
+				s.Empty(tasks[0].TraceID())
+			}
+		})
+	}
+}
+
+func (s *BFWriteBufferSuite) TestBufferDataGrouped() {
+	newMetaCache := func() metacache.MetaCache {
+		return metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+			Schema: s.collSchema,
+			Vchan: &datapb.VchannelInfo{
+				CollectionID: s.collID,
+				ChannelName:  s.channelName,
+			},
+		}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	}
+	startPos, endPos := &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200}
+
+	pks1, msg1 := s.composeInsertMsg(1000, 10, 128)
+	_, msg2 := s.composeInsertMsg(1001, 20, 128)
+	_, msg3 := s.composeInsertMsg(1000, 5, 128)
+	delMsg := s.composeDeleteMsg(lo.Map(pks1, func(id int64, _ int) storage.PrimaryKey { return storage.NewInt64PrimaryKey(id) }))
+
+	wb, err := NewBFWriteBuffer(s.channelName, newMetaCache(), nil, s.syncMgr, &writeBufferOption{})
+	s.Require().NoError(err)
+	err = wb.BufferData([]*msgstream.InsertMsg{msg1, msg2, msg3}, []*msgstream.DeleteMsg{delMsg}, startPos, endPos)
+	s.Require().NoError(err)
+
+	groupedWb, err := NewBFWriteBuffer(s.channelName, newMetaCache(), nil, s.syncMgr, &writeBufferOption{})
+	s.Require().NoError(err)
+	err = groupedWb.BufferDataGrouped(map[int64][]*msgstream.InsertMsg{
+		1000: {msg1, msg3},
+		1001: {msg2},
+	}, map[int64][]*msgstream.DeleteMsg{
+		1000: {delMsg},
+	}, startPos, endPos)
+	s.Require().NoError(err)
+
+	expected := wb.(*bfWriteBuffer).buffers
+	actual := groupedWb.(*bfWriteBuffer).buffers
+	s.ElementsMatch(lo.Keys(expected), lo.Keys(actual))
+	for segmentID, buf := range expected {
+		s.Equal(buf.insertBuffer.rows, actual[segmentID].insertBuffer.rows)
+		s.Equal(buf.deltaBuffer.rows, actual[segmentID].deltaBuffer.rows)
+	}
+	s.EqualValues(15, actual[1000].insertBuffer.rows)
+	s.EqualValues(10, actual[1000].deltaBuffer.rows)
+	s.EqualValues(20, actual[1001].insertBuffer.rows)
+}
+
+func (s *BFWriteBufferSuite) TestAutoSyncInterval() {
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	evaluated := atomic.NewInt64(0)
+	policy := wrapSelectSegmentFuncPolicy(func(_ []*segmentBuffer, _ uint64) []int64 {
+		evaluated.Inc()
+		return nil
+	}, "test policy")
+	option := &writeBufferOption{
+		syncPolicies: []SyncPolicy{policy},
+	}
+	WithAutoSyncInterval(10 * time.Millisecond)(option)
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, option)
+	s.Require().NoError(err)
+
+	s.Eventually(func() bool { return evaluated.Load() >= 2 }, time.Second, 10*time.Millisecond)
+
+	wb.Close(false)
+	count := evaluated.Load()
+	time.Sleep(50 * time.Millisecond)
+	// no evaluation after closed
+	s.Equal(count, evaluated.Load())
+}
+
+func (s *BFWriteBufferSuite) TestRowLagStats() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{})
+	s.Require().NoError(err)
+	bfWb := wb.(*bfWriteBuffer)
+
+	idAllocator := allocator.NewMockGIDAllocator()
+	idAllocator.AllocF = func(count uint32) (int64, int64, error) {
+		return time.Now().Unix(), int64(count), nil
+	}
+	idAllocator.AllocOneF = func() (int64, error) {
+		return time.Now().Unix(), nil
+	}
+	chunkManager := mocks.NewChunkManager(s.T())
+	chunkManager.EXPECT().RootPath().Return("files").Maybe()
+	chunkManager.EXPECT().MultiWrite(mock.Anything, mock.Anything).Return(nil).Maybe()
+	s.syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, task syncmgr.Task) *conc.Future[error] {
+		task.(*syncmgr.SyncTask).WithAllocator(idAllocator).WithChunkManager(chunkManager)
+		err := task.Run()
+		return conc.Go(func() (error, error) { return err, nil })
+	})
+
+	_, msg1 := s.composeInsertMsg(1000, 10, 128)
+	_, msg2 := s.composeInsertMsg(1001, 20, 128)
+	err = wb.BufferData([]*msgstream.InsertMsg{msg1, msg2}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	s.Require().NoError(err)
+	s.Equal(map[int64]int64{1000: 10, 1001: 20}, wb.RowLagStats())
+
+	bfWb.mut.Lock()
+	bfWb.syncSegments(context.Background(), []int64{1000})
+	bfWb.mut.Unlock()
+	s.Equal(map[int64]int64{1001: 20}, wb.RowLagStats())
+}
+
+func (s *BFWriteBufferSuite) TestSpaceCreateRetry() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("true")
+	params.Params.CommonCfg.StorageScheme.SwapTempValue("file")
+	tmpDir := s.T().TempDir()
+	arrowSchema, err := typeutil.ConvertToArrowSchema(s.collSchema.Fields)
+	s.Require().NoError(err)
+	space, err := milvus_storage.Open(fmt.Sprintf("file:///%s", tmpDir), options.NewSpaceOptionBuilder().
+		SetSchema(schema.NewSchema(arrowSchema, &schema.SchemaOptions{
+			PrimaryColumn: "pk", VectorColumn: "vector", VersionColumn: common.TimeStampFieldName,
+		})).Build())
+	s.Require().NoError(err)
+
+	segmentID := int64(1001)
+	seg := metacache.NewSegmentInfo(&datapb.SegmentInfo{ID: segmentID, State: commonpb.SegmentState_Growing}, metacache.NewBloomFilterSet())
+	s.metacache.EXPECT().GetSegmentByID(segmentID).Return(seg, true)
+	s.metacache.EXPECT().UpdateSegments(mock.Anything, mock.Anything).Return()
+
+	newWriteBuffer := func(attempts int, failures int) (*bfWriteBuffer, *int) {
+		storageCache, err := metacache.NewStorageV2Cache(s.collSchema)
+		s.Require().NoError(err)
+		option := &writeBufferOption{}
+		WithSpaceCreateRetry(attempts, time.Millisecond)(option)
+		wb, err := NewBFWriteBuffer(s.channelName, s.metacache, storageCache, s.syncMgr, option)
+		s.Require().NoError(err)
+		bfWb := wb.(*bfWriteBuffer)
+
+		calls := 0
+		bfWb.spaceCreator = func(int64, *schemapb.CollectionSchema, *arrow.Schema) func() (*milvus_storage.Space, error) {
+			return func() (*milvus_storage.Space, error) {
+				calls++
+				if calls <= failures {
+					return nil, errors.New("mocked")
+				}
+				return space, nil
+			}
+		}
+		buf := bfWb.getOrCreateBuffer(segmentID)
+		buf.insertBuffer.UpdateStatistics(10, 1024, TimeRange{timestampMin: 100, timestampMax: 200},
+			&msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+		return bfWb, &calls
+	}
+
+	s.Run("succeed_after_retry", func() {
+		wb, calls := newWriteBuffer(3, 2)
+		task, err := wb.getSyncTask(context.Background(), segmentID)
+		s.NoError(err)
+		s.NotNil(task)
+		s.Equal(3, *calls)
+	})
+
+	s.Run("retry_exhausted", func() {
+		wb, calls := newWriteBuffer(2, 2)
+		task, err := wb.getSyncTask(context.Background(), segmentID)
+		s.Error(err)
+		s.Nil(task)
+		s.Equal(2, *calls)
+		// data stays buffered for next sync
+		s.True(wb.HasSegment(segmentID))
+		s.Error(wb.LastSyncError(segmentID))
+	})
+}
+
+func (s *BFWriteBufferSuite) TestDeleteAppliesTo() {
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	bfs := metacache.NewBloomFilterSet()
+	err := bfs.UpdatePKRange(&storage.Int64FieldData{Data: []int64{1, 2, 3}})
+	s.Require().NoError(err)
+	metaCache.AddSegment(&datapb.SegmentInfo{ID: 1000, State: commonpb.SegmentState_Growing},
+		func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return bfs })
+
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{})
+	s.Require().NoError(err)
+	bfWb := wb.(*bfWriteBuffer)
+
+	s.True(bfWb.DeleteAppliesTo(1000, storage.NewInt64PrimaryKey(1)))
+	s.True(bfWb.DeleteAppliesTo(1000, storage.NewInt64PrimaryKey(3)))
+	s.False(bfWb.DeleteAppliesTo(1000, storage.NewInt64PrimaryKey(100)))
+	// segment not found
+	s.False(bfWb.DeleteAppliesTo(1001, storage.NewInt64PrimaryKey(1)))
+}
+
+func (s *BFWriteBufferSuite) TestNoAutoSegmentCreate() {
+	newMetaCache := func() metacache.MetaCache {
+		return metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+			Schema: s.collSchema,
+			Vchan: &datapb.VchannelInfo{
+				CollectionID: s.collID,
+				ChannelName:  s.channelName,
+			},
+		}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	}
+
+	s.Run("disabled", func() {
+		metaCache := newMetaCache()
+		option := &writeBufferOption{}
+		WithNoAutoSegmentCreate()(option)
+		wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, option)
+		s.Require().NoError(err)
+
+		_, msg := s.composeInsertMsg(1000, 10, 128)
+		err = wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+		s.ErrorIs(err, merr.ErrSegmentNotFound)
+		_, ok := metaCache.GetSegmentByID(1000)
+		s.False(ok)
+		s.False(wb.HasSegment(1000))
+	})
+
+	s.Run("default", func() {
+		metaCache := newMetaCache()
+		wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{})
+		s.Require().NoError(err)
+
+		_, msg := s.composeInsertMsg(1000, 10, 128)
+		err = wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+		s.NoError(err)
+		_, ok := metaCache.GetSegmentByID(1000)
+		s.True(ok)
+		s.True(wb.HasSegment(1000))
+	})
+}
+
+func (s *BFWriteBufferSuite) TestBufferRows() {
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	metaCache.AddSegment(&datapb.SegmentInfo{
+		ID:            1000,
+		CollectionID:  s.collID,
+		InsertChannel: s.channelName,
+		State:         commonpb.SegmentState_Growing,
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+
+	s.syncMgr.EXPECT().GetEarliestPosition(s.channelName).Return(0, nil).Maybe()
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{})
+	s.Require().NoError(err)
+
+	startPos, endPos := &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200}
+	fillRows := func(rowCount int) func(builder InsertBuilder) error {
+		return func(builder InsertBuilder) error {
+			for i := 0; i < rowCount; i++ {
+				for fieldID, value := range map[int64]any{
+					common.RowIDField:     int64(i),
+					common.TimeStampField: int64(150),
+					100:                   int64(i),
+					101:                   lo.RepeatBy(128, func(_ int) float32 { return rand.Float32() }),
+				} {
+					if err := builder.Append(fieldID, value); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}
+	}
+
+	s.Run("normal_buffer", func() {
+		err := wb.BufferRows(1000, fillRows(10), startPos, endPos)
+		s.NoError(err)
+
+		s.True(wb.HasSegment(1000))
+		s.EqualValues(10, wb.RowLagStats()[1000])
+		segment, ok := metaCache.GetSegmentByID(1000)
+		s.Require().True(ok)
+		s.True(segment.GetBloomFilterSet().PkExists(storage.NewInt64PrimaryKey(5)))
+		s.Equal(endPos, wb.GetCheckpoint())
+	})
+
+	s.Run("segment_not_found", func() {
+		err := wb.BufferRows(1001, fillRows(10), startPos, endPos)
+		s.ErrorIs(err, merr.ErrSegmentNotFound)
+		s.False(wb.HasSegment(1001))
+	})
+
+	s.Run("unknown_field", func() {
+		err := wb.BufferRows(1000, func(builder InsertBuilder) error {
+			return builder.Append(999, int64(1))
+		}, startPos, endPos)
+		s.ErrorIs(err, merr.ErrFieldNotFound)
+	})
+
+	s.Run("row_num_not_match", func() {
+		err := wb.BufferRows(1000, func(builder InsertBuilder) error {
+			return builder.Append(100, int64(1))
+		}, startPos, endPos)
+		s.ErrorIs(err, merr.ErrParameterInvalid)
+		s.EqualValues(10, wb.RowLagStats()[1000])
+	})
+}
+
+func (s *BFWriteBufferSuite) TestBufferRowsParallel() {
+	const segmentNum, writerNum, batchNum, batchRows = 32, 8, 20, 5
+	metaCache := newRowsMetaCache(s.collSchema, s.collID, s.channelName, segmentNum)
+	s.syncMgr.EXPECT().GetEarliestPosition(s.channelName).Return(0, nil).Maybe()
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{})
+	s.Require().NoError(err)
+	base := wb.(*bfWriteBuffer)
+
+	// writers buffer rows into interleaved segments while readers poll buffer contents
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				wb.GetCheckpoint()
+				wb.BufferStats()
+				wb.TopSegmentsByMemory(3)
+				wb.FieldMemorySize(1000)
+				base.EstimateFlushSize(1001)
+			}
+		}()
+	}
+
+	var writers sync.WaitGroup
+	for w := 0; w < writerNum; w++ {
+		writers.Add(1)
+		go func(w int) {
+			defer writers.Done()
+			for i := 0; i < batchNum; i++ {
+				segmentID := int64(1000 + (w*batchNum+i)%segmentNum)
+				ts := uint64(100 + w*batchNum + i)
+				err := wb.BufferRows(segmentID, fillBenchRows(batchRows, ts), &msgpb.MsgPosition{Timestamp: ts}, &msgpb.MsgPosition{Timestamp: ts + 1})
+				s.NoError(err)
+			}
+		}(w)
+	}
+	writers.Wait()
+	close(done)
+	readers.Wait()
+
+	stats := wb.BufferStats()
+	s.EqualValues(writerNum*batchNum*batchRows, stats.InsertRows)
+	s.Len(base.buffers, segmentNum)
+	// out of order completion shall not move checkpoint backward
+	s.EqualValues(100+writerNum*batchNum, base.checkpoint.GetTimestamp())
+}
+
+func (s *BFWriteBufferSuite) TestConcurrentSegmentCreation() {
+	wb, err := NewBFWriteBuffer(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+	s.Require().NoError(err)
+
+	seg := metacache.NewSegmentInfo(&datapb.SegmentInfo{ID: 1000}, metacache.NewBloomFilterSet())
+	created := atomic.NewBool(false)
+	addCount := atomic.NewInt32(0)
+	s.metacache.EXPECT().GetSegmentByID(int64(1000)).RunAndReturn(func(_ int64, _ ...metacache.SegmentFilter) (*metacache.SegmentInfo, bool) {
+		if created.Load() {
+			return seg, true
+		}
+		return nil, false
+	})
+	s.metacache.EXPECT().AddSegment(mock.Anything, mock.Anything, mock.Anything).Run(func(_ *datapb.SegmentInfo, _ metacache.PkStatsFactory, _ ...metacache.SegmentAction) {
+		addCount.Inc()
+		// widen the window between check and create
+		time.Sleep(time.Millisecond)
+		created.Store(true)
+	}).Return()
+	s.metacache.EXPECT().GetSegmentsBy(mock.Anything, mock.Anything).Return([]*metacache.SegmentInfo{seg})
+	s.metacache.EXPECT().UpdateSegments(mock.Anything, mock.Anything).Return()
+	s.metacache.EXPECT().GetSegmentIDsBy(mock.Anything, mock.Anything).Return([]int64{})
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, msg := s.composeInsertMsg(1000, 10, 128)
+			err := wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+			s.NoError(err)
+		}()
+	}
+	wg.Wait()
+
+	s.EqualValues(1, addCount.Load())
+}
+
+func (s *BFWriteBufferSuite) TestBufferDataSeq() {
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{})
+	s.Require().NoError(err)
+
+	bufferSeq := func(segmentID int64, seq uint64) error {
+		_, msg := s.composeInsertMsg(segmentID, 10, 128)
+		return wb.BufferDataSeq([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200}, seq)
+	}
+
+	s.NoError(bufferSeq(1000, 1))
+	s.NoError(bufferSeq(1000, 3))
+	s.EqualValues(20, wb.RowLagStats()[1000])
+
+	// out of order & duplicate sequence
+	s.ErrorIs(bufferSeq(1000, 2), merr.ErrParameterInvalid)
+	s.ErrorIs(bufferSeq(1000, 3), merr.ErrParameterInvalid)
+	s.EqualValues(20, wb.RowLagStats()[1000])
+
+	// sequence is tracked per segment
+	s.NoError(bufferSeq(1001, 2))
+	s.NoError(bufferSeq(1000, 4))
+	s.EqualValues(30, wb.RowLagStats()[1000])
+}
+
+func (s *BFWriteBufferSuite) TestIngestRateLimit() {
+	newMetaCache := func() metacache.MetaCache {
+		return metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+			Schema: s.collSchema,
+			Vchan: &datapb.VchannelInfo{
+				CollectionID: s.collID,
+				ChannelName:  s.channelName,
+			},
+		}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	}
+	_, msg := s.composeInsertMsg(1000, 10, 128)
+	// quota refilled in about 200ms after first batch
+	limit := int64(float64(msg.Size()) / 1.2)
+	bufferData := func(wb WriteBuffer) error {
+		_, msg := s.composeInsertMsg(1000, 10, 128)
+		return wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	}
+
+	s.Run("reject", func() {
+		option := &writeBufferOption{}
+		WithIngestRateLimit(limit)(option)
+		wb, err := NewBFWriteBuffer(s.channelName, newMetaCache(), nil, s.syncMgr, option)
+		s.Require().NoError(err)
+
+		s.NoError(bufferData(wb))
+		s.ErrorIs(bufferData(wb), ErrRateLimited)
+		s.EqualValues(10, wb.RowLagStats()[1000])
+
+		s.Eventually(func() bool { return bufferData(wb) == nil }, time.Second, 50*time.Millisecond)
+	})
+
+	s.Run("block", func() {
+		option := &writeBufferOption{}
+		WithIngestRateLimit(limit)(option)
+		WithIngestRateLimitBlocking()(option)
+		wb, err := NewBFWriteBuffer(s.channelName, newMetaCache(), nil, s.syncMgr, option)
+		s.Require().NoError(err)
+
+		s.NoError(bufferData(wb))
+		start := time.Now()
+		s.NoError(bufferData(wb))
+		s.GreaterOrEqual(time.Since(start), 100*time.Millisecond)
+		s.EqualValues(20, wb.RowLagStats()[1000])
+	})
+
+	s.Run("block_closed", func() {
+		option := &writeBufferOption{}
+		WithIngestRateLimit(limit)(option)
+		WithIngestRateLimitBlocking()(option)
+		wb, err := NewBFWriteBuffer(s.channelName, newMetaCache(), nil, s.syncMgr, option)
+		s.Require().NoError(err)
+
+		s.NoError(bufferData(wb))
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			wb.Close(false)
+		}()
+		s.ErrorIs(bufferData(wb), ErrBufferClosed)
+	})
+
+	s.Run("batch_larger_than_rate", func() {
+		// every batch exceeds one second of quota
+		option := &writeBufferOption{}
+		WithIngestRateLimit(int64(msg.Size()) / 2)(option)
+		WithIngestRateLimitBlocking()(option)
+		wb, err := NewBFWriteBuffer(s.channelName, newMetaCache(), nil, s.syncMgr, option)
+		s.Require().NoError(err)
+
+		s.NoError(bufferData(wb))
+		done := make(chan error, 1)
+		go func() { done <- bufferData(wb) }()
+		select {
+		case err := <-done:
+			s.NoError(err)
+		case <-time.After(5 * time.Second):
+			s.FailNow("batch larger than rate never admitted")
+		}
+		s.EqualValues(20, wb.RowLagStats()[1000])
+	})
+}
+
+func (s *BFWriteBufferSuite) TestWatchBufferSize() {
+	newWriteBuffer := func(opts ...WriteBufferOption) WriteBuffer {
+		metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+			Schema: s.collSchema,
+			Vchan: &datapb.VchannelInfo{
+				CollectionID: s.collID,
+				ChannelName:  s.channelName,
+			},
+		}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+		option := &writeBufferOption{}
+		for _, opt := range opts {
+			opt(option)
+		}
+		wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, option)
+		s.Require().NoError(err)
+		return wb
+	}
+	bufferData := func(wb WriteBuffer) {
+		_, msg := s.composeInsertMsg(1000, 10, 128)
+		err := wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+		s.Require().NoError(err)
+	}
+
+	s.Run("emit_on_change", func() {
+		wb := newWriteBuffer()
+		ch := wb.WatchBufferSize()
+
+		bufferData(wb)
+		first := <-ch
+		s.Greater(first, int64(0))
+
+		bufferData(wb)
+		second := <-ch
+		s.Greater(second, first)
+
+		wb.Close(false)
+		_, ok := <-ch
+		s.False(ok)
+	})
+
+	s.Run("change_within_delta", func() {
+		wb := newWriteBuffer(WithBufferSizeWatchDelta(1 << 30))
+		ch := wb.WatchBufferSize()
+
+		bufferData(wb)
+		select {
+		case size := <-ch:
+			s.Failf("unexpected size event", "size: %d", size)
+		case <-time.After(50 * time.Millisecond):
+		}
+		wb.Close(false)
+	})
+}
+
+func (s *BFWriteBufferSuite) TestBFFalsePositiveDeletes() {
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{})
+	s.Require().NoError(err)
+	bfWb := wb.(*bfWriteBuffer)
+
+	pks, msg := s.composeInsertMsg(1000, 10, 128)
+	err = wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	s.Require().NoError(err)
+
+	// make bloom filter match pks never inserted
+	segment, ok := metaCache.GetSegmentByID(1000)
+	s.Require().True(ok)
+	s.Require().NoError(segment.GetBloomFilterSet().UpdatePKRange(&storage.Int64FieldData{Data: []int64{1, 2, 3}}))
+
+	delMsg := s.composeDeleteMsg(lo.Map([]int64{1, 2, 3, 4, pks[0]}, func(id int64, _ int) storage.PrimaryKey { return storage.NewInt64PrimaryKey(id) }))
+	err = wb.BufferData(nil, []*msgstream.DeleteMsg{delMsg}, &msgpb.MsgPosition{Timestamp: 200}, &msgpb.MsgPosition{Timestamp: 300})
+	s.Require().NoError(err)
+	s.EqualValues(3, bfWb.BFFalsePositiveDeletes())
+
+	delMsg = s.composeDeleteMsg([]storage.PrimaryKey{storage.NewInt64PrimaryKey(1), storage.NewInt64PrimaryKey(pks[1])})
+	err = wb.BufferData(nil, []*msgstream.DeleteMsg{delMsg}, &msgpb.MsgPosition{Timestamp: 300}, &msgpb.MsgPosition{Timestamp: 400})
+	s.Require().NoError(err)
+	s.EqualValues(4, bfWb.BFFalsePositiveDeletes())
+
+	// deletes of segment with unfinished sync task are not checked
+	bfWb.pendingSyncs.Add(1000)
+	delMsg = s.composeDeleteMsg([]storage.PrimaryKey{storage.NewInt64PrimaryKey(2)})
+	err = wb.BufferData(nil, []*msgstream.DeleteMsg{delMsg}, &msgpb.MsgPosition{Timestamp: 400}, &msgpb.MsgPosition{Timestamp: 500})
+	s.Require().NoError(err)
+	s.EqualValues(4, wb.BFFalsePositiveDeletes())
+}
+
+func (s *BFWriteBufferSuite) TestSnapshotFlush() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{})
+	s.Require().NoError(err)
+	bfWb := wb.(*bfWriteBuffer)
+
+	var tasks []*syncmgr.SyncTask
+	s.syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, task syncmgr.Task) *conc.Future[error] {
+		tasks = append(tasks, task.(*syncmgr.SyncTask))
+		return conc.Go(func() (error, error) { return nil, nil })
+	})
+
+	// insert msg with row timestamps starting from provided ts
+	composeMsg := func(segmentID int64, startTs int64) *msgstream.InsertMsg {
+		tss, msg := s.composeInsertMsg(segmentID, 10, 128)
+		for i := range tss {
+			tss[i] = startTs + int64(i)
+		}
+		msg.Timestamps = lo.Map(tss, func(ts int64, _ int) uint64 { return uint64(ts) })
+		return msg
+	}
+	var barrier uint64 = 200
+	err = wb.BufferData([]*msgstream.InsertMsg{
+		composeMsg(1000, 100),
+		composeMsg(1001, 196),
+		composeMsg(1002, 300),
+	}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 400})
+	s.Require().NoError(err)
+
+	segmentIDs, err := wb.SnapshotFlush(context.Background(), barrier)
+	s.NoError(err)
+	s.Equal([]int64{1000, 1001}, segmentIDs)
+	s.Len(tasks, 2)
+	for _, task := range tasks {
+		_, to := task.TimeRange()
+		s.LessOrEqual(to, barrier)
+	}
+
+	// data after barrier stays buffered
+	s.False(wb.HasSegment(1000))
+	s.Require().True(wb.HasSegment(1001))
+	s.EqualValues(5, bfWb.buffers[1001].insertBuffer.rows)
+	s.EqualValues(201, bfWb.buffers[1001].insertBuffer.TimestampFrom)
+	s.Require().True(wb.HasSegment(1002))
+	s.EqualValues(10, bfWb.buffers[1002].insertBuffer.rows)
+
+	_, err = wb.SnapshotFlush(context.Background(), barrier)
+	s.NoError(err)
+	s.Len(tasks, 2)
+
+	s.NoError(wb.Close(false))
+	_, err = wb.SnapshotFlush(context.Background(), barrier)
+	s.ErrorIs(err, ErrBufferClosed)
+}
+
+func (s *BFWriteBufferSuite) TestSnapshotFlushWholeSegments() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	option := &writeBufferOption{}
+	WithSyncDeduplication()(option)
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, option)
+	s.Require().NoError(err)
+	bfWb := wb.(*bfWriteBuffer)
+
+	var tasks []*syncmgr.SyncTask
+	s.syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, task syncmgr.Task) *conc.Future[error] {
+		tasks = append(tasks, task.(*syncmgr.SyncTask))
+		return conc.Go(func() (error, error) { return nil, nil })
+	})
+
+	composeMsg := func(segmentID int64, startTs int64) *msgstream.InsertMsg {
+		tss, msg := s.composeInsertMsg(segmentID, 10, 128)
+		msg.Timestamps = lo.Map(tss, func(_ int64, i int) uint64 { return uint64(startTs + int64(i)) })
+		return msg
+	}
+	var barrier uint64 = 200
+	err = wb.BufferData([]*msgstream.InsertMsg{
+		composeMsg(1000, 196),
+		composeMsg(1001, 196),
+	}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 400})
+	s.Require().NoError(err)
+	metaCache.UpdateSegments(metacache.UpdateState(commonpb.SegmentState_Flushing), metacache.WithSegmentIDs(1000))
+
+	s.Run("pending_sync", func() {
+		bfWb.pendingSyncs.Add(1000)
+		bfWb.pendingSyncs.Add(1001)
+		defer bfWb.pendingSyncs.Done(1000)
+		defer bfWb.pendingSyncs.Done(1001)
+		_, err := wb.SnapshotFlush(context.Background(), barrier)
+		s.ErrorIs(err, ErrSnapshotIncomplete)
+		s.Empty(tasks)
+		// split data of skipped segment is restored
+		s.Require().True(wb.HasSegment(1000))
+		s.Require().True(wb.HasSegment(1001))
+		s.EqualValues(10, bfWb.buffers[1001].insertBuffer.rows)
+	})
+
+	s.Run("flushing_not_split", func() {
+		segmentIDs, err := wb.SnapshotFlush(context.Background(), barrier)
+		s.NoError(err)
+		s.Equal([]int64{1000, 1001}, segmentIDs)
+		s.False(wb.HasSegment(1000))
+		s.Require().True(wb.HasSegment(1001))
+		s.EqualValues(5, bfWb.buffers[1001].insertBuffer.rows)
+		// flushing segment is synced along with data after barrier
+		tsTo := lo.SliceToMap(tasks, func(task *syncmgr.SyncTask) (int64, uint64) {
+			_, to := task.TimeRange()
+			return task.SegmentID(), to
+		})
+		s.EqualValues(205, tsTo[1000])
+		s.LessOrEqual(tsTo[1001], barrier)
+	})
+}
+
+func (s *BFWriteBufferSuite) TestCheckpointUnblockCallback() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	type advance struct {
+		oldCP, newCP *msgpb.MsgPosition
+	}
+	var advances []advance
+	var wb WriteBuffer
+	option := &writeBufferOption{syncPolicies: []SyncPolicy{GetFlushingSegmentsPolicy(metaCache)}}
+	WithCheckpointUnblockCallback(func(oldCP, newCP *msgpb.MsgPosition) {
+		advances = append(advances, advance{oldCP, newCP})
+		// callback runs outside write buffer lock
+		s.Empty(wb.Reconcile())
+	})(option)
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, option)
+	s.Require().NoError(err)
+
+	idAllocator := allocator.NewMockGIDAllocator()
+	idAllocator.AllocF = func(count uint32) (int64, int64, error) {
+		return time.Now().Unix(), int64(count), nil
+	}
+	idAllocator.AllocOneF = func() (int64, error) {
+		return time.Now().Unix(), nil
+	}
+	chunkManager := mocks.NewChunkManager(s.T())
+	chunkManager.EXPECT().RootPath().Return("files").Maybe()
+	chunkManager.EXPECT().MultiWrite(mock.Anything, mock.Anything).Return(nil).Maybe()
+	s.syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, task syncmgr.Task) *conc.Future[error] {
+		task.(*syncmgr.SyncTask).WithAllocator(idAllocator).WithChunkManager(chunkManager)
+		err := task.Run()
+		return conc.Go(func() (error, error) { return err, nil })
+	})
+	s.syncMgr.EXPECT().GetEarliestPosition(s.channelName).Return(0, nil)
+
+	_, msg := s.composeInsertMsg(1000, 10, 128)
+	err = wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	s.Require().NoError(err)
+	_, msg = s.composeInsertMsg(1001, 10, 128)
+	err = wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 200}, &msgpb.MsgPosition{Timestamp: 300})
+	s.Require().NoError(err)
+
+	s.EqualValues(100, wb.GetCheckpoint().GetTimestamp())
+	s.Empty(advances)
+
+	// flush segment holding the earliest position
+	s.Require().NoError(wb.FlushSegments(context.Background(), []int64{1000}))
+	err = wb.BufferData(nil, nil, &msgpb.MsgPosition{Timestamp: 300}, &msgpb.MsgPosition{Timestamp: 400})
+	s.Require().NoError(err)
+	s.False(wb.HasSegment(1000))
+
+	s.EqualValues(200, wb.GetCheckpoint().GetTimestamp())
+	s.Require().Len(advances, 1)
+	s.EqualValues(100, advances[0].oldCP.GetTimestamp())
+	s.EqualValues(200, advances[0].newCP.GetTimestamp())
+
+	// checkpoint unchanged
+	s.EqualValues(200, wb.GetCheckpoint().GetTimestamp())
+	s.Len(advances, 1)
+}
+
+func (s *BFWriteBufferSuite) TestBufferDataFieldMismatch() {
+	wb, err := NewBFWriteBuffer(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+	s.Require().NoError(err)
+
+	// rejected before segment lookup, no metacache call expected
+	_, msg := s.composeInsertMsg(1000, 10, 64)
+	err = wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	s.ErrorIs(err, ErrFieldDataMismatch)
+
+	_, msg = s.composeInsertMsg(1000, 10, 128)
+	msg.FieldsData[2].Type = schemapb.DataType_VarChar
+	err = wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	s.ErrorIs(err, ErrFieldDataMismatch)
+
+	_, msg = s.composeInsertMsg(1000, 10, 128)
+	msg.FieldsData[2].FieldId = 999
+	err = wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	s.ErrorIs(err, ErrFieldDataMismatch)
+	s.False(wb.HasSegment(1000))
+}
+
+func (s *BFWriteBufferSuite) TestBufferDataSchemaDrift() {
+	composeDriftMsg := func() *msgstream.InsertMsg {
+		tss, msg := s.composeInsertMsg(1000, 10, 128)
+		// field of newer schema
+		msg.FieldsData = append(msg.FieldsData, &schemapb.FieldData{
+			FieldId: 999, FieldName: "new_field", Type: schemapb.DataType_Int64,
+			Field: &schemapb.FieldData_Scalars{
+				Scalars: &schemapb.ScalarField{
+					Data: &schemapb.ScalarField_LongData{LongData: &schemapb.LongArray{Data: tss}},
+				},
+			},
+		})
+		return msg
+	}
+
+	s.Run("reject", func() {
+		wb, err := NewBFWriteBuffer(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+		s.Require().NoError(err)
+
+		err = wb.BufferData([]*msgstream.InsertMsg{composeDriftMsg()}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+		s.ErrorIs(err, ErrSchemaDrift)
+		s.ErrorIs(err, ErrFieldDataMismatch)
+		s.False(wb.HasSegment(1000))
+	})
+
+	s.Run("drop_unknown_fields", func() {
+		metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+			Schema: s.collSchema,
+			Vchan: &datapb.VchannelInfo{
+				CollectionID: s.collID,
+				ChannelName:  s.channelName,
+			},
+		}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+		option := &writeBufferOption{}
+		WithDropUnknownFields()(option)
+		wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, option)
+		s.Require().NoError(err)
+
+		s.NoError(wb.BufferData([]*msgstream.InsertMsg{composeDriftMsg()}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200}))
+		s.True(wb.HasSegment(1000))
+		buffer := wb.(*bfWriteBuffer).buffers[1000].insertBuffer.buffer
+		s.Equal(10, buffer.GetRowNum())
+		s.NotContains(buffer.Data, int64(999))
+
+		// known fields are still validated
+		_, msg := s.composeInsertMsg(1000, 10, 64)
+		err = wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 200}, &msgpb.MsgPosition{Timestamp: 300})
+		s.ErrorIs(err, ErrFieldDataMismatch)
+		s.NotErrorIs(err, ErrSchemaDrift)
+	})
+}
+
+func (s *BFWriteBufferSuite) TestDimValidation() {
+	// vector field data with declared dim matched but one vector missing
+	composeMisalignedMsg := func() *msgstream.InsertMsg {
+		_, msg := s.composeInsertMsg(1000, 10, 128)
+		vectors := msg.FieldsData[3].GetVectors().GetFloatVector()
+		vectors.Data = vectors.Data[:9*128]
+		return msg
+	}
+
+	s.Run("enabled_by_default", func() {
+		wb, err := NewBFWriteBuffer(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+		s.Require().NoError(err)
+
+		err = wb.BufferData([]*msgstream.InsertMsg{composeMisalignedMsg()}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+		s.ErrorIs(err, ErrDimMismatch)
+		s.ErrorIs(err, ErrFieldDataMismatch)
+
+		_, msg := s.composeInsertMsg(1000, 10, 64)
+		err = wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+		s.ErrorIs(err, ErrDimMismatch)
+		s.False(wb.HasSegment(1000))
+	})
+
+	s.Run("disabled", func() {
+		option := &writeBufferOption{}
+		WithDimValidation(false)(option)
+		validator := newFieldValidator(s.collSchema, option.dropUnknownFields, !option.skipDimValidation)
+
+		s.NoError(validator.validate(composeMisalignedMsg()))
+		_, msg := s.composeInsertMsg(1000, 10, 64)
+		s.NoError(validator.validate(msg))
+		// data type still checked
+		msg.FieldsData[2].Type = schemapb.DataType_VarChar
+		s.ErrorIs(validator.validate(msg), ErrFieldDataMismatch)
+	})
+}
+
+func (s *BFWriteBufferSuite) TestFlushEventSink() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	flushingPolicy := GetFlushingSegmentsPolicy(metaCache)
+	var events []FlushEvent
+	option := &writeBufferOption{syncPolicies: []SyncPolicy{flushingPolicy}}
+	WithFlushEventSink(func(event FlushEvent) {
+		events = append(events, event)
+	})(option)
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, option)
+	s.Require().NoError(err)
+
+	idAllocator := allocator.NewMockGIDAllocator()
+	idAllocator.AllocF = func(count uint32) (int64, int64, error) {
+		return time.Now().Unix(), int64(count), nil
+	}
+	idAllocator.AllocOneF = func() (int64, error) {
+		return time.Now().Unix(), nil
+	}
+	chunkManager := mocks.NewChunkManager(s.T())
+	chunkManager.EXPECT().RootPath().Return("files").Maybe()
+	chunkManager.EXPECT().MultiWrite(mock.Anything, mock.Anything).Return(nil).Maybe()
+	s.syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, task syncmgr.Task) *conc.Future[error] {
+		task.(*syncmgr.SyncTask).WithAllocator(idAllocator).WithChunkManager(chunkManager)
+		err := task.Run()
+		return conc.Go(func() (error, error) { return err, nil })
+	})
+
+	tss := make(map[int64][]int64)
+	var msgs []*msgstream.InsertMsg
+	for i, segmentID := range []int64{1000, 1001} {
+		segmentTss, msg := s.composeInsertMsg(segmentID, 10*(i+1), 128)
+		tss[segmentID] = segmentTss
+		msgs = append(msgs, msg)
+	}
+	err = wb.BufferData(msgs, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	s.Require().NoError(err)
+	s.Empty(events)
+
+	start := time.Now()
+	for i, segmentID := range []int64{1000, 1001} {
+		s.Require().NoError(wb.FlushSegments(context.Background(), []int64{segmentID}))
+		err = wb.BufferData(nil, nil, &msgpb.MsgPosition{Timestamp: 200}, &msgpb.MsgPosition{Timestamp: 300})
+		s.Require().NoError(err)
+
+		s.Require().Len(events, i+1)
+		event := events[i]
+		s.Equal(segmentID, event.SegmentID)
+		s.EqualValues(10*(i+1), event.Rows)
+		s.Greater(event.Bytes, int64(0))
+		s.EqualValues(lo.Min(tss[segmentID]), event.TimestampFrom)
+		s.EqualValues(lo.Max(tss[segmentID]), event.TimestampTo)
+		s.Equal(flushingPolicy.Reason(), event.Reason)
+		s.False(event.Timestamp.Before(start))
+	}
+	s.Greater(events[1].Bytes, events[0].Bytes)
+}
+
+func (s *BFWriteBufferSuite) TestCloseJoinsBackground() {
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	bufferData := func(wb WriteBuffer) error {
+		_, msg := s.composeInsertMsg(1000, 10, 128)
+		return wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	}
+	_, msg := s.composeInsertMsg(1000, 10, 128)
+
+	baseline := runtime.NumGoroutine()
+	option := &writeBufferOption{}
+	WithAutoSyncInterval(10 * time.Millisecond)(option)
+	// second batch blocks for quota
+	WithIngestRateLimit(int64(msg.Size()))(option)
+	WithIngestRateLimitBlocking()(option)
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, option)
+	s.Require().NoError(err)
+	sizeCh := wb.WatchBufferSize()
+
+	s.Require().NoError(bufferData(wb))
+	blocked := make(chan error, 1)
+	go func() {
+		blocked <- bufferData(wb)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	s.NoError(wb.Close(false))
+	s.ErrorIs(<-blocked, ErrBufferClosed)
+	for range sizeCh {
+	}
+	s.Eventually(func() bool { return runtime.NumGoroutine() <= baseline }, time.Second, 10*time.Millisecond)
+
+	// no background goroutine is started once closed
+	bfWb := wb.(*bfWriteBuffer)
+	bfWb.mut.Lock()
+	s.False(bfWb.runBackground(func(<-chan struct{}) { s.Fail("background goroutine started after close") }))
+	bfWb.mut.Unlock()
+}
+
+func (s *BFWriteBufferSuite) TestEstimateFlushSize() {
+	wb, err := NewBFWriteBuffer(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+	s.Require().NoError(err)
+	base := wb.(*bfWriteBuffer).writeBufferBase
+
+	s.EqualValues(0, wb.EstimateFlushSize(1000))
+
+	_, msg := s.composeInsertMsg(1000, 1000, 128)
+	_, err = base.getOrCreateBuffer(1000).insertBuffer.Buffer([]*msgstream.InsertMsg{msg}, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	s.Require().NoError(err)
+
+	codec := storage.NewInsertCodecWithSchema(&etcdpb.CollectionMeta{ID: s.collID, Schema: s.collSchema})
+	blobs, err := codec.Serialize(1, 1000, base.buffers[1000].insertBuffer.buffer)
+	s.Require().NoError(err)
+	var actual int64
+	for _, blob := range blobs {
+		actual += int64(len(blob.GetValue()))
+	}
+
+	estimate := wb.EstimateFlushSize(1000)
+	s.InEpsilon(actual, estimate, 0.3)
+}
+
+func (s *BFWriteBufferSuite) TestSyncDeduplication() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	// automatic policy selecting every buffered segment
+	allPolicy := wrapSelectSegmentFuncPolicy(func(buffers []*segmentBuffer, _ uint64) []int64 {
+		return lo.Map(buffers, func(buf *segmentBuffer, _ int) int64 { return buf.segmentID })
+	}, "all")
+	option := &writeBufferOption{syncPolicies: []SyncPolicy{allPolicy, GetFlushingSegmentsPolicy(metaCache)}}
+	WithSyncDeduplication()(option)
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, option)
+	s.Require().NoError(err)
+
+	idAllocator := allocator.NewMockGIDAllocator()
+	idAllocator.AllocF = func(count uint32) (int64, int64, error) {
+		return time.Now().Unix(), int64(count), nil
+	}
+	idAllocator.AllocOneF = func() (int64, error) {
+		return time.Now().Unix(), nil
+	}
+	chunkManager := mocks.NewChunkManager(s.T())
+	chunkManager.EXPECT().RootPath().Return("files").Maybe()
+	chunkManager.EXPECT().MultiWrite(mock.Anything, mock.Anything).Return(nil).Maybe()
+	release := make(chan struct{})
+	var mut sync.Mutex
+	var futures []*conc.Future[error]
+	s.syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, task syncmgr.Task) *conc.Future[error] {
+		f := conc.Go(func() (error, error) {
+			<-release
+			task.(*syncmgr.SyncTask).WithAllocator(idAllocator).WithChunkManager(chunkManager)
+			return task.Run(), nil
+		})
+		mut.Lock()
+		defer mut.Unlock()
+		futures = append(futures, f)
+		return f
+	})
+	submitted := func() int {
+		mut.Lock()
+		defer mut.Unlock()
+		return len(futures)
+	}
+
+	_, msg := s.composeInsertMsg(1000, 10, 128)
+	s.Require().NoError(wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200}))
+	s.Require().Equal(1, submitted())
+
+	// data buffered while sync task pending stays in buffer
+	_, msg = s.composeInsertMsg(1000, 10, 128)
+	s.Require().NoError(wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 200}, &msgpb.MsgPosition{Timestamp: 300}))
+	s.Equal(1, submitted())
+	s.True(wb.HasSegment(1000))
+
+	// explicit flush & automatic sync at the same time
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		s.NoError(wb.FlushSegments(context.Background(), []int64{1000}))
+	}()
+	go func() {
+		defer wg.Done()
+		s.NoError(wb.BufferData(nil, nil, &msgpb.MsgPosition{Timestamp: 300}, &msgpb.MsgPosition{Timestamp: 400}))
+	}()
+	wg.Wait()
+	s.Equal(1, submitted())
+
+	close(release)
+	mut.Lock()
+	first := futures[0]
+	mut.Unlock()
+	_, err = first.Await()
+	s.Require().NoError(err)
+
+	// segment is synced again once previous task finished
+	s.Require().NoError(wb.BufferData(nil, nil, &msgpb.MsgPosition{Timestamp: 400}, &msgpb.MsgPosition{Timestamp: 500}))
+	s.Equal(2, submitted())
+	s.False(wb.HasSegment(1000))
+}
+
+func (s *BFWriteBufferSuite) TestPreSyncTransform() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	seg := metacache.NewSegmentInfo(&datapb.SegmentInfo{ID: 1000, State: commonpb.SegmentState_Growing}, metacache.NewBloomFilterSet())
+	s.metacache.EXPECT().GetSegmentByID(int64(1000)).Return(seg, true)
+	s.metacache.EXPECT().UpdateSegments(mock.Anything, mock.Anything).Return()
+
+	transformErr := errors.New("mock transform error")
+	var calls int
+	var transformed *storage.InsertData
+	option := &writeBufferOption{}
+	WithPreSyncTransform(func(segmentID int64, insert *storage.InsertData, delete *storage.DeleteData) error {
+		calls++
+		if calls == 1 {
+			return transformErr
+		}
+		s.EqualValues(1000, segmentID)
+		s.NotNil(delete)
+		// mask vector field
+		vectors := insert.Data[101].(*storage.FloatVectorFieldData)
+		for i := range vectors.Data {
+			vectors.Data[i] = 0
+		}
+		transformed = insert
+		return nil
+	})(option)
+	wb, err := NewBFWriteBuffer(s.channelName, s.metacache, nil, s.syncMgr, option)
+	s.Require().NoError(err)
+	base := wb.(*bfWriteBuffer).writeBufferBase
+
+	pks, msg := s.composeInsertMsg(1000, 10, 128)
+	buffer := base.getOrCreateBuffer(1000)
+	_, err = buffer.insertBuffer.Buffer([]*msgstream.InsertMsg{msg}, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	s.Require().NoError(err)
+	buffer.deltaBuffer.Buffer([]storage.PrimaryKey{storage.NewInt64PrimaryKey(pks[0])}, []uint64{uint64(pks[0])},
+		&msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	buffered := buffer.insertBuffer.buffer
+
+	// failed transform aborts sync and keeps data buffered
+	_, err = base.getSyncTask(context.Background(), 1000)
+	s.Error(err)
+	s.NotErrorIs(err, merr.ErrSegmentNotFound)
+	s.True(wb.HasSegment(1000))
+
+	task, err := base.getSyncTask(context.Background(), 1000)
+	s.Require().NoError(err)
+	s.Require().NotNil(task)
+	s.False(wb.HasSegment(1000))
+	s.Equal(2, calls)
+	// yielded insert data is transformed
+	s.Same(buffered, transformed)
+	s.True(lo.EveryBy(buffered.Data[101].(*storage.FloatVectorFieldData).Data, func(v float32) bool { return v == 0 }))
+}
+
+func (s *BFWriteBufferSuite) TestDeletePruning() {
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+
+	// buffers inserts & deletes, returns delete data yielded for sync and the earliest insert timestamp
+	bufferData := func(option *writeBufferOption) (*storage.DeleteData, uint64) {
+		wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, option)
+		s.Require().NoError(err)
+
+		pks, msg := s.composeInsertMsg(1000, 10, 128)
+		s.Require().NoError(wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200}))
+
+		// first 3 deletes are older than all inserted rows
+		delMsg := s.composeDeleteMsg(lo.Map(pks[:5], func(pk int64, _ int) storage.PrimaryKey { return storage.NewInt64PrimaryKey(pk) }))
+		for i := 0; i < 3; i++ {
+			delMsg.Timestamps[i] = tsoutil.ComposeTSByTime(time.Now().Add(-time.Hour), int64(i))
+		}
+		s.Require().NoError(wb.BufferData(nil, []*msgstream.DeleteMsg{delMsg}, &msgpb.MsgPosition{Timestamp: 200}, &msgpb.MsgPosition{Timestamp: 300}))
+
+		base := wb.(*bfWriteBuffer).writeBufferBase
+		base.mut.Lock()
+		defer base.mut.Unlock()
+		_, _, delta, _, _, err := base.yieldBuffer(1000, false)
+		s.Require().NoError(err)
+		s.Require().NotNil(delta)
+		metaCache.RemoveSegments(metacache.WithSegmentIDs(1000))
+		return delta, lo.Min(msg.GetTimestamps())
+	}
+
+	s.Run("pruning_enabled", func() {
+		option := &writeBufferOption{}
+		WithDeletePruning()(option)
+		delta, watermark := bufferData(option)
+		s.EqualValues(2, delta.RowCount)
+		for _, ts := range delta.Tss {
+			s.GreaterOrEqual(ts, watermark)
+		}
+	})
+
+	s.Run("pruning_disabled", func() {
+		delta, _ := bufferData(&writeBufferOption{})
+		s.EqualValues(5, delta.RowCount)
+	})
+}
+
+func (s *BFWriteBufferSuite) TestSegmentPartition() {
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{})
+	s.Require().NoError(err)
+
+	_, msg := s.composeInsertMsg(1000, 10, 128)
+	msg.PartitionID = 10
+	s.Require().NoError(wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200}))
+
+	partitionID, ok := wb.SegmentPartition(1000)
+	s.True(ok)
+	s.EqualValues(10, partitionID)
+
+	partitionID, ok = wb.SegmentPartition(1001)
+	s.False(ok)
+	s.EqualValues(0, partitionID)
+}
+
+func (s *BFWriteBufferSuite) TestDeletesAcrossFlushingTransition() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+
+	for _, coupled := range []bool{true, false} {
+		s.Run(fmt.Sprintf("coupled_%t", coupled), func() {
+			metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+				Schema: s.collSchema,
+				Vchan: &datapb.VchannelInfo{
+					CollectionID: s.collID,
+					ChannelName:  s.channelName,
+				},
+			}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+			syncMgr := syncmgr.NewMockSyncManager(s.T())
+			var tasks int
+			syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, _ syncmgr.Task) *conc.Future[error] {
+				tasks++
+				return conc.Go(func() (error, error) { return nil, nil })
+			})
+
+			// count deletes yielded into sync tasks
+			var synced int64
+			option := &writeBufferOption{syncPolicies: []SyncPolicy{GetFlushingSegmentsPolicy(metaCache)}}
+			WithCoupledDeltaFlush(coupled)(option)
+			WithPreSyncTransform(func(_ int64, _ *storage.InsertData, delete *storage.DeleteData) error {
+				if delete != nil {
+					synced += delete.RowCount
+				}
+				return nil
+			})(option)
+			wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, syncMgr, option)
+			s.Require().NoError(err)
+
+			pks, msg := s.composeInsertMsg(1000, 10, 128)
+			deleteMsg := func(from, to int) []*msgstream.DeleteMsg {
+				return []*msgstream.DeleteMsg{s.composeDeleteMsg(lo.Map(pks[from:to], func(pk int64, _ int) storage.PrimaryKey { return storage.NewInt64PrimaryKey(pk) }))}
+			}
+
+			// deletes buffered while segment growing
+			s.Require().NoError(wb.BufferData([]*msgstream.InsertMsg{msg}, deleteMsg(0, 3), &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200}))
+			s.Equal(0, tasks)
+			s.Require().NoError(wb.FlushSegments(context.Background(), []int64{1000}))
+
+			// deletes buffered after transition go with the flush task
+			s.Require().NoError(wb.BufferData(nil, deleteMsg(3, 6), &msgpb.MsgPosition{Timestamp: 200}, &msgpb.MsgPosition{Timestamp: 300}))
+			s.Equal(1, tasks)
+			s.EqualValues(6, synced)
+
+			// deletes arriving after flush task submitted go with the next task
+			s.Require().NoError(wb.BufferData(nil, deleteMsg(6, 8), &msgpb.MsgPosition{Timestamp: 300}, &msgpb.MsgPosition{Timestamp: 400}))
+			s.Equal(2, tasks)
+			s.EqualValues(8, synced)
+			s.False(wb.HasSegment(1000))
+		})
+	}
+}
+
+func (s *BFWriteBufferSuite) TestPostFlushVerify() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	idAllocator := allocator.NewMockGIDAllocator()
+	idAllocator.AllocF = func(count uint32) (int64, int64, error) {
+		return time.Now().Unix(), int64(count), nil
+	}
+	idAllocator.AllocOneF = func() (int64, error) {
+		return time.Now().Unix(), nil
+	}
+	chunkManager := mocks.NewChunkManager(s.T())
+	chunkManager.EXPECT().RootPath().Return("files").Maybe()
+	chunkManager.EXPECT().MultiWrite(mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	// returns sync task of 10 buffered rows verified by reader
+	syncTask := func(reader FlushVerifyReader) (WriteBuffer, syncmgr.Task) {
+		metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+			Schema: s.collSchema,
+			Vchan: &datapb.VchannelInfo{
+				CollectionID: s.collID,
+				ChannelName:  s.channelName,
+			},
+		}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+		option := &writeBufferOption{}
+		WithPostFlushVerify(reader)(option)
+		wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, option)
+		s.Require().NoError(err)
+
+		_, msg := s.composeInsertMsg(1000, 10, 128)
+		s.Require().NoError(wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200}))
+		task, err := wb.(*bfWriteBuffer).getSyncTask(context.Background(), 1000)
+		s.Require().NoError(err)
+		s.Require().NotNil(task)
+		return wb, task.(*syncmgr.SyncTask).WithAllocator(idAllocator).WithChunkManager(chunkManager)
+	}
+
+	s.Run("row_count_matched", func() {
+		var verified bool
+		_, task := syncTask(func(_ context.Context, segmentID int64, binlogs map[int64]*datapb.FieldBinlog) (int64, error) {
+			verified = true
+			s.EqualValues(1000, segmentID)
+			s.NotEmpty(binlogs)
+			return binlogs[common.RowIDField].GetBinlogs()[0].GetEntriesNum(), nil
+		})
+		s.NotPanics(func() { s.NoError(task.Run()) })
+		s.True(verified)
+	})
+
+	s.Run("row_count_mismatched", func() {
+		wb, task := syncTask(func(_ context.Context, _ int64, _ map[int64]*datapb.FieldBinlog) (int64, error) {
+			return 9, nil
+		})
+		s.NotPanics(func() { s.NoError(task.Run()) })
+		s.ErrorIs(wb.LastSyncError(1000), merr.ErrServiceInternal)
+	})
+
+	s.Run("read_failed", func() {
+		wb, task := syncTask(func(_ context.Context, _ int64, _ map[int64]*datapb.FieldBinlog) (int64, error) {
+			return 0, errors.New("mock read error")
+		})
+		s.NotPanics(func() { s.NoError(task.Run()) })
+		s.Error(wb.LastSyncError(1000))
+	})
+}
+
+func (s *BFWriteBufferSuite) TestMaxBufferedPartitions() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	syncMgr := syncmgr.NewMockSyncManager(s.T())
+	var synced []int64
+	syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, task syncmgr.Task) *conc.Future[error] {
+		synced = append(synced, task.SegmentID())
+		return conc.Go(func() (error, error) { return nil, nil })
+	})
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, syncMgr, &writeBufferOption{maxBufferedPartitions: 2})
+	s.Require().NoError(err)
+
+	bufferPartition := func(segmentID, partitionID int64) {
+		_, msg := s.composeInsertMsg(segmentID, 10, 128)
+		msg.PartitionID = partitionID
+		s.Require().NoError(wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200}))
+	}
+
+	bufferPartition(1000, 10)
+	bufferPartition(1001, 10)
+	bufferPartition(2000, 20)
+	s.Equal(2, wb.BufferedPartitionCount())
+	s.Empty(synced)
+
+	// partition 10 is written again, partition 20 becomes the least recently written one
+	bufferPartition(1000, 10)
+	bufferPartition(3000, 30)
+	s.Equal([]int64{2000}, synced)
+	s.Equal(2, wb.BufferedPartitionCount())
+	s.True(wb.HasSegment(1000))
+	s.True(wb.HasSegment(1001))
+	s.True(wb.HasSegment(3000))
+
+	bufferPartition(4000, 40)
+	s.Equal([]int64{2000, 1000, 1001}, synced)
+	s.Equal(2, wb.BufferedPartitionCount())
+}
+
+func (s *BFWriteBufferSuite) TestDrainSegment() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	newWriteBuffer := func(syncMgr syncmgr.SyncManager) (WriteBuffer, metacache.MetaCache) {
+		metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+			Schema: s.collSchema,
+			Vchan: &datapb.VchannelInfo{
+				CollectionID: s.collID,
+				ChannelName:  s.channelName,
+			},
+		}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+		wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, syncMgr, &writeBufferOption{})
+		s.Require().NoError(err)
+		for _, segmentID := range []int64{1000, 1001} {
+			_, msg := s.composeInsertMsg(segmentID, 10, 128)
+			s.Require().NoError(wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200}))
+		}
+		return wb, metaCache
+	}
+
+	s.Run("normal", func() {
+		syncMgr := syncmgr.NewMockSyncManager(s.T())
+		var drained []*syncmgr.SyncTask
+		done := atomic.NewBool(false)
+		syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, task syncmgr.Task) *conc.Future[error] {
+			drained = append(drained, task.(*syncmgr.SyncTask))
+			return conc.Go(func() (error, error) {
+				time.Sleep(10 * time.Millisecond)
+				done.Store(true)
+				return nil, nil
+			})
+		}).Once()
+		wb, metaCache := newWriteBuffer(syncMgr)
+
+		s.NoError(wb.DrainSegment(context.Background(), 1000))
+		s.True(done.Load())
+		s.Require().Len(drained, 1)
+		s.EqualValues(1000, drained[0].SegmentID())
+		s.False(wb.HasSegment(1000))
+		segment, ok := metaCache.GetSegmentByID(1000)
+		s.Require().True(ok)
+		s.Equal(commonpb.SegmentState_Flushing, segment.State())
+		// other segments stay buffered
+		s.True(wb.HasSegment(1001))
+		segment, ok = metaCache.GetSegmentByID(1001)
+		s.Require().True(ok)
+		s.Equal(commonpb.SegmentState_Growing, segment.State())
+	})
+
+	s.Run("sync_failed", func() {
+		syncMgr := syncmgr.NewMockSyncManager(s.T())
+		syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).Return(conc.Go(func() (error, error) {
+			return errors.New("mocked"), nil
+		})).Once()
+		wb, _ := newWriteBuffer(syncMgr)
+
+		s.Error(wb.DrainSegment(context.Background(), 1000))
+	})
+
+	s.Run("context_canceled", func() {
+		syncMgr := syncmgr.NewMockSyncManager(s.T())
+		block := make(chan struct{})
+		defer close(block)
+		syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).Return(conc.Go(func() (error, error) {
+			<-block
+			return nil, nil
+		})).Once()
+		wb, _ := newWriteBuffer(syncMgr)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		s.ErrorIs(wb.DrainSegment(ctx, 1000), context.DeadlineExceeded)
+	})
+
+	s.Run("unknown_segment", func() {
+		wb, _ := newWriteBuffer(syncmgr.NewMockSyncManager(s.T()))
+
+		s.ErrorIs(wb.DrainSegment(context.Background(), 2000), merr.ErrSegmentNotFound)
+	})
+}
+
+func (s *BFWriteBufferSuite) TestLastSyncError() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	idAllocator := allocator.NewMockGIDAllocator()
+	idAllocator.AllocF = func(count uint32) (int64, int64, error) {
+		return time.Now().Unix(), int64(count), nil
+	}
+	idAllocator.AllocOneF = func() (int64, error) {
+		return time.Now().Unix(), nil
+	}
+	chunkManager := mocks.NewChunkManager(s.T())
+	chunkManager.EXPECT().RootPath().Return("files").Maybe()
+	chunkManager.EXPECT().MultiWrite(mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	// sync failure is simulated by post flush verification
+	mockErr := errors.New("mock read error")
+	var fail bool
+	option := &writeBufferOption{}
+	WithPostFlushVerify(func(_ context.Context, _ int64, binlogs map[int64]*datapb.FieldBinlog) (int64, error) {
+		if fail {
+			return 0, mockErr
+		}
+		return binlogs[common.RowIDField].GetBinlogs()[0].GetEntriesNum(), nil
+	})(option)
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, option)
+	s.Require().NoError(err)
+	syncTask := func() syncmgr.Task {
+		_, msg := s.composeInsertMsg(1000, 10, 128)
+		s.Require().NoError(wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200}))
+		task, err := wb.(*bfWriteBuffer).getSyncTask(context.Background(), 1000)
+		s.Require().NoError(err)
+		s.Require().NotNil(task)
+		return task.(*syncmgr.SyncTask).WithAllocator(idAllocator).WithChunkManager(chunkManager)
+	}
+
+	s.NoError(wb.LastSyncError(1000))
+
+	fail = true
+	task := syncTask()
+	s.NotPanics(func() { s.NoError(task.Run()) })
+	s.ErrorIs(wb.LastSyncError(1000), mockErr)
+	// other segments unaffected
+	s.NoError(wb.LastSyncError(1001))
+
+	fail = false
+	task = syncTask()
+	s.NotPanics(func() { s.NoError(task.Run()) })
+	s.NoError(wb.LastSyncError(1000))
+}
+
+func (s *BFWriteBufferSuite) TestConservativeCheckpoint() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	idAllocator := allocator.NewMockGIDAllocator()
+	idAllocator.AllocF = func(count uint32) (int64, int64, error) {
+		return time.Now().Unix(), int64(count), nil
+	}
+	idAllocator.AllocOneF = func() (int64, error) {
+		return time.Now().Unix(), nil
+	}
+	chunkManager := mocks.NewChunkManager(s.T())
+	chunkManager.EXPECT().RootPath().Return("files").Maybe()
+	chunkManager.EXPECT().MultiWrite(mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	// returns write buffer with one segment buffered from 100 to 200 and its sync task
+	syncTask := func(opts ...WriteBufferOption) (WriteBuffer, syncmgr.Task) {
+		metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+			Schema: s.collSchema,
+			Vchan: &datapb.VchannelInfo{
+				CollectionID: s.collID,
+				ChannelName:  s.channelName,
+			},
+		}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+		// sync manager not tracking submitted task
+		syncMgr := syncmgr.NewMockSyncManager(s.T())
+		syncMgr.EXPECT().GetEarliestPosition(s.channelName).Return(0, nil)
+		option := &writeBufferOption{}
+		for _, opt := range opts {
+			opt(option)
+		}
+		wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, syncMgr, option)
+		s.Require().NoError(err)
+
+		_, msg := s.composeInsertMsg(1000, 10, 128)
+		s.Require().NoError(wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200}))
+		s.Require().EqualValues(100, wb.GetCheckpoint().GetTimestamp())
+		task, err := wb.(*bfWriteBuffer).getSyncTask(context.Background(), 1000)
+		s.Require().NoError(err)
+		s.Require().NotNil(task)
+		return wb, task.(*syncmgr.SyncTask).WithAllocator(idAllocator).WithChunkManager(chunkManager)
+	}
+
+	s.Run("conservative", func() {
+		wb, task := syncTask(WithConservativeCheckpoint())
+
+		// data yielded from buffer but not synced yet
+		s.False(wb.HasSegment(1000))
+		s.EqualValues(100, wb.GetCheckpoint().GetTimestamp())
+
+		s.NoError(task.Run())
+		s.EqualValues(200, wb.GetCheckpoint().GetTimestamp())
+	})
+
+	s.Run("failed_sync_holds_checkpoint", func() {
+		mockErr := errors.New("mock read error")
+		wb, task := syncTask(WithConservativeCheckpoint(), WithPostFlushVerify(func(_ context.Context, _ int64, _ map[int64]*datapb.FieldBinlog) (int64, error) {
+			return 0, mockErr
+		}))
+
+		s.NoError(task.Run())
+		s.ErrorIs(wb.LastSyncError(1000), mockErr)
+		s.EqualValues(100, wb.GetCheckpoint().GetTimestamp())
+	})
+
+	s.Run("live_positions", func() {
+		wb, _ := syncTask()
+
+		s.EqualValues(200, wb.GetCheckpoint().GetTimestamp())
+	})
+}
+
+func (s *BFWriteBufferSuite) TestSealSegment() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	syncMgr := syncmgr.NewMockSyncManager(s.T())
+	var synced []int64
+	syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, task syncmgr.Task) *conc.Future[error] {
+		synced = append(synced, task.SegmentID())
+		return conc.Go(func() (error, error) { return nil, nil })
+	})
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, syncMgr, &writeBufferOption{})
+	s.Require().NoError(err)
+	bufferSegment := func(segmentID int64, ts uint64) error {
+		_, msg := s.composeInsertMsg(segmentID, 10, 128)
+		return wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: ts}, &msgpb.MsgPosition{Timestamp: ts + 100})
+	}
+
+	s.Require().NoError(bufferSegment(1000, 100))
+	s.Require().NoError(bufferSegment(1001, 100))
+
+	s.Require().NoError(wb.SealSegment(1000))
+	s.Empty(synced)
+	segment, ok := metaCache.GetSegmentByID(1000)
+	s.Require().True(ok)
+	s.Equal(commonpb.SegmentState_Flushing, segment.State())
+
+	s.ErrorIs(bufferSegment(1000, 200), ErrSegmentSealed)
+	s.Empty(synced)
+
+	// next sync flushes sealed segment only
+	s.NoError(bufferSegment(1001, 300))
+	s.Equal([]int64{1000}, synced)
+	s.False(wb.HasSegment(1000))
+	s.True(wb.HasSegment(1001))
+
+	// still rejected after flushed
+	s.ErrorIs(bufferSegment(1000, 400), ErrSegmentSealed)
+	s.False(wb.HasSegment(1000))
+}
+
+func (s *BFWriteBufferSuite) TestSealSegmentUnsealedAfterFlush() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{})
+	s.Require().NoError(err)
+	base := wb.(*bfWriteBuffer).writeBufferBase
+
+	idAllocator := allocator.NewMockGIDAllocator()
+	idAllocator.AllocF = func(count uint32) (int64, int64, error) {
+		return time.Now().Unix(), int64(count), nil
+	}
+	idAllocator.AllocOneF = func() (int64, error) {
+		return time.Now().Unix(), nil
+	}
+	chunkManager := mocks.NewChunkManager(s.T())
+	chunkManager.EXPECT().RootPath().Return("files").Maybe()
+	chunkManager.EXPECT().MultiWrite(mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	_, msg := s.composeInsertMsg(1000, 10, 128)
+	s.Require().NoError(wb.BufferData([]*msgstream.InsertMsg{msg}, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200}))
+	s.Require().NoError(wb.SealSegment(1000))
+	s.True(base.sealedSegments.Contain(1000))
+
+	task, err := base.getSyncTask(context.Background(), 1000)
+	s.Require().NoError(err)
+	s.Require().NoError(task.(*syncmgr.SyncTask).WithAllocator(idAllocator).WithChunkManager(chunkManager).Run())
+	s.False(base.sealedSegments.Contain(1000))
+
+	s.Require().NoError(wb.Close(false))
+	s.ErrorIs(wb.SealSegment(1000), ErrBufferClosed)
+}
+
+func TestBFWriteBuffer(t *testing.T) {
+	suite.Run(t, new(BFWriteBufferSuite))
+}
+
+func benchmarkBufferData(b *testing.B, grouped bool) {
+	s := &BFWriteBufferSuite{}
+	s.SetupSuite()
+	syncMgr := syncmgr.NewMockSyncManager(b)
+
+	var insertMsgs []*msgstream.InsertMsg
+	for i := 0; i < 100; i++ {
+		_, msg := s.composeInsertMsg(int64(1000+i%10), 10, 128)
+		insertMsgs = append(insertMsgs, msg)
+	}
+	insertBySegment := groupInsertMsgs(insertMsgs)
+	startPos, endPos := &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+			Schema: s.collSchema,
+			Vchan: &datapb.VchannelInfo{
+				CollectionID: s.collID,
+				ChannelName:  s.channelName,
+			},
+		}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+		wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, syncMgr, &writeBufferOption{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+
+		if grouped {
+			err = wb.BufferDataGrouped(insertBySegment, nil, startPos, endPos)
+		} else {
+			err = wb.BufferData(insertMsgs, nil, startPos, endPos)
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBufferData(b *testing.B) {
+	benchmarkBufferData(b, false)
+}
+
+func BenchmarkBufferDataGrouped(b *testing.B) {
+	benchmarkBufferData(b, true)
+}
+
+// newRowsMetaCache returns metacache with growing segments starting from 1000.
+func newRowsMetaCache(collSchema *schemapb.CollectionSchema, collID int64, channelName string, segmentNum int) metacache.MetaCache {
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: collID,
+			ChannelName:  channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	for i := 0; i < segmentNum; i++ {
+		metaCache.AddSegment(&datapb.SegmentInfo{
+			ID:            int64(1000 + i),
+			CollectionID:  collID,
+			InsertChannel: channelName,
+			State:         commonpb.SegmentState_Growing,
+		}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	}
+	return metaCache
+}
+
+// fillBenchRows returns fill callback appending rowCount rows with timestamp ts.
+func fillBenchRows(rowCount int, ts uint64) func(builder InsertBuilder) error {
+	return func(builder InsertBuilder) error {
+		for i := 0; i < rowCount; i++ {
+			for fieldID, value := range map[int64]any{
+				common.RowIDField:     int64(i),
+				common.TimeStampField: int64(ts),
+				100:                   int64(i),
+				101:                   lo.RepeatBy(128, func(_ int) float32 { return rand.Float32() }),
+			} {
+				if err := builder.Append(fieldID, value); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// BenchmarkBufferRowsParallel buffers rows into many segments in parallel,
+// which contend only on segments sharing the same lock stripe.
+func BenchmarkBufferRowsParallel(b *testing.B) {
+	const segmentNum = 256
+	s := &BFWriteBufferSuite{}
+	s.SetupSuite()
+	syncMgr := syncmgr.NewMockSyncManager(b)
+	syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).Return(nil).Maybe()
+	metaCache := newRowsMetaCache(s.collSchema, s.collID, s.channelName, segmentNum)
+	wb, err := NewBFWriteBuffer(s.channelName, metaCache, nil, syncMgr, &writeBufferOption{
+		syncPolicies: []SyncPolicy{GetFullBufferPolicy()},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	// keep buffers small so that memory is bounded by periodic sync
+	wb.SetThresholdMultiplier(0.01)
+
+	var next atomic.Int64
+	startPos, endPos := &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			segmentID := 1000 + next.Inc()%segmentNum
+			if err := wb.BufferRows(segmentID, fillBenchRows(10, 150), startPos, endPos); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
 }
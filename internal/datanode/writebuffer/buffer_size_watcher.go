@@ -0,0 +1,58 @@
+package writebuffer
+
+import (
+	"sync"
+)
+
+// bufferSizeWatcher emits total buffered size when it changes by more than delta since last emitted.
+// only the latest size is kept if the watcher does not consume in time.
+type bufferSizeWatcher struct {
+	mut sync.Mutex
+
+	delta  int64
+	last   int64
+	ch     chan int64
+	closed bool
+}
+
+func newBufferSizeWatcher(delta int64) *bufferSizeWatcher {
+	return &bufferSizeWatcher{
+		delta: delta,
+		ch:    make(chan int64, 1),
+	}
+}
+
+func (w *bufferSizeWatcher) Observe(size int64) {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	change := size - w.last
+	if change < 0 {
+		change = -change
+	}
+	if w.closed || change == 0 || change <= w.delta {
+		return
+	}
+	w.last = size
+
+	// drop stale size not consumed yet
+	select {
+	case <-w.ch:
+	default:
+	}
+	w.ch <- size
+}
+
+func (w *bufferSizeWatcher) Chan() <-chan int64 {
+	return w.ch
+}
+
+func (w *bufferSizeWatcher) Close() {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	if !w.closed {
+		w.closed = true
+		close(w.ch)
+	}
+}
@@ -0,0 +1,130 @@
+package writebuffer
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/cockroachdb/errors"
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/msgpb"
+	"github.com/milvus-io/milvus/pkg/log"
+	"github.com/milvus-io/milvus/pkg/util/merr"
+)
+
+// checkpointExportVersion is the version of exported checkpoint format,
+// it shall be bumped if any incompatible change is made to checkpointExport.
+const checkpointExportVersion = 1
+
+// checkpointExport is the stable format of exported write buffer checkpoint.
+type checkpointExport struct {
+	Version    int                `json:"version"`
+	Channel    string             `json:"channel"`
+	Checkpoint *positionExport    `json:"checkpoint,omitempty"`
+	Segments   []*segmentPosition `json:"segments"`
+}
+
+type segmentPosition struct {
+	SegmentID int64           `json:"segment_id"`
+	Position  *positionExport `json:"position"`
+}
+
+type positionExport struct {
+	ChannelName string `json:"channel_name"`
+	MsgID       []byte `json:"msg_id"`
+	MsgGroup    string `json:"msg_group"`
+	Timestamp   uint64 `json:"timestamp"`
+}
+
+func newPositionExport(pos *msgpb.MsgPosition) *positionExport {
+	if pos == nil {
+		return nil
+	}
+	return &positionExport{
+		ChannelName: pos.GetChannelName(),
+		MsgID:       pos.GetMsgID(),
+		MsgGroup:    pos.GetMsgGroup(),
+		Timestamp:   pos.GetTimestamp(),
+	}
+}
+
+func (p *positionExport) toMsgPosition() *msgpb.MsgPosition {
+	if p == nil {
+		return nil
+	}
+	return &msgpb.MsgPosition{
+		ChannelName: p.ChannelName,
+		MsgID:       p.MsgID,
+		MsgGroup:    p.MsgGroup,
+		Timestamp:   p.Timestamp,
+	}
+}
+
+// ExportCheckpoint serializes channel checkpoint and earliest positions of buffered segments.
+func (wb *writeBufferBase) ExportCheckpoint() ([]byte, error) {
+	wb.mut.RLock()
+	defer wb.mut.RUnlock()
+
+	export := &checkpointExport{
+		Version:    checkpointExportVersion,
+		Channel:    wb.channelName,
+		Checkpoint: newPositionExport(wb.checkpoint),
+		Segments:   make([]*segmentPosition, 0, len(wb.buffers)),
+	}
+	for segmentID, buf := range wb.buffers {
+		pos := wb.earliestPosition(buf)
+		if pos == nil {
+			continue
+		}
+		export.Segments = append(export.Segments, &segmentPosition{
+			SegmentID: segmentID,
+			Position:  newPositionExport(pos),
+		})
+	}
+	sort.Slice(export.Segments, func(i, j int) bool {
+		return export.Segments[i].SegmentID < export.Segments[j].SegmentID
+	})
+
+	return json.Marshal(export)
+}
+
+// ImportCheckpoint restores channel checkpoint and segment earliest positions exported by ExportCheckpoint.
+// segment positions are kept in empty segment buffers so that channel checkpoint will not pass them.
+// Positions of segments not in metacache are skipped, since their buffers could never be synced.
+func (wb *writeBufferBase) ImportCheckpoint(data []byte) error {
+	export := &checkpointExport{}
+	if err := json.Unmarshal(data, export); err != nil {
+		return errors.Wrap(err, "failed to parse exported checkpoint")
+	}
+	if export.Version != checkpointExportVersion {
+		return merr.WrapErrParameterInvalid(checkpointExportVersion, export.Version, "unsupported checkpoint export version")
+	}
+	if export.Channel != wb.channelName {
+		return merr.WrapErrParameterInvalid(wb.channelName, export.Channel, "checkpoint exported from another channel")
+	}
+
+	wb.mut.Lock()
+	defer wb.mut.Unlock()
+
+	if wb.closed {
+		return ErrBufferClosed
+	}
+
+	wb.checkpoint = export.Checkpoint.toMsgPosition()
+	for _, segment := range export.Segments {
+		pos := segment.Position.toMsgPosition()
+		if pos == nil {
+			continue
+		}
+		if _, ok := wb.metaCache.GetSegmentByID(segment.SegmentID); !ok {
+			log.Warn("skip imported position of segment not in metacache",
+				zap.String("channel", wb.channelName), zap.Int64("segmentID", segment.SegmentID))
+			continue
+		}
+		buf := wb.getOrCreateBuffer(segment.SegmentID)
+		if buf.insertBuffer.startPos == nil || pos.GetTimestamp() < buf.insertBuffer.startPos.GetTimestamp() {
+			buf.insertBuffer.startPos = pos
+		}
+	}
+	return nil
+}
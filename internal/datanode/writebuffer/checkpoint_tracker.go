@@ -0,0 +1,97 @@
+package writebuffer
+
+import (
+	"sync"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/msgpb"
+)
+
+// checkpointTracker records the reason why segments are synced
+// and attributes checkpoint advance to the segment which held the previous checkpoint.
+type checkpointTracker struct {
+	mut sync.Mutex
+
+	syncReasons map[int64]string   // segmentID => sync reason
+	flushed     map[int64]struct{} // segments with completed flush
+
+	lastSegmentID int64
+	lastPosition  *msgpb.MsgPosition
+
+	causeSegmentID int64
+	causeReason    string
+}
+
+func newCheckpointTracker() *checkpointTracker {
+	return &checkpointTracker{
+		syncReasons: make(map[int64]string),
+		flushed:     make(map[int64]struct{}),
+	}
+}
+
+// RecordSync records the reason of sync for provided segment.
+func (t *checkpointTracker) RecordSync(segmentID int64, reason string) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	t.syncReasons[segmentID] = reason
+}
+
+// MarkFlushed records that flush of provided segment completed.
+func (t *checkpointTracker) MarkFlushed(segmentID int64) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	t.flushed[segmentID] = struct{}{}
+}
+
+// Observe updates the latest evaluated checkpoint and the segment it comes from.
+// segmentID shall be zero if the checkpoint does not belong to any segment.
+// The previous checkpoint is returned if the advance is caused by completed flush of the segment holding it,
+// nil otherwise.
+func (t *checkpointTracker) Observe(segmentID int64, position *msgpb.MsgPosition) *msgpb.MsgPosition {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	var unblocked *msgpb.MsgPosition
+	if t.lastPosition != nil && t.lastSegmentID != 0 &&
+		position.GetTimestamp() > t.lastPosition.GetTimestamp() {
+		t.causeSegmentID = t.lastSegmentID
+		t.causeReason = t.syncReasons[t.lastSegmentID]
+		delete(t.syncReasons, t.lastSegmentID)
+		if _, ok := t.flushed[t.lastSegmentID]; ok {
+			unblocked = t.lastPosition
+			delete(t.flushed, t.lastSegmentID)
+		}
+	}
+
+	// flushed segments no longer hold checkpoint unless evaluated from stale sync manager state
+	for flushedID := range t.flushed {
+		if flushedID != segmentID {
+			delete(t.flushed, flushedID)
+		}
+	}
+
+	t.lastSegmentID = segmentID
+	t.lastPosition = position
+	return unblocked
+}
+
+// CauseAt returns the cause Observe would report after observing provided checkpoint, without updating tracker.
+func (t *checkpointTracker) CauseAt(segmentID int64, position *msgpb.MsgPosition) (int64, string) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	if t.lastPosition != nil && t.lastSegmentID != 0 &&
+		position.GetTimestamp() > t.lastPosition.GetTimestamp() {
+		return t.lastSegmentID, t.syncReasons[t.lastSegmentID]
+	}
+	return t.causeSegmentID, t.causeReason
+}
+
+// Cause returns the segment and its sync reason which advanced the checkpoint last time.
+func (t *checkpointTracker) Cause() (int64, string) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	return t.causeSegmentID, t.causeReason
+}
@@ -0,0 +1,91 @@
+package writebuffer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/msgpb"
+	"github.com/milvus-io/milvus/pkg/util/tsoutil"
+)
+
+type CollectorSuite struct {
+	suite.Suite
+}
+
+func (s *CollectorSuite) TestCollect() {
+	wb := NewMockWriteBuffer(s.T())
+	wb.EXPECT().BufferStats().Return(ChannelBufferStats{
+		InsertRows:   100,
+		DeltaRows:    10,
+		MemorySize:   2048,
+		PendingSyncs: 2,
+		SyncCount:    4,
+		SyncLatency:  2 * time.Second,
+		Checkpoint:   &msgpb.MsgPosition{Timestamp: tsoutil.ComposeTSByTime(time.Now().Add(-time.Minute), 0)},
+	})
+
+	registry := prometheus.NewRegistry()
+	s.Require().NoError(registry.Register(NewCollector(100, "channel_1", wb)))
+	// collectors of other channels could be registered together
+	s.Require().NoError(registry.Register(NewCollector(100, "channel_2", wb)))
+
+	families, err := registry.Gather()
+	s.Require().NoError(err)
+	byName := lo.SliceToMap(families, func(family *dto.MetricFamily) (string, *dto.MetricFamily) {
+		return family.GetName(), family
+	})
+	s.ElementsMatch([]string{
+		"milvus_datanode_write_buffer_rows",
+		"milvus_datanode_write_buffer_bytes",
+		"milvus_datanode_write_buffer_pending_syncs",
+		"milvus_datanode_write_buffer_sync_latency_seconds",
+		"milvus_datanode_write_buffer_checkpoint_lag_seconds",
+	}, lo.Keys(byName))
+
+	metricOf := func(name string, labels map[string]string) *dto.Metric {
+		family := byName[name]
+		s.Require().NotNil(family)
+		metric, ok := lo.Find(family.GetMetric(), func(metric *dto.Metric) bool {
+			return lo.EveryBy(lo.Entries(labels), func(entry lo.Entry[string, string]) bool {
+				return lo.ContainsBy(metric.GetLabel(), func(label *dto.LabelPair) bool {
+					return label.GetName() == entry.Key && label.GetValue() == entry.Value
+				})
+			})
+		})
+		s.Require().True(ok)
+		return metric
+	}
+	labels := map[string]string{"collection_id": "100", "channel_name": "channel_1"}
+
+	s.EqualValues(100, metricOf("milvus_datanode_write_buffer_rows", lo.Assign(labels, map[string]string{"type": "insert"})).GetGauge().GetValue())
+	s.EqualValues(10, metricOf("milvus_datanode_write_buffer_rows", lo.Assign(labels, map[string]string{"type": "delete"})).GetGauge().GetValue())
+	s.EqualValues(2048, metricOf("milvus_datanode_write_buffer_bytes", labels).GetGauge().GetValue())
+	s.EqualValues(2, metricOf("milvus_datanode_write_buffer_pending_syncs", labels).GetGauge().GetValue())
+	latency := metricOf("milvus_datanode_write_buffer_sync_latency_seconds", labels).GetSummary()
+	s.EqualValues(4, latency.GetSampleCount())
+	s.InDelta(2, latency.GetSampleSum(), 1e-9)
+	s.GreaterOrEqual(metricOf("milvus_datanode_write_buffer_checkpoint_lag_seconds", labels).GetGauge().GetValue(), float64(60))
+}
+
+func (s *CollectorSuite) TestCollectWithoutCheckpoint() {
+	wb := NewMockWriteBuffer(s.T())
+	wb.EXPECT().BufferStats().Return(ChannelBufferStats{})
+
+	registry := prometheus.NewRegistry()
+	s.Require().NoError(registry.Register(NewCollector(100, "channel_1", wb)))
+
+	families, err := registry.Gather()
+	s.Require().NoError(err)
+	s.False(lo.ContainsBy(families, func(family *dto.MetricFamily) bool {
+		return family.GetName() == "milvus_datanode_write_buffer_checkpoint_lag_seconds"
+	}))
+}
+
+func TestCollector(t *testing.T) {
+	suite.Run(t, new(CollectorSuite))
+}
@@ -0,0 +1,250 @@
+package writebuffer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/internal/storage"
+	"github.com/milvus-io/milvus/pkg/log"
+	"github.com/milvus-io/milvus/pkg/util/typeutil"
+)
+
+const (
+	// deleteIndexSparseInterval is the number of entries between two pks kept in memory for spilled index file.
+	deleteIndexSparseInterval = 64
+
+	deleteIndexInt64Pk   byte = 0
+	deleteIndexVarCharPk byte = 1
+)
+
+// deleteIndex indexes buffered deletes by pk, keeping at most maxEntries pks in memory.
+// Once exceeded, in-memory entries are spilled into a sorted index file under dir, never spills if maxEntries is not positive.
+type deleteIndex struct {
+	dir        string
+	maxEntries int
+
+	entries map[any]typeutil.Timestamp // pk value => latest delete timestamp
+	spills  []*deleteIndexFile
+}
+
+// deleteIndexFile is a spilled index file holding entries sorted by pk.
+// Only every deleteIndexSparseInterval-th pk and its offset are kept in memory to locate the block holding a pk.
+type deleteIndexFile struct {
+	path   string
+	size   int64
+	sparse []deleteIndexMark
+}
+
+type deleteIndexMark struct {
+	pk     any
+	offset int64
+}
+
+func newDeleteIndex(dir string, maxEntries int) *deleteIndex {
+	return &deleteIndex{
+		dir:        dir,
+		maxEntries: maxEntries,
+		entries:    make(map[any]typeutil.Timestamp),
+	}
+}
+
+// Add records delete of pk at ts, in-memory entries are spilled if exceeding limit.
+// Entries are kept in memory if spill fails, so that lookup never misses.
+func (idx *deleteIndex) Add(pk storage.PrimaryKey, ts typeutil.Timestamp) {
+	value := pk.GetValue()
+	if last, ok := idx.entries[value]; !ok || ts > last {
+		idx.entries[value] = ts
+	}
+	if idx.maxEntries <= 0 || len(idx.entries) <= idx.maxEntries {
+		return
+	}
+	if err := idx.spill(); err != nil {
+		log.Warn("failed to spill delete index, keep entries in memory", zap.String("dir", idx.dir), zap.Error(err))
+	}
+}
+
+// Lookup returns the latest delete timestamp of pk.
+func (idx *deleteIndex) Lookup(pk storage.PrimaryKey) (typeutil.Timestamp, bool, error) {
+	value := pk.GetValue()
+	ts, found := idx.entries[value]
+	for _, file := range idx.spills {
+		spilled, ok, err := file.lookup(value)
+		if err != nil {
+			return 0, false, err
+		}
+		if ok && (!found || spilled > ts) {
+			ts, found = spilled, true
+		}
+	}
+	return ts, found, nil
+}
+
+// Release removes all spilled index files.
+func (idx *deleteIndex) Release() {
+	for _, file := range idx.spills {
+		if err := os.Remove(file.path); err != nil {
+			log.Warn("failed to remove spilled delete index file", zap.String("path", file.path), zap.Error(err))
+		}
+	}
+	idx.spills = nil
+	idx.entries = make(map[any]typeutil.Timestamp)
+}
+
+func (idx *deleteIndex) spill() error {
+	pks := make([]any, 0, len(idx.entries))
+	for pk := range idx.entries {
+		pks = append(pks, pk)
+	}
+	sort.Slice(pks, func(i, j int) bool { return compareIndexPk(pks[i], pks[j]) < 0 })
+
+	f, err := os.CreateTemp(idx.dir, "delete-index-*")
+	if err != nil {
+		return errors.Wrap(err, "failed to create delete index file")
+	}
+	file := &deleteIndexFile{path: f.Name()}
+	w := bufio.NewWriter(f)
+	for i, pk := range pks {
+		if i%deleteIndexSparseInterval == 0 {
+			file.sparse = append(file.sparse, deleteIndexMark{pk: pk, offset: file.size})
+		}
+		var n int
+		n, err = writeIndexEntry(w, pk, idx.entries[pk])
+		if err != nil {
+			break
+		}
+		file.size += int64(n)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(file.path)
+		return errors.Wrapf(err, "failed to write delete index file %s", file.path)
+	}
+
+	idx.spills = append(idx.spills, file)
+	idx.entries = make(map[any]typeutil.Timestamp)
+	return nil
+}
+
+// lookup reads the block which may hold pk from index file.
+func (file *deleteIndexFile) lookup(pk any) (typeutil.Timestamp, bool, error) {
+	// the last block starting with pk not greater than target
+	block := sort.Search(len(file.sparse), func(i int) bool { return compareIndexPk(file.sparse[i].pk, pk) > 0 }) - 1
+	if block < 0 {
+		return 0, false, nil
+	}
+
+	f, err := os.Open(file.path)
+	if err != nil {
+		return 0, false, errors.Wrap(err, "failed to open delete index file")
+	}
+	defer f.Close()
+
+	offset := file.sparse[block].offset
+	r := bufio.NewReader(io.NewSectionReader(f, offset, file.size-offset))
+	for i := 0; i < deleteIndexSparseInterval; i++ {
+		entryPk, ts, err := readIndexEntry(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, false, errors.Wrapf(err, "failed to read delete index file %s", file.path)
+		}
+		switch cmp := compareIndexPk(entryPk, pk); {
+		case cmp == 0:
+			return ts, true, nil
+		case cmp > 0:
+			return 0, false, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// writeIndexEntry encodes entry as pk type, pk & timestamp, varchar pk is prefixed with its length.
+func writeIndexEntry(w *bufio.Writer, pk any, ts typeutil.Timestamp) (int, error) {
+	buf := make([]byte, 0, 1+binary.MaxVarintLen64+8)
+	switch v := pk.(type) {
+	case int64:
+		buf = append(buf, deleteIndexInt64Pk)
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(v))
+	case string:
+		buf = append(buf, deleteIndexVarCharPk)
+		buf = binary.AppendUvarint(buf, uint64(len(v)))
+		buf = append(buf, v...)
+	default:
+		return 0, errors.Newf("unsupported pk type %T", pk)
+	}
+	buf = binary.LittleEndian.AppendUint64(buf, ts)
+	return w.Write(buf)
+}
+
+func readIndexEntry(r *bufio.Reader) (any, typeutil.Timestamp, error) {
+	pkType, err := r.ReadByte()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var pk any
+	fixed := make([]byte, 8)
+	switch pkType {
+	case deleteIndexInt64Pk:
+		if _, err := io.ReadFull(r, fixed); err != nil {
+			return nil, 0, err
+		}
+		pk = int64(binary.LittleEndian.Uint64(fixed))
+	case deleteIndexVarCharPk:
+		length, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, 0, err
+		}
+		value := make([]byte, length)
+		if _, err := io.ReadFull(r, value); err != nil {
+			return nil, 0, err
+		}
+		pk = string(value)
+	default:
+		return nil, 0, errors.Newf("unknown pk type %d", pkType)
+	}
+
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, 0, err
+	}
+	return pk, binary.LittleEndian.Uint64(fixed), nil
+}
+
+// compareIndexPk compares pk values of the same type, int64 pks are ordered before varchar ones.
+func compareIndexPk(a, b any) int {
+	switch a := a.(type) {
+	case int64:
+		b, ok := b.(int64)
+		switch {
+		case !ok:
+			return -1
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		default:
+			return 0
+		}
+	case string:
+		b, ok := b.(string)
+		if !ok {
+			return 1
+		}
+		return strings.Compare(a, b)
+	default:
+		return 0
+	}
+}
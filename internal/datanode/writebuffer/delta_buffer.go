@@ -14,6 +14,8 @@ type DeltaBuffer struct {
 	BufferBase
 
 	buffer *storage.DeleteData
+	// index indexes buffered deletes by pk for lookup, buffer is scanned if nil.
+	index *deleteIndex
 }
 
 func NewDeltaBuffer() *DeltaBuffer {
@@ -58,19 +60,81 @@ func (db *DeltaBuffer) Buffer(pks []storage.PrimaryKey, tss []typeutil.Timestamp
 
 	for i := 0; i < rowCount; i++ {
 		db.buffer.Append(pks[i], tss[i])
-
-		switch pks[i].Type() {
-		case schemapb.DataType_Int64:
-			bufSize += 8
-		case schemapb.DataType_VarChar:
-			varCharPk := pks[i].(*storage.VarCharPrimaryKey)
-			bufSize += int64(len(varCharPk.Value))
+		bufSize += deleteEntrySize(pks[i])
+		if db.index != nil {
+			db.index.Add(pks[i], tss[i])
 		}
-		// accumulate buf size for timestamp, which is 8 bytes
-		bufSize += 8
 	}
 
 	db.UpdateStatistics(int64(rowCount), bufSize, db.getTimestampRange(tss), startPos, endPos)
 
 	return bufSize
 }
+
+// Prune removes buffered deletes with timestamp below ts and returns the number of removed entries.
+// buffer positions are kept as is, so checkpoint could not pass over positions of pruned data.
+func (db *DeltaBuffer) Prune(ts typeutil.Timestamp) int {
+	kept := &storage.DeleteData{}
+	var size int64
+	for i, pk := range db.buffer.Pks {
+		if db.buffer.Tss[i] < ts {
+			continue
+		}
+		kept.Append(pk, db.buffer.Tss[i])
+		size += deleteEntrySize(pk)
+	}
+	pruned := int(db.buffer.RowCount - kept.RowCount)
+	if pruned == 0 {
+		return 0
+	}
+
+	if db.index != nil {
+		db.index.Release()
+		for i, pk := range kept.Pks {
+			db.index.Add(pk, kept.Tss[i])
+		}
+	}
+
+	tr := db.getTimestampRange(kept.Tss)
+	db.buffer = kept
+	db.rows = kept.RowCount
+	db.size = size
+	db.TimestampFrom, db.TimestampTo = tr.timestampMin, tr.timestampMax
+	return pruned
+}
+
+// Lookup returns the latest timestamp of buffered deletes of pk.
+func (db *DeltaBuffer) Lookup(pk storage.PrimaryKey) (typeutil.Timestamp, bool, error) {
+	if db.index != nil {
+		return db.index.Lookup(pk)
+	}
+
+	var ts typeutil.Timestamp
+	var found bool
+	for i, buffered := range db.buffer.Pks {
+		if buffered.EQ(pk) && (!found || db.buffer.Tss[i] > ts) {
+			ts, found = db.buffer.Tss[i], true
+		}
+	}
+	return ts, found, nil
+}
+
+// releaseIndex removes spilled files of delete index, shall be invoked once buffer is dropped from write buffer.
+func (db *DeltaBuffer) releaseIndex() {
+	if db.index != nil {
+		db.index.Release()
+	}
+}
+
+// deleteEntrySize returns the buffer size of one delete entry, which is pk size plus 8 bytes of timestamp.
+func deleteEntrySize(pk storage.PrimaryKey) int64 {
+	var size int64
+	switch pk.Type() {
+	case schemapb.DataType_Int64:
+		size += 8
+	case schemapb.DataType_VarChar:
+		varCharPk := pk.(*storage.VarCharPrimaryKey)
+		size += int64(len(varCharPk.Value))
+	}
+	return size + 8
+}
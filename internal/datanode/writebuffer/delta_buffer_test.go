@@ -2,6 +2,7 @@ package writebuffer
 
 import (
 	"fmt"
+	"os"
 	"testing"
 	"time"
 
@@ -61,6 +62,58 @@ func (s *DeltaBufferSuite) TestYield() {
 	s.ElementsMatch(pks, result.Pks)
 }
 
+func (s *DeltaBufferSuite) TestLookupWithSpill() {
+	cases := map[string]func(idx int) storage.PrimaryKey{
+		"int64_pk":  func(idx int) storage.PrimaryKey { return storage.NewInt64PrimaryKey(int64(idx)) },
+		"string_pk": func(idx int) storage.PrimaryKey { return storage.NewVarCharPrimaryKey(fmt.Sprintf("pk_%04d", idx)) },
+	}
+	for name, pkOf := range cases {
+		s.Run(name, func() {
+			dir := s.T().TempDir()
+			indexed := NewDeltaBuffer()
+			indexed.index = newDeleteIndex(dir, 10)
+			scanned := NewDeltaBuffer()
+
+			// first 100 pks are deleted twice, latest timestamp shall be returned
+			pks := lo.RepeatBy(300, func(idx int) storage.PrimaryKey { return pkOf(idx % 200) })
+			tss := lo.RepeatBy(300, func(idx int) uint64 { return uint64(1000 + idx) })
+			for i := 0; i < len(pks); i += 7 {
+				end := lo.Min([]int{i + 7, len(pks)})
+				indexed.Buffer(pks[i:end], tss[i:end], &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+				scanned.Buffer(pks[i:end], tss[i:end], &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+			}
+			s.LessOrEqual(len(indexed.index.entries), 10)
+			files, err := os.ReadDir(dir)
+			s.Require().NoError(err)
+			s.NotEmpty(files)
+
+			for idx := 0; idx < 200; idx++ {
+				expected := uint64(1000 + idx)
+				if idx < 100 {
+					expected += 200
+				}
+				ts, ok, err := indexed.Lookup(pkOf(idx))
+				s.Require().NoError(err)
+				s.True(ok)
+				s.Equal(expected, ts)
+
+				ts, ok, err = scanned.Lookup(pkOf(idx))
+				s.Require().NoError(err)
+				s.True(ok)
+				s.Equal(expected, ts)
+			}
+			_, ok, err := indexed.Lookup(pkOf(500))
+			s.NoError(err)
+			s.False(ok)
+
+			indexed.releaseIndex()
+			files, err = os.ReadDir(dir)
+			s.Require().NoError(err)
+			s.Empty(files)
+		})
+	}
+}
+
 func TestDeltaBuffer(t *testing.T) {
 	suite.Run(t, new(DeltaBufferSuite))
 }
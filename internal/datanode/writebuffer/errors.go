@@ -0,0 +1,30 @@
+package writebuffer
+
+import (
+	"github.com/cockroachdb/errors"
+)
+
+var (
+	// ErrBufferClosed is the error that the write buffer is already closed.
+	ErrBufferClosed = errors.New("write buffer closed")
+	// ErrNilSchema is the error that the write buffer is created with nil collection schema.
+	ErrNilSchema = errors.New("write buffer collection schema is nil")
+	// ErrRateLimited is the error that the ingest rate of write buffer exceeds the limit.
+	ErrRateLimited = errors.New("write buffer ingest rate limited")
+	// ErrFieldDataMismatch is the error that the insert field data does not match collection schema.
+	ErrFieldDataMismatch = errors.New("insert field data mismatches collection schema")
+	// ErrDimMismatch is the error that the insert vector field data does not match vector dim in collection schema.
+	// It is also marked as ErrFieldDataMismatch.
+	ErrDimMismatch = errors.New("insert vector data mismatches schema dim")
+	// ErrSchemaDrift is the error that the insert msg carries fields unknown to collection schema,
+	// normally produced with newer schema. It is also marked as ErrFieldDataMismatch.
+	ErrSchemaDrift = errors.New("insert msg carries fields unknown to collection schema")
+	// ErrSegmentSealed is the error that the segment to buffer insert data into is sealed by SealSegment.
+	ErrSegmentSealed = errors.New("segment sealed in write buffer")
+	// ErrSchemaChangeBuffered is the error that collection schema changes while insert data of old schema is buffered.
+	ErrSchemaChangeBuffered = errors.New("collection schema changed with insert data buffered")
+	// ErrSnapshotIncomplete is the error that buffered data before snapshot barrier is not synced by SnapshotFlush.
+	ErrSnapshotIncomplete = errors.New("write buffer snapshot incomplete")
+	// ErrSegmentMetaUpdate is the error that the segment updated by write buffer is missing in metacache.
+	ErrSegmentMetaUpdate = errors.New("write buffer segment meta update failed")
+)
@@ -0,0 +1,94 @@
+package writebuffer
+
+import (
+	"github.com/cockroachdb/errors"
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
+	"github.com/milvus-io/milvus/pkg/log"
+	"github.com/milvus-io/milvus/pkg/mq/msgstream"
+	"github.com/milvus-io/milvus/pkg/util/typeutil"
+)
+
+// fieldSpec is the precomputed schema info of one field used by fieldValidator.
+type fieldSpec struct {
+	dataType schemapb.DataType
+	// dim is the vector dim, zero if field is not vector or dim is unknown.
+	dim int64
+}
+
+// fieldValidator checks field data of insert msgs against collection schema.
+// It is built once from schema so that buffering does not walk schema fields per msg.
+type fieldValidator struct {
+	fields map[int64]fieldSpec // fieldID => fieldSpec
+	// dropUnknown ignores field data absent from schema instead of rejecting msg.
+	dropUnknown bool
+	// checkDim validates vector dim and element count of vector field data.
+	checkDim bool
+}
+
+func newFieldValidator(collSchema *schemapb.CollectionSchema, dropUnknown bool, checkDim bool) *fieldValidator {
+	fields := make(map[int64]fieldSpec, len(collSchema.GetFields()))
+	for _, field := range collSchema.GetFields() {
+		spec := fieldSpec{dataType: field.GetDataType()}
+		if typeutil.IsVectorType(field.GetDataType()) {
+			// dim not checked if not in schema
+			spec.dim, _ = typeutil.GetDim(field)
+		}
+		fields[field.GetFieldID()] = spec
+	}
+	return &fieldValidator{fields: fields, dropUnknown: dropUnknown, checkDim: checkDim}
+}
+
+// validate returns ErrFieldDataMismatch if any field data of msg has different data type,
+// ErrDimMismatch if vector dim or element count mismatches schema dim if dim is checked,
+// and ErrSchemaDrift if any field data is absent from schema unless unknown fields are dropped.
+// dropped field data stays in msg, it is ignored when msg is converted with schema.
+func (v *fieldValidator) validate(msg *msgstream.InsertMsg) error {
+	var unknown []int64
+	for _, fieldData := range msg.GetFieldsData() {
+		spec, ok := v.fields[fieldData.GetFieldId()]
+		if !ok {
+			if v.dropUnknown {
+				unknown = append(unknown, fieldData.GetFieldId())
+				continue
+			}
+			return errors.Mark(errors.Wrapf(ErrSchemaDrift, "field %d not in schema", fieldData.GetFieldId()), ErrFieldDataMismatch)
+		}
+		if fieldData.GetType() != spec.dataType {
+			return errors.Wrapf(ErrFieldDataMismatch, "field %d type %s, expected %s",
+				fieldData.GetFieldId(), fieldData.GetType().String(), spec.dataType.String())
+		}
+		if !v.checkDim || spec.dim <= 0 {
+			continue
+		}
+		if fieldData.GetVectors().GetDim() != spec.dim {
+			return errors.Mark(errors.Wrapf(ErrDimMismatch, "field %d dim %d, expected %d",
+				fieldData.GetFieldId(), fieldData.GetVectors().GetDim(), spec.dim), ErrFieldDataMismatch)
+		}
+		if elements, rows := vectorElements(spec.dataType, fieldData.GetVectors()), int64(len(msg.GetTimestamps())); elements != rows*spec.dim {
+			return errors.Mark(errors.Wrapf(ErrDimMismatch, "field %d has %d vector elements, expected %d rows of dim %d",
+				fieldData.GetFieldId(), elements, rows, spec.dim), ErrFieldDataMismatch)
+		}
+	}
+	if len(unknown) > 0 {
+		log.Warn("drop insert field data unknown to collection schema", zap.Int64("segmentID", msg.GetSegmentID()), zap.Int64s("fieldIDs", unknown))
+	}
+	return nil
+}
+
+// vectorElements returns the number of vector elements in field data of provided vector type.
+func vectorElements(dataType schemapb.DataType, vectors *schemapb.VectorField) int64 {
+	switch dataType {
+	case schemapb.DataType_FloatVector:
+		return int64(len(vectors.GetFloatVector().GetData()))
+	case schemapb.DataType_BinaryVector:
+		// one bit per element
+		return int64(len(vectors.GetBinaryVector())) * 8
+	case schemapb.DataType_Float16Vector:
+		// two bytes per element
+		return int64(len(vectors.GetFloat16Vector())) / 2
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,84 @@
+package writebuffer
+
+import (
+	"github.com/milvus-io/milvus/internal/storage"
+)
+
+const (
+	// binlogOverheadBytes is the approximate size of magic number, descriptor event, event header & parquet footer of one binlog.
+	binlogOverheadBytes = 512
+	// offsetBytes is the size of offset parquet stores for each variable length value.
+	offsetBytes = 4
+	// deltaLogRowOverheadBytes is the approximate size of json encoded delete log besides its pk value.
+	deltaLogRowOverheadBytes = 48
+	// int64PkTextBytes is the max length of text int64 pk & timestamp encoded in delete log.
+	int64PkTextBytes = 20
+)
+
+func (wb *writeBufferBase) EstimateFlushSize(segmentID int64) int64 {
+	wb.mut.RLock()
+	defer wb.mut.RUnlock()
+	wb.segmentLocks.Lock(segmentID)
+	defer wb.segmentLocks.Unlock(segmentID)
+
+	buf, ok := wb.buffers[segmentID]
+	if !ok {
+		return 0
+	}
+
+	var size int64
+	if !buf.insertBuffer.IsEmpty() {
+		for _, fieldData := range buf.insertBuffer.buffer.Data {
+			size += estimateFieldBinlogSize(fieldData)
+		}
+	}
+	if !buf.deltaBuffer.IsEmpty() {
+		size += estimateDeltaLogSize(buf.deltaBuffer.buffer)
+	}
+	return size
+}
+
+// estimateFieldBinlogSize estimates the uncompressed size of insert binlog serialized from field data,
+// which is the encoded payload width of each row plus the fixed overhead of binlog file.
+func estimateFieldBinlogSize(fieldData storage.FieldData) int64 {
+	var payload int64
+	switch data := fieldData.(type) {
+	case *storage.BoolFieldData:
+		// bool values are bit packed
+		payload = int64((len(data.Data) + 7) / 8)
+	case *storage.Int8FieldData, *storage.Int16FieldData, *storage.Int32FieldData, *storage.FloatFieldData:
+		// stored with int32/float physical type
+		payload = int64(fieldData.RowNum()) * 4
+	case *storage.StringFieldData:
+		for _, str := range data.Data {
+			payload += int64(len(str)) + offsetBytes
+		}
+	case *storage.JSONFieldData:
+		for _, bs := range data.Data {
+			payload += int64(len(bs)) + offsetBytes
+		}
+	case *storage.ArrayFieldData:
+		// array elements are serialized as proto bytes
+		payload = int64(data.GetMemorySize()) + int64(data.RowNum())*offsetBytes
+	default:
+		// fixed width values, e.g. int64, double & vectors, are written as is
+		payload = int64(fieldData.GetMemorySize())
+	}
+	return payload + binlogOverheadBytes
+}
+
+// estimateDeltaLogSize estimates the size of delta log serialized from delete data,
+// each row of which is encoded as json string of pk, ts & pk type.
+func estimateDeltaLogSize(data *storage.DeleteData) int64 {
+	size := int64(binlogOverheadBytes)
+	for _, pk := range data.Pks {
+		size += deltaLogRowOverheadBytes + offsetBytes
+		switch pk := pk.(type) {
+		case *storage.VarCharPrimaryKey:
+			size += int64(len(pk.Value)) + int64PkTextBytes
+		default:
+			size += 2 * int64PkTextBytes
+		}
+	}
+	return size
+}
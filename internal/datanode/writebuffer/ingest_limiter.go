@@ -0,0 +1,73 @@
+package writebuffer
+
+import (
+	"time"
+
+	"github.com/milvus-io/milvus/pkg/mq/msgstream"
+	"github.com/milvus-io/milvus/pkg/util/ratelimitutil"
+)
+
+// ingestLimitRetryInterval is the interval to retry acquiring quota in blocking mode.
+const ingestLimitRetryInterval = 10 * time.Millisecond
+
+// ingestLimiter limits bytes buffered per second with token bucket.
+type ingestLimiter struct {
+	limiter *ratelimitutil.Limiter
+	// block waits for quota instead of returning ErrRateLimited.
+	block bool
+}
+
+func newIngestLimiter(bytesPerSec int64, block bool) *ingestLimiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &ingestLimiter{
+		limiter: ratelimitutil.NewLimiter(ratelimitutil.Limit(bytesPerSec), float64(bytesPerSec)),
+		block:   block,
+	}
+}
+
+// acquire acquires quota of provided bytes, nil limiter means no limit.
+// in blocking mode it returns ErrBufferClosed if closeCh closed while waiting.
+// Quota is granted whenever tokens are not negative, so a batch larger than the burst is admitted
+// once the bucket recovers and its excess is paid as debt by later batches.
+func (l *ingestLimiter) acquire(bytes int, closeCh <-chan struct{}) error {
+	if l == nil {
+		return nil
+	}
+	var timer *time.Timer
+	for !l.limiter.AllowN(time.Now(), bytes) {
+		if !l.block {
+			return ErrRateLimited
+		}
+		// reuse one timer, so that no timer is left pending after close
+		if timer == nil {
+			timer = time.NewTimer(ingestLimitRetryInterval)
+			defer timer.Stop()
+		} else {
+			timer.Reset(ingestLimitRetryInterval)
+		}
+		select {
+		case <-closeCh:
+			return ErrBufferClosed
+		case <-timer.C:
+		}
+	}
+	return nil
+}
+
+// ingestBytes returns total size of grouped dml msgs.
+func ingestBytes(insertBySegment map[int64][]*msgstream.InsertMsg, deleteBySegment map[int64][]*msgstream.DeleteMsg) int {
+	var size int
+	for _, msgs := range insertBySegment {
+		for _, msg := range msgs {
+			size += msg.Size()
+		}
+	}
+	for _, msgs := range deleteBySegment {
+		for _, msg := range msgs {
+			size += msg.Size()
+		}
+	}
+	return size
+}
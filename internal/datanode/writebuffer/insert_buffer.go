@@ -3,11 +3,16 @@ package writebuffer
 import (
 	"math"
 
+	"github.com/apache/arrow/go/v12/arrow"
+	"github.com/apache/arrow/go/v12/arrow/array"
+	"github.com/apache/arrow/go/v12/arrow/memory"
 	"github.com/cockroachdb/errors"
+	"go.uber.org/atomic"
 	"go.uber.org/zap"
 
 	"github.com/milvus-io/milvus-proto/go-api/v2/msgpb"
 	"github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
+	"github.com/milvus-io/milvus/internal/datanode/syncmgr"
 	"github.com/milvus-io/milvus/internal/storage"
 	"github.com/milvus-io/milvus/pkg/log"
 	"github.com/milvus-io/milvus/pkg/mq/msgstream"
@@ -31,6 +36,9 @@ type BufferBase struct {
 
 	startPos *msgpb.MsgPosition
 	endPos   *msgpb.MsgPosition
+
+	// limitScale scales row & size limit when evaluating IsFull, limits are used as is if nil.
+	limitScale *atomic.Float64
 }
 
 func (b *BufferBase) UpdateStatistics(entryNum, size int64, tr TimeRange, startPos, endPos *msgpb.MsgPosition) {
@@ -53,8 +61,12 @@ func (b *BufferBase) UpdateStatistics(entryNum, size int64, tr TimeRange, startP
 }
 
 func (b *BufferBase) IsFull() bool {
-	return (b.rowLimit != noLimit && b.rows >= b.rowLimit) ||
-		(b.sizeLimit != noLimit && b.size >= b.sizeLimit)
+	scale := 1.0
+	if b.limitScale != nil {
+		scale = b.limitScale.Load()
+	}
+	return (b.rowLimit != noLimit && float64(b.rows) >= float64(b.rowLimit)*scale) ||
+		(b.sizeLimit != noLimit && float64(b.size) >= float64(b.sizeLimit)*scale)
 }
 
 func (b *BufferBase) IsEmpty() bool {
@@ -78,6 +90,10 @@ func (b *BufferBase) GetTimeRange() *TimeRange {
 type InsertBuffer struct {
 	BufferBase
 	collSchema *schemapb.CollectionSchema
+	// pkExtractor extracts primary keys from insert data, GetPkFromInsertData is used if nil.
+	pkExtractor func(*storage.InsertData) []storage.PrimaryKey
+	// recordBuilder appends buffered data into arrow record as well if not nil.
+	recordBuilder *array.RecordBuilder
 
 	buffer *storage.InsertData
 }
@@ -118,6 +134,29 @@ func (ib *InsertBuffer) Yield() *storage.InsertData {
 	return ib.buffer
 }
 
+// enableRecord makes insert buffer build arrow record of provided schema while buffering,
+// so that storage v2 sync task does not convert insert data.
+func (ib *InsertBuffer) enableRecord(arrowSchema *arrow.Schema) {
+	ib.recordBuilder = array.NewRecordBuilder(memory.DefaultAllocator, arrowSchema)
+}
+
+// yieldRecord returns arrow record of buffered data and resets the record builder,
+// nil is returned if record is not enabled or buffer is empty.
+func (ib *InsertBuffer) yieldRecord() arrow.Record {
+	if ib.recordBuilder == nil || ib.IsEmpty() {
+		return nil
+	}
+	return ib.recordBuilder.NewRecord()
+}
+
+// releaseRecord releases the record builder, records yielded before stay valid.
+func (ib *InsertBuffer) releaseRecord() {
+	if ib.recordBuilder != nil {
+		ib.recordBuilder.Release()
+		ib.recordBuilder = nil
+	}
+}
+
 func (ib *InsertBuffer) Buffer(msgs []*msgstream.InsertMsg, startPos, endPos *msgpb.MsgPosition) ([]storage.FieldData, error) {
 	pkData := make([]storage.FieldData, 0, len(msgs))
 	for _, msg := range msgs {
@@ -127,27 +166,121 @@ func (ib *InsertBuffer) Buffer(msgs []*msgstream.InsertMsg, startPos, endPos *ms
 			return nil, err
 		}
 
-		pkFieldData, err := storage.GetPkFromInsertData(ib.collSchema, tmpBuffer)
+		pkFieldData, err := ib.bufferInsertData(tmpBuffer, startPos, endPos)
 		if err != nil {
 			return nil, err
 		}
-		if pkFieldData.RowNum() != tmpBuffer.GetRowNum() {
-			return nil, merr.WrapErrServiceInternal("pk column row num not match")
-		}
 		pkData = append(pkData, pkFieldData)
+	}
+	return pkData, nil
+}
 
-		storage.MergeInsertData(ib.buffer, tmpBuffer)
+// bufferInsertData merges insert data into buffer and returns its pk column.
+func (ib *InsertBuffer) bufferInsertData(data *storage.InsertData, startPos, endPos *msgpb.MsgPosition) (storage.FieldData, error) {
+	pkFieldData, err := ib.getPkData(data)
+	if err != nil {
+		return nil, err
+	}
+	if pkFieldData.RowNum() != data.GetRowNum() {
+		return nil, merr.WrapErrServiceInternal("pk column row num not match")
+	}
+	// validate before appending, so that buffered data and record never diverge
+	if err := ib.checkRowNum(data); err != nil {
+		return nil, err
+	}
+	tsData, err := storage.GetTimestampFromInsertData(data)
+	if err != nil {
+		log.Warn("no timestamp field found in insert msg", zap.Error(err))
+		return nil, err
+	}
 
-		tsData, err := storage.GetTimestampFromInsertData(tmpBuffer)
-		if err != nil {
-			log.Warn("no timestamp field found in insert msg", zap.Error(err))
+	if ib.recordBuilder != nil {
+		if err := syncmgr.BuildRecord(ib.recordBuilder, data, ib.collSchema.GetFields()); err != nil {
+			ib.rebuildRecord()
 			return nil, err
 		}
+	}
+	storage.MergeInsertData(ib.buffer, data)
 
-		// update buffer size
-		ib.UpdateStatistics(int64(tmpBuffer.GetRowNum()), int64(tmpBuffer.GetMemorySize()), ib.getTimestampRange(tsData), startPos, endPos)
+	// update buffer size
+	ib.UpdateStatistics(int64(data.GetRowNum()), int64(data.GetMemorySize()), ib.getTimestampRange(tsData), startPos, endPos)
+	return pkFieldData, nil
+}
+
+// checkRowNum checks all columns of insert data hold the same number of rows,
+// columns of all schema fields are required if record is enabled.
+func (ib *InsertBuffer) checkRowNum(data *storage.InsertData) error {
+	rows := data.GetRowNum()
+	for fieldID, fieldData := range data.Data {
+		if fieldData.RowNum() != rows {
+			return merr.WrapErrParameterInvalidMsg("field %d has %d rows, expected %d", fieldID, fieldData.RowNum(), rows)
+		}
+	}
+	if ib.recordBuilder == nil {
+		return nil
+	}
+	for _, field := range ib.collSchema.GetFields() {
+		if _, ok := data.Data[field.GetFieldID()]; !ok {
+			return merr.WrapErrParameterInvalidMsg("field %d missing in insert data", field.GetFieldID())
+		}
+	}
+	return nil
+}
+
+// rebuildRecord replaces record builder with a new one holding buffered data only,
+// dropping data partially appended by failed build. Record is disabled if buffered data could not be rebuilt,
+// in which case sync task converts insert data itself.
+func (ib *InsertBuffer) rebuildRecord() {
+	arrowSchema := ib.recordBuilder.Schema()
+	ib.recordBuilder.Release()
+	ib.enableRecord(arrowSchema)
+	if ib.IsEmpty() {
+		return
+	}
+	if err := syncmgr.BuildRecord(ib.recordBuilder, ib.buffer, ib.collSchema.GetFields()); err != nil {
+		log.Warn("failed to rebuild record of buffered data, disable record", zap.Error(err))
+		ib.releaseRecord()
+	}
+}
+
+func (ib *InsertBuffer) getPkData(data *storage.InsertData) (storage.FieldData, error) {
+	if ib.pkExtractor == nil {
+		return storage.GetPkFromInsertData(ib.collSchema, data)
+	}
+
+	pkField, err := typeutil.GetPrimaryFieldSchema(ib.collSchema)
+	if err != nil {
+		return nil, err
+	}
+	return pksToFieldData(pkField.GetDataType(), ib.pkExtractor(data))
+}
+
+// pksToFieldData converts primary keys into field data of provided pk type.
+func pksToFieldData(pkType schemapb.DataType, pks []storage.PrimaryKey) (storage.FieldData, error) {
+	switch pkType {
+	case schemapb.DataType_Int64:
+		data := make([]int64, 0, len(pks))
+		for _, pk := range pks {
+			value, ok := pk.GetValue().(int64)
+			if !ok {
+				return nil, merr.WrapErrParameterInvalidMsg("invalid int64 primary key %v", pk.GetValue())
+			}
+			data = append(data, value)
+		}
+		return &storage.Int64FieldData{Data: data}, nil
+	case schemapb.DataType_VarChar:
+		data := make([]string, 0, len(pks))
+		for _, pk := range pks {
+			value, ok := pk.GetValue().(string)
+			if !ok {
+				return nil, merr.WrapErrParameterInvalidMsg("invalid varchar primary key %v", pk.GetValue())
+			}
+			data = append(data, value)
+		}
+		return &storage.StringFieldData{Data: data}, nil
+	default:
+		return nil, merr.WrapErrParameterInvalidMsg("unsupported primary key type %s", pkType.String())
 	}
-	return pkData, nil
 }
 
 func (ib *InsertBuffer) getTimestampRange(tsData *storage.Int64FieldData) TimeRange {
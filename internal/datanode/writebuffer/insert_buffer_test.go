@@ -1,19 +1,26 @@
 package writebuffer
 
 import (
+	"math"
 	"math/rand"
 	"testing"
 	"time"
 
+	"github.com/apache/arrow/go/v12/arrow"
+	"github.com/apache/arrow/go/v12/arrow/array"
+	"github.com/apache/arrow/go/v12/arrow/memory"
 	"github.com/samber/lo"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/milvus-io/milvus-proto/go-api/v2/commonpb"
 	"github.com/milvus-io/milvus-proto/go-api/v2/msgpb"
 	"github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
+	"github.com/milvus-io/milvus/internal/datanode/syncmgr"
 	"github.com/milvus-io/milvus/internal/storage"
+	"github.com/milvus-io/milvus/internal/util/typeutil"
 	"github.com/milvus-io/milvus/pkg/common"
 	"github.com/milvus-io/milvus/pkg/mq/msgstream"
+	"github.com/milvus-io/milvus/pkg/util/merr"
 	"github.com/milvus-io/milvus/pkg/util/paramtable"
 	"github.com/milvus-io/milvus/pkg/util/tsoutil"
 )
@@ -208,6 +215,128 @@ func (s *InsertBufferSuite) TestYield() {
 	s.ElementsMatch(pks, pkData)
 }
 
+func (s *InsertBufferSuite) TestYieldRowTimestamps() {
+	insertBuffer, err := NewInsertBuffer(s.collSchema)
+	s.Require().NoError(err)
+
+	tss1, insertMsg1 := s.composeInsertMsg(10, 128)
+	tss2, insertMsg2 := s.composeInsertMsg(5, 128)
+	_, err = insertBuffer.Buffer([]*msgstream.InsertMsg{insertMsg1, insertMsg2}, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	s.Require().NoError(err)
+
+	result := insertBuffer.Yield()
+	s.Require().NotNil(result)
+
+	// per-row timestamps are kept in buffer order, not only the min/max range
+	tsField, ok := result.Data[common.TimeStampField]
+	s.Require().True(ok)
+	tsData := lo.RepeatBy(tsField.RowNum(), func(idx int) int64 { return tsField.GetRow(idx).(int64) })
+	s.Equal(append(tss1, tss2...), tsData)
+}
+
+func (s *InsertBufferSuite) TestYieldRecord() {
+	arrowSchema, err := typeutil.ConvertToArrowSchema(s.collSchema.GetFields())
+	s.Require().NoError(err)
+
+	insertBuffer, err := NewInsertBuffer(s.collSchema)
+	s.Require().NoError(err)
+	s.Nil(insertBuffer.yieldRecord())
+
+	insertBuffer.enableRecord(arrowSchema)
+	s.Nil(insertBuffer.yieldRecord())
+
+	tss1, insertMsg1 := s.composeInsertMsg(10, 128)
+	tss2, insertMsg2 := s.composeInsertMsg(5, 128)
+	_, err = insertBuffer.Buffer([]*msgstream.InsertMsg{insertMsg1, insertMsg2}, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	s.Require().NoError(err)
+
+	rec := insertBuffer.yieldRecord()
+	s.Require().NotNil(rec)
+	defer rec.Release()
+	data := insertBuffer.Yield()
+	s.EqualValues(data.GetRowNum(), rec.NumRows())
+
+	// record holds the same rows as buffered insert data
+	for i, field := range s.collSchema.GetFields() {
+		column := rec.Column(i)
+		fieldData := data.Data[field.GetFieldID()]
+		switch field.GetDataType() {
+		case schemapb.DataType_Int64:
+			s.Equal(fieldData.(*storage.Int64FieldData).Data, column.(*array.Int64).Int64Values())
+		case schemapb.DataType_FloatVector:
+			vectors := column.(*array.FixedSizeBinary)
+			for row := 0; row < vectors.Len(); row++ {
+				vec := fieldData.GetRow(row).([]float32)
+				bs := vectors.Value(row)
+				for j := range vec {
+					s.Equal(math.Float32bits(vec[j]), common.Endian.Uint32(bs[j*4:]))
+				}
+			}
+		}
+	}
+	s.Equal(append(tss1, tss2...), rec.Column(1).(*array.Int64).Int64Values())
+}
+
+func (s *InsertBufferSuite) TestBufferRowNumMismatch() {
+	arrowSchema, err := typeutil.ConvertToArrowSchema(s.collSchema.GetFields())
+	s.Require().NoError(err)
+	insertBuffer, err := NewInsertBuffer(s.collSchema)
+	s.Require().NoError(err)
+	insertBuffer.enableRecord(arrowSchema)
+
+	_, insertMsg := s.composeInsertMsg(10, 128)
+	_, err = insertBuffer.Buffer([]*msgstream.InsertMsg{insertMsg}, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	s.Require().NoError(err)
+
+	// vector column shorter than others is rejected before appended
+	_, insertMsg = s.composeInsertMsg(10, 128)
+	data, err := storage.InsertMsgToInsertData(insertMsg, s.collSchema)
+	s.Require().NoError(err)
+	vectors := data.Data[101].(*storage.FloatVectorFieldData)
+	vectors.Data = vectors.Data[:5*128]
+	_, err = insertBuffer.bufferInsertData(data, &msgpb.MsgPosition{Timestamp: 200}, &msgpb.MsgPosition{Timestamp: 300})
+	s.ErrorIs(err, merr.ErrParameterInvalid)
+	s.EqualValues(10, insertBuffer.rows)
+
+	// partially appended record is rebuilt from buffered data
+	insertBuffer.recordBuilder.Field(0).(*array.Int64Builder).Append(1)
+	insertBuffer.rebuildRecord()
+
+	rec := insertBuffer.yieldRecord()
+	s.Require().NotNil(rec)
+	defer rec.Release()
+	s.EqualValues(10, rec.NumRows())
+	s.EqualValues(10, insertBuffer.Yield().GetRowNum())
+}
+
+func (s *InsertBufferSuite) TestReleaseRecord() {
+	arrowSchema, err := typeutil.ConvertToArrowSchema(s.collSchema.GetFields())
+	s.Require().NoError(err)
+	buffer, err := newSegmentBuffer(1000, s.collSchema)
+	s.Require().NoError(err)
+	buffer.insertBuffer.enableRecord(arrowSchema)
+
+	_, insertMsg := s.composeInsertMsg(10, 128)
+	_, err = buffer.insertBuffer.Buffer([]*msgstream.InsertMsg{insertMsg}, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	s.Require().NoError(err)
+
+	// builder of yielded insert buffer is released, new one is enabled
+	rec := buffer.insertBuffer.yieldRecord()
+	s.Require().NotNil(rec)
+	defer rec.Release()
+	yielded := buffer.insertBuffer
+	_, err = buffer.yieldInsert()
+	s.Require().NoError(err)
+	s.Nil(yielded.recordBuilder)
+	s.NotNil(buffer.insertBuffer.recordBuilder)
+
+	// record yielded before stays valid after buffer released
+	buffer.release()
+	s.Nil(buffer.insertBuffer.recordBuilder)
+	s.EqualValues(10, rec.NumRows())
+	buffer.release()
+}
+
 type InsertBufferConstructSuite struct {
 	suite.Suite
 	schema *schemapb.CollectionSchema
@@ -293,3 +422,55 @@ func TestInsertBuffer(t *testing.T) {
 	suite.Run(t, new(InsertBufferSuite))
 	suite.Run(t, new(InsertBufferConstructSuite))
 }
+
+func benchmarkInsertBufferRecord(b *testing.B, native bool) {
+	s := &InsertBufferSuite{}
+	s.SetupSuite()
+	arrowSchema, err := typeutil.ConvertToArrowSchema(s.collSchema.GetFields())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var insertMsgs []*msgstream.InsertMsg
+	for i := 0; i < 10; i++ {
+		_, msg := s.composeInsertMsg(100, 128)
+		insertMsgs = append(insertMsgs, msg)
+	}
+	startPos, endPos := &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		insertBuffer, err := NewInsertBuffer(s.collSchema)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if native {
+			insertBuffer.enableRecord(arrowSchema)
+		}
+		if _, err := insertBuffer.Buffer(insertMsgs, startPos, endPos); err != nil {
+			b.Fatal(err)
+		}
+
+		var rec arrow.Record
+		if native {
+			rec = insertBuffer.yieldRecord()
+		} else {
+			// conversion done by storage v2 sync task
+			builder := array.NewRecordBuilder(memory.DefaultAllocator, arrowSchema)
+			if err := syncmgr.BuildRecord(builder, insertBuffer.Yield(), s.collSchema.GetFields()); err != nil {
+				b.Fatal(err)
+			}
+			rec = builder.NewRecord()
+			builder.Release()
+		}
+		rec.Release()
+	}
+}
+
+func BenchmarkInsertBufferRecordConversion(b *testing.B) {
+	benchmarkInsertBufferRecord(b, false)
+}
+
+func BenchmarkInsertBufferRecordNative(b *testing.B) {
+	benchmarkInsertBufferRecord(b, true)
+}
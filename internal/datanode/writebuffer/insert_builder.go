@@ -0,0 +1,48 @@
+package writebuffer
+
+import (
+	"github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
+	"github.com/milvus-io/milvus/internal/storage"
+	"github.com/milvus-io/milvus/pkg/util/merr"
+)
+
+// InsertBuilder appends row values column by column for BufferRows.
+type InsertBuilder interface {
+	// Append appends one value to the column of provided field.
+	Append(fieldID int64, value any) error
+}
+
+type insertDataBuilder struct {
+	data *storage.InsertData
+}
+
+func newInsertDataBuilder(sch *schemapb.CollectionSchema) (*insertDataBuilder, error) {
+	data, err := storage.NewInsertData(sch)
+	if err != nil {
+		return nil, err
+	}
+	return &insertDataBuilder{data: data}, nil
+}
+
+func (b *insertDataBuilder) Append(fieldID int64, value any) error {
+	fieldData, ok := b.data.Data[fieldID]
+	if !ok {
+		return merr.WrapErrFieldNotFound(fieldID)
+	}
+	return fieldData.AppendRow(value)
+}
+
+// build validates all columns have the same row number and returns the built insert data.
+func (b *insertDataBuilder) build() (*storage.InsertData, error) {
+	rowNum := -1
+	for fieldID, fieldData := range b.data.Data {
+		if rowNum < 0 {
+			rowNum = fieldData.RowNum()
+			continue
+		}
+		if fieldData.RowNum() != rowNum {
+			return nil, merr.WrapErrParameterInvalidMsg("row num of field %d not match, expected %d, got %d", fieldID, rowNum, fieldData.RowNum())
+		}
+	}
+	return b.data, nil
+}
@@ -32,43 +32,84 @@ func NewL0WriteBuffer(channel string, metacache metacache.MetaCache, storageV2Ca
 	if option.idAllocator == nil {
 		return nil, merr.WrapErrServiceInternal("id allocator is nil when creating l0 write buffer")
 	}
-	return &l0WriteBuffer{
+	wb := &l0WriteBuffer{
 		l0Segments:      make(map[int64]int64),
 		l0partition:     make(map[int64]int64),
 		writeBufferBase: newWriteBufferBase(channel, metacache, storageV2Cache, syncMgr, option),
 		syncMgr:         syncMgr,
 		idAllocator:     option.idAllocator,
-	}, nil
+	}
+	// deletes after l0 segment is submitted for sync go to a new l0 segment
+	wb.onSyncTask = wb.resetL0Segment
+	wb.startAutoSync(wb.syncAndCleanup)
+	return wb, nil
 }
 
 func (wb *l0WriteBuffer) BufferData(insertMsgs []*msgstream.InsertMsg, deleteMsgs []*msgstream.DeleteMsg, startPos, endPos *msgpb.MsgPosition) error {
+	return wb.BufferDataGrouped(groupInsertMsgs(insertMsgs), map[int64][]*msgstream.DeleteMsg{0: deleteMsgs}, startPos, endPos)
+}
+
+func (wb *l0WriteBuffer) BufferDataGrouped(insertBySegment map[int64][]*msgstream.InsertMsg, deleteBySegment map[int64][]*msgstream.DeleteMsg, startPos, endPos *msgpb.MsgPosition) error {
+	// acquire quota before lock, blocking mode shall not block other operations
+	if err := wb.ingestLimit.acquire(ingestBytes(insertBySegment, deleteBySegment), wb.closeCh); err != nil {
+		return err
+	}
+
+	wb.mut.Lock()
+	defer wb.mut.Unlock()
+
+	if wb.closed {
+		return ErrBufferClosed
+	}
+
+	return wb.bufferDataGrouped(insertBySegment, deleteBySegment, startPos, endPos)
+}
+
+func (wb *l0WriteBuffer) BufferDataSeq(insertMsgs []*msgstream.InsertMsg, deleteMsgs []*msgstream.DeleteMsg, startPos, endPos *msgpb.MsgPosition, seq uint64) error {
+	insertBySegment := groupInsertMsgs(insertMsgs)
+	if err := wb.ingestLimit.acquire(ingestBytes(insertBySegment, map[int64][]*msgstream.DeleteMsg{0: deleteMsgs}), wb.closeCh); err != nil {
+		return err
+	}
+
 	wb.mut.Lock()
 	defer wb.mut.Unlock()
 
+	if wb.closed {
+		return ErrBufferClosed
+	}
+
+	if err := wb.checkSequence(insertBySegment, seq); err != nil {
+		return err
+	}
+	err := wb.bufferDataGrouped(insertBySegment, map[int64][]*msgstream.DeleteMsg{0: deleteMsgs}, startPos, endPos)
+	if err != nil {
+		return err
+	}
+	wb.acceptSequence(insertBySegment, seq)
+	return nil
+}
+
+// bufferDataGrouped buffers grouped dml data, caller shall hold the lock.
+func (wb *l0WriteBuffer) bufferDataGrouped(insertBySegment map[int64][]*msgstream.InsertMsg, deleteBySegment map[int64][]*msgstream.DeleteMsg, startPos, endPos *msgpb.MsgPosition) error {
+	ctx, sp := wb.startSpan(msgsTraceCtx(insertBySegment), "WriteBuffer-BufferData")
+	defer sp.End()
+
 	// process insert msgs
-	pkData, err := wb.bufferInsert(insertMsgs, startPos, endPos)
+	pkData, err := wb.bufferInsert(insertBySegment, startPos, endPos)
 	if err != nil {
 		log.Warn("failed to buffer insert data", zap.Error(err))
 		return err
 	}
 
 	// update pk oracle
-	for segmentID, dataList := range pkData {
-		segments := wb.metaCache.GetSegmentsBy(metacache.WithSegmentIDs(segmentID))
-		for _, segment := range segments {
-			for _, fieldData := range dataList {
-				err := segment.GetBloomFilterSet().UpdatePKRange(fieldData)
-				if err != nil {
-					return err
-				}
-			}
-		}
+	if err := wb.updatePKOracle(pkData); err != nil {
+		return err
 	}
 
-	for _, msg := range deleteMsgs {
-		l0SegmentID := wb.getL0SegmentID(msg.GetPartitionID(), startPos)
+	for _, msg := range flattenDeleteMsgs(deleteBySegment) {
+		partitionID := msg.GetPartitionID()
 		pks := storage.ParseIDs2PrimaryKeys(msg.GetPrimaryKeys())
-		err := wb.bufferDelete(l0SegmentID, pks, msg.GetTimestamps(), startPos, endPos)
+		err := wb.bufferDeleteTo(func() int64 { return wb.getL0SegmentID(partitionID, startPos) }, pks, msg.GetTimestamps(), startPos, endPos)
 		if err != nil {
 			log.Warn("failed to buffer delete data", zap.Error(err))
 			return err
@@ -78,19 +119,55 @@ func (wb *l0WriteBuffer) BufferData(insertMsgs []*msgstream.InsertMsg, deleteMsg
 	// update buffer last checkpoint
 	wb.checkpoint = endPos
 
-	segmentsSync := wb.triggerSync()
-	for _, segment := range segmentsSync {
-		partition, ok := wb.l0partition[segment]
-		if ok {
-			delete(wb.l0partition, segment)
-			delete(wb.l0Segments, partition)
-		}
+	return wb.syncAndCleanup(ctx)
+}
+
+func (wb *l0WriteBuffer) BufferRows(segmentID int64, fill func(builder InsertBuilder) error, startPos, endPos *msgpb.MsgPosition) error {
+	ctx, sp := wb.startSpan(context.Background(), "WriteBuffer-BufferRows")
+	defer sp.End()
+
+	// rows are buffered with segment lock, only checkpoint update & sync require the write lock
+	if err := wb.bufferRows(segmentID, fill, startPos, endPos); err != nil {
+		return err
+	}
+
+	wb.mut.Lock()
+	defer wb.mut.Unlock()
+
+	if wb.closed {
+		return ErrBufferClosed
+	}
+
+	// update buffer last checkpoint
+	wb.advanceCheckpoint(endPos)
+
+	return wb.syncAndCleanup(ctx)
+}
+
+// syncAndCleanup triggers sync and removes compacted segments, caller shall hold the lock.
+func (wb *l0WriteBuffer) syncAndCleanup(ctx context.Context) error {
+	if _, err := wb.triggerSync(ctx); err != nil {
+		return err
 	}
 
 	wb.cleanupCompactedSegments()
 	return nil
 }
 
+// resetL0Segment removes the partition mapping of l0 segment submitted for sync, caller shall hold the lock.
+func (wb *l0WriteBuffer) resetL0Segment(segmentID int64) {
+	partition, ok := wb.l0partition[segmentID]
+	if ok {
+		delete(wb.l0partition, segmentID)
+		delete(wb.l0Segments, partition)
+	}
+}
+
+// BFFalsePositiveDeletes always returns zero, since deletes are buffered into L0 segments without bloom filter check.
+func (wb *l0WriteBuffer) BFFalsePositiveDeletes() int64 {
+	return 0
+}
+
 func (wb *l0WriteBuffer) getL0SegmentID(partitionID int64, startPos *msgpb.MsgPosition) int64 {
 	segmentID, ok := wb.l0Segments[partitionID]
 	if !ok {
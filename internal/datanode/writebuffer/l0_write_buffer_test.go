@@ -1,6 +1,7 @@
 package writebuffer
 
 import (
+	"context"
 	"math/rand"
 	"testing"
 	"time"
@@ -16,9 +17,11 @@ import (
 	"github.com/milvus-io/milvus/internal/datanode/metacache"
 	"github.com/milvus-io/milvus/internal/datanode/syncmgr"
 	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/milvus-io/milvus/internal/querycoordv2/params"
 	"github.com/milvus-io/milvus/internal/storage"
 	"github.com/milvus-io/milvus/pkg/common"
 	"github.com/milvus-io/milvus/pkg/mq/msgstream"
+	"github.com/milvus-io/milvus/pkg/util/conc"
 	"github.com/milvus-io/milvus/pkg/util/paramtable"
 	"github.com/milvus-io/milvus/pkg/util/tsoutil"
 )
@@ -156,7 +159,8 @@ func (s *L0WriteBufferSuite) TestBufferData() {
 
 	seg := metacache.NewSegmentInfo(&datapb.SegmentInfo{ID: 1000}, metacache.NewBloomFilterSet())
 	s.metacache.EXPECT().GetSegmentsBy(mock.Anything, mock.Anything).Return([]*metacache.SegmentInfo{seg})
-	s.metacache.EXPECT().GetSegmentByID(int64(1000)).Return(nil, false)
+	s.metacache.EXPECT().GetSegmentByID(int64(1000)).Return(nil, false).Once()
+	s.metacache.EXPECT().GetSegmentByID(int64(1000)).Return(seg, true)
 	s.metacache.EXPECT().AddSegment(mock.Anything, mock.Anything, mock.Anything).Return()
 	s.metacache.EXPECT().UpdateSegments(mock.Anything, mock.Anything).Return()
 	s.metacache.EXPECT().GetSegmentIDsBy(mock.Anything, mock.Anything).Return([]int64{})
@@ -165,6 +169,89 @@ func (s *L0WriteBufferSuite) TestBufferData() {
 	s.NoError(err)
 }
 
+func (s *L0WriteBufferSuite) TestDeltaOnlySync() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	l0SegmentID := int64(2000)
+	s.allocator.AllocOneF = func() (int64, error) { return l0SegmentID, nil }
+	// sync all buffered segments
+	policy := wrapSelectSegmentFuncPolicy(func(buffers []*segmentBuffer, _ uint64) []int64 {
+		return lo.Map(buffers, func(buf *segmentBuffer, _ int) int64 { return buf.segmentID })
+	}, "test policy")
+	wb, err := NewL0WriteBuffer(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{
+		idAllocator:  s.allocator,
+		syncPolicies: []SyncPolicy{policy},
+	})
+	s.Require().NoError(err)
+
+	seg := metacache.NewSegmentInfo(&datapb.SegmentInfo{
+		ID:    l0SegmentID,
+		State: commonpb.SegmentState_Growing,
+		Level: datapb.SegmentLevel_L0,
+	}, metacache.NewBloomFilterSet())
+	s.metacache.EXPECT().AddSegment(mock.Anything, mock.Anything, mock.Anything).Return()
+	s.metacache.EXPECT().GetSegmentByID(l0SegmentID).Return(seg, true)
+	s.metacache.EXPECT().UpdateSegments(mock.Anything, mock.Anything).Return()
+	s.metacache.EXPECT().GetSegmentIDsBy(mock.Anything, mock.Anything).Return([]int64{})
+
+	var tasks []syncmgr.Task
+	s.syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, task syncmgr.Task) *conc.Future[error] {
+		tasks = append(tasks, task)
+		return nil
+	})
+
+	delMsg := s.composeDeleteMsg([]storage.PrimaryKey{storage.NewInt64PrimaryKey(1), storage.NewInt64PrimaryKey(2)})
+	err = wb.BufferData(nil, []*msgstream.DeleteMsg{delMsg}, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	s.Require().NoError(err)
+
+	s.Require().Len(tasks, 1)
+	task, ok := tasks[0].(*syncmgr.SyncTask)
+	s.Require().True(ok)
+	s.EqualValues(l0SegmentID, task.SegmentID())
+	s.EqualValues(100, task.StartPosition().GetTimestamp())
+	s.False(wb.HasSegment(l0SegmentID))
+	// l0 segment shall be re-allocated after synced
+	s.Empty(wb.(*l0WriteBuffer).l0Segments)
+}
+
+func (s *L0WriteBufferSuite) TestDeleteBatchResetsL0Segment() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	nextID := int64(2000)
+	s.allocator.AllocOneF = func() (int64, error) {
+		nextID++
+		return nextID, nil
+	}
+	option := &writeBufferOption{idAllocator: s.allocator}
+	WithMaxDeleteBatch(2)(option)
+	wb, err := NewL0WriteBuffer(s.channelName, s.metacache, nil, s.syncMgr, option)
+	s.Require().NoError(err)
+
+	s.metacache.EXPECT().AddSegment(mock.Anything, mock.Anything, mock.Anything).Return()
+	s.metacache.EXPECT().GetSegmentByID(mock.Anything).RunAndReturn(func(segmentID int64, _ ...metacache.SegmentFilter) (*metacache.SegmentInfo, bool) {
+		return metacache.NewSegmentInfo(&datapb.SegmentInfo{
+			ID:    segmentID,
+			State: commonpb.SegmentState_Growing,
+			Level: datapb.SegmentLevel_L0,
+		}, metacache.NewBloomFilterSet()), true
+	})
+	s.metacache.EXPECT().UpdateSegments(mock.Anything, mock.Anything).Return()
+	s.metacache.EXPECT().GetSegmentIDsBy(mock.Anything, mock.Anything).Return([]int64{})
+
+	var synced []int64
+	s.syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, task syncmgr.Task) *conc.Future[error] {
+		synced = append(synced, task.SegmentID())
+		return nil
+	})
+
+	pks := lo.RepeatBy(5, func(i int) storage.PrimaryKey { return storage.NewInt64PrimaryKey(int64(i)) })
+	err = wb.BufferData(nil, []*msgstream.DeleteMsg{s.composeDeleteMsg(pks)}, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	s.Require().NoError(err)
+
+	// each synced batch goes to its own l0 segment, remaining deletes go to a new one
+	s.Equal([]int64{2001, 2002}, synced)
+	s.True(wb.HasSegment(2003))
+	s.Equal([]int64{2003}, lo.Values(wb.(*l0WriteBuffer).l0Segments))
+}
+
 func TestL0WriteBuffer(t *testing.T) {
 	suite.Run(t, new(L0WriteBufferSuite))
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/milvus-io/milvus/internal/datanode/metacache"
 	"github.com/milvus-io/milvus/internal/datanode/syncmgr"
 	"github.com/milvus-io/milvus/pkg/log"
+	"github.com/milvus-io/milvus/pkg/metrics"
 	"github.com/milvus-io/milvus/pkg/mq/msgstream"
 	"github.com/milvus-io/milvus/pkg/util/merr"
 )
@@ -37,15 +38,17 @@ type BufferManager interface {
 // NewManager returns initialized manager as `Manager`
 func NewManager(syncMgr syncmgr.SyncManager) BufferManager {
 	return &bufferManager{
-		syncMgr: syncMgr,
-		buffers: make(map[string]WriteBuffer),
+		syncMgr:    syncMgr,
+		buffers:    make(map[string]WriteBuffer),
+		collectors: make(map[string]*Collector),
 	}
 }
 
 type bufferManager struct {
-	syncMgr syncmgr.SyncManager
-	buffers map[string]WriteBuffer
-	mut     sync.RWMutex
+	syncMgr    syncmgr.SyncManager
+	buffers    map[string]WriteBuffer
+	collectors map[string]*Collector // channel => registered metrics collector of write buffer
+	mut        sync.RWMutex
 }
 
 // Register a new WriteBuffer for channel.
@@ -62,6 +65,13 @@ func (m *bufferManager) Register(channel string, metacache metacache.MetaCache,
 		return err
 	}
 	m.buffers[channel] = buf
+
+	collector := NewCollector(metacache.Collection(), channel, buf)
+	if err := metrics.GetRegisterer().Register(collector); err != nil {
+		log.Warn("failed to register write buffer metrics collector", zap.String("channel", channel), zap.Error(err))
+	} else {
+		m.collectors[channel] = collector
+	}
 	return nil
 }
 
@@ -146,6 +156,7 @@ func (m *bufferManager) RemoveChannel(channel string) {
 	m.mut.Lock()
 	buf, ok := m.buffers[channel]
 	delete(m.buffers, channel)
+	m.unregisterCollector(channel)
 	m.mut.Unlock()
 
 	if !ok {
@@ -153,7 +164,8 @@ func (m *bufferManager) RemoveChannel(channel string) {
 		return
 	}
 
-	buf.Close(false)
+	// buffered data is discarded, no sync error could happen
+	_ = buf.Close(false)
 }
 
 // DropChannel removes channel WriteBuffer and process `DropChannel`
@@ -162,6 +174,7 @@ func (m *bufferManager) DropChannel(channel string) {
 	m.mut.Lock()
 	buf, ok := m.buffers[channel]
 	delete(m.buffers, channel)
+	m.unregisterCollector(channel)
 	m.mut.Unlock()
 
 	if !ok {
@@ -169,5 +182,19 @@ func (m *bufferManager) DropChannel(channel string) {
 		return
 	}
 
-	buf.Close(true)
+	err := buf.Close(true)
+	if err != nil {
+		// channel is not dropped in meta, which will be recovered by next watch
+		log.Error("failed to drop channel write buffer", zap.String("channel", channel), zap.Error(err))
+	}
+}
+
+// unregisterCollector unregisters metrics collector of channel write buffer, caller shall hold the lock.
+func (m *bufferManager) unregisterCollector(channel string) {
+	collector, ok := m.collectors[channel]
+	if !ok {
+		return
+	}
+	metrics.GetRegisterer().Unregister(collector)
+	delete(m.collectors, channel)
 }
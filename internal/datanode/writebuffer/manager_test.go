@@ -185,6 +185,30 @@ func (s *ManagerSuite) TestRemoveChannel() {
 	})
 }
 
+func (s *ManagerSuite) TestDropChannel() {
+	manager := NewManager(s.syncMgr).(*bufferManager)
+
+	s.Run("drop_not_exist", func() {
+		s.NotPanics(func() {
+			manager.DropChannel(s.channelName)
+		})
+	})
+
+	s.Run("close_failed", func() {
+		wb := NewMockWriteBuffer(s.T())
+		wb.EXPECT().Close(true).Return(merr.WrapErrServiceInternal("mocked"))
+
+		manager.mut.Lock()
+		manager.buffers[s.channelName] = wb
+		manager.mut.Unlock()
+
+		s.NotPanics(func() {
+			manager.DropChannel(s.channelName)
+		})
+		s.NotContains(manager.buffers, s.channelName)
+	})
+}
+
 func TestManager(t *testing.T) {
 	suite.Run(t, new(ManagerSuite))
 }
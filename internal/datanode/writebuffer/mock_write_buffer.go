@@ -9,6 +9,14 @@ import (
 	mock "github.com/stretchr/testify/mock"
 
 	msgstream "github.com/milvus-io/milvus/pkg/mq/msgstream"
+
+	schemapb "github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
+
+	storage "github.com/milvus-io/milvus/internal/storage"
+
+	time "time"
+
+	typeutil "github.com/milvus-io/milvus/pkg/util/typeutil"
 )
 
 // MockWriteBuffer is an autogenerated mock type for the WriteBuffer type
@@ -24,6 +32,47 @@ func (_m *MockWriteBuffer) EXPECT() *MockWriteBuffer_Expecter {
 	return &MockWriteBuffer_Expecter{mock: &_m.Mock}
 }
 
+// BFFalsePositiveDeletes provides a mock function with given fields:
+func (_m *MockWriteBuffer) BFFalsePositiveDeletes() int64 {
+	ret := _m.Called()
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func() int64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_BFFalsePositiveDeletes_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'BFFalsePositiveDeletes'
+type MockWriteBuffer_BFFalsePositiveDeletes_Call struct {
+	*mock.Call
+}
+
+// BFFalsePositiveDeletes is a helper method to define mock.On call
+func (_e *MockWriteBuffer_Expecter) BFFalsePositiveDeletes() *MockWriteBuffer_BFFalsePositiveDeletes_Call {
+	return &MockWriteBuffer_BFFalsePositiveDeletes_Call{Call: _e.mock.On("BFFalsePositiveDeletes")}
+}
+
+func (_c *MockWriteBuffer_BFFalsePositiveDeletes_Call) Run(run func()) *MockWriteBuffer_BFFalsePositiveDeletes_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_BFFalsePositiveDeletes_Call) Return(_a0 int64) *MockWriteBuffer_BFFalsePositiveDeletes_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_BFFalsePositiveDeletes_Call) RunAndReturn(run func() int64) *MockWriteBuffer_BFFalsePositiveDeletes_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // BufferData provides a mock function with given fields: insertMsgs, deleteMsgs, startPos, endPos
 func (_m *MockWriteBuffer) BufferData(insertMsgs []*msgstream.InsertMsg, deleteMsgs []*msgstream.DeleteMsg, startPos *msgpb.MsgPosition, endPos *msgpb.MsgPosition) error {
 	ret := _m.Called(insertMsgs, deleteMsgs, startPos, endPos)
@@ -69,46 +118,58 @@ func (_c *MockWriteBuffer_BufferData_Call) RunAndReturn(run func([]*msgstream.In
 	return _c
 }
 
-// Close provides a mock function with given fields: drop
-func (_m *MockWriteBuffer) Close(drop bool) {
-	_m.Called(drop)
+// BufferDataGrouped provides a mock function with given fields: insertBySegment, deleteBySegment, startPos, endPos
+func (_m *MockWriteBuffer) BufferDataGrouped(insertBySegment map[int64][]*msgstream.InsertMsg, deleteBySegment map[int64][]*msgstream.DeleteMsg, startPos *msgpb.MsgPosition, endPos *msgpb.MsgPosition) error {
+	ret := _m.Called(insertBySegment, deleteBySegment, startPos, endPos)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(map[int64][]*msgstream.InsertMsg, map[int64][]*msgstream.DeleteMsg, *msgpb.MsgPosition, *msgpb.MsgPosition) error); ok {
+		r0 = rf(insertBySegment, deleteBySegment, startPos, endPos)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
 }
 
-// MockWriteBuffer_Close_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Close'
-type MockWriteBuffer_Close_Call struct {
+// MockWriteBuffer_BufferDataGrouped_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'BufferDataGrouped'
+type MockWriteBuffer_BufferDataGrouped_Call struct {
 	*mock.Call
 }
 
-// Close is a helper method to define mock.On call
-//   - drop bool
-func (_e *MockWriteBuffer_Expecter) Close(drop interface{}) *MockWriteBuffer_Close_Call {
-	return &MockWriteBuffer_Close_Call{Call: _e.mock.On("Close", drop)}
+// BufferDataGrouped is a helper method to define mock.On call
+//   - insertBySegment map[int64][]*msgstream.InsertMsg
+//   - deleteBySegment map[int64][]*msgstream.DeleteMsg
+//   - startPos *msgpb.MsgPosition
+//   - endPos *msgpb.MsgPosition
+func (_e *MockWriteBuffer_Expecter) BufferDataGrouped(insertBySegment interface{}, deleteBySegment interface{}, startPos interface{}, endPos interface{}) *MockWriteBuffer_BufferDataGrouped_Call {
+	return &MockWriteBuffer_BufferDataGrouped_Call{Call: _e.mock.On("BufferDataGrouped", insertBySegment, deleteBySegment, startPos, endPos)}
 }
 
-func (_c *MockWriteBuffer_Close_Call) Run(run func(drop bool)) *MockWriteBuffer_Close_Call {
+func (_c *MockWriteBuffer_BufferDataGrouped_Call) Run(run func(insertBySegment map[int64][]*msgstream.InsertMsg, deleteBySegment map[int64][]*msgstream.DeleteMsg, startPos *msgpb.MsgPosition, endPos *msgpb.MsgPosition)) *MockWriteBuffer_BufferDataGrouped_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(bool))
+		run(args[0].(map[int64][]*msgstream.InsertMsg), args[1].(map[int64][]*msgstream.DeleteMsg), args[2].(*msgpb.MsgPosition), args[3].(*msgpb.MsgPosition))
 	})
 	return _c
 }
 
-func (_c *MockWriteBuffer_Close_Call) Return() *MockWriteBuffer_Close_Call {
-	_c.Call.Return()
+func (_c *MockWriteBuffer_BufferDataGrouped_Call) Return(_a0 error) *MockWriteBuffer_BufferDataGrouped_Call {
+	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *MockWriteBuffer_Close_Call) RunAndReturn(run func(bool)) *MockWriteBuffer_Close_Call {
+func (_c *MockWriteBuffer_BufferDataGrouped_Call) RunAndReturn(run func(map[int64][]*msgstream.InsertMsg, map[int64][]*msgstream.DeleteMsg, *msgpb.MsgPosition, *msgpb.MsgPosition) error) *MockWriteBuffer_BufferDataGrouped_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// FlushSegments provides a mock function with given fields: ctx, segmentIDs
-func (_m *MockWriteBuffer) FlushSegments(ctx context.Context, segmentIDs []int64) error {
-	ret := _m.Called(ctx, segmentIDs)
+// BufferDataSeq provides a mock function with given fields: insertMsgs, deleteMsgs, startPos, endPos, seq
+func (_m *MockWriteBuffer) BufferDataSeq(insertMsgs []*msgstream.InsertMsg, deleteMsgs []*msgstream.DeleteMsg, startPos *msgpb.MsgPosition, endPos *msgpb.MsgPosition, seq uint64) error {
+	ret := _m.Called(insertMsgs, deleteMsgs, startPos, endPos, seq)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, []int64) error); ok {
-		r0 = rf(ctx, segmentIDs)
+	if rf, ok := ret.Get(0).(func([]*msgstream.InsertMsg, []*msgstream.DeleteMsg, *msgpb.MsgPosition, *msgpb.MsgPosition, uint64) error); ok {
+		r0 = rf(insertMsgs, deleteMsgs, startPos, endPos, seq)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -116,126 +177,246 @@ func (_m *MockWriteBuffer) FlushSegments(ctx context.Context, segmentIDs []int64
 	return r0
 }
 
-// MockWriteBuffer_FlushSegments_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FlushSegments'
-type MockWriteBuffer_FlushSegments_Call struct {
+// MockWriteBuffer_BufferDataSeq_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'BufferDataSeq'
+type MockWriteBuffer_BufferDataSeq_Call struct {
 	*mock.Call
 }
 
-// FlushSegments is a helper method to define mock.On call
-//   - ctx context.Context
-//   - segmentIDs []int64
-func (_e *MockWriteBuffer_Expecter) FlushSegments(ctx interface{}, segmentIDs interface{}) *MockWriteBuffer_FlushSegments_Call {
-	return &MockWriteBuffer_FlushSegments_Call{Call: _e.mock.On("FlushSegments", ctx, segmentIDs)}
+// BufferDataSeq is a helper method to define mock.On call
+//   - insertMsgs []*msgstream.InsertMsg
+//   - deleteMsgs []*msgstream.DeleteMsg
+//   - startPos *msgpb.MsgPosition
+//   - endPos *msgpb.MsgPosition
+//   - seq uint64
+func (_e *MockWriteBuffer_Expecter) BufferDataSeq(insertMsgs interface{}, deleteMsgs interface{}, startPos interface{}, endPos interface{}, seq interface{}) *MockWriteBuffer_BufferDataSeq_Call {
+	return &MockWriteBuffer_BufferDataSeq_Call{Call: _e.mock.On("BufferDataSeq", insertMsgs, deleteMsgs, startPos, endPos, seq)}
 }
 
-func (_c *MockWriteBuffer_FlushSegments_Call) Run(run func(ctx context.Context, segmentIDs []int64)) *MockWriteBuffer_FlushSegments_Call {
+func (_c *MockWriteBuffer_BufferDataSeq_Call) Run(run func(insertMsgs []*msgstream.InsertMsg, deleteMsgs []*msgstream.DeleteMsg, startPos *msgpb.MsgPosition, endPos *msgpb.MsgPosition, seq uint64)) *MockWriteBuffer_BufferDataSeq_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].([]int64))
+		run(args[0].([]*msgstream.InsertMsg), args[1].([]*msgstream.DeleteMsg), args[2].(*msgpb.MsgPosition), args[3].(*msgpb.MsgPosition), args[4].(uint64))
 	})
 	return _c
 }
 
-func (_c *MockWriteBuffer_FlushSegments_Call) Return(_a0 error) *MockWriteBuffer_FlushSegments_Call {
+func (_c *MockWriteBuffer_BufferDataSeq_Call) Return(_a0 error) *MockWriteBuffer_BufferDataSeq_Call {
 	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *MockWriteBuffer_FlushSegments_Call) RunAndReturn(run func(context.Context, []int64) error) *MockWriteBuffer_FlushSegments_Call {
+func (_c *MockWriteBuffer_BufferDataSeq_Call) RunAndReturn(run func([]*msgstream.InsertMsg, []*msgstream.DeleteMsg, *msgpb.MsgPosition, *msgpb.MsgPosition, uint64) error) *MockWriteBuffer_BufferDataSeq_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// GetCheckpoint provides a mock function with given fields:
-func (_m *MockWriteBuffer) GetCheckpoint() *msgpb.MsgPosition {
+// BufferRows provides a mock function with given fields: segmentID, fill, startPos, endPos
+func (_m *MockWriteBuffer) BufferRows(segmentID int64, fill func(builder InsertBuilder) error, startPos *msgpb.MsgPosition, endPos *msgpb.MsgPosition) error {
+	ret := _m.Called(segmentID, fill, startPos, endPos)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int64, func(builder InsertBuilder) error, *msgpb.MsgPosition, *msgpb.MsgPosition) error); ok {
+		r0 = rf(segmentID, fill, startPos, endPos)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_BufferRows_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'BufferRows'
+type MockWriteBuffer_BufferRows_Call struct {
+	*mock.Call
+}
+
+// BufferRows is a helper method to define mock.On call
+//   - segmentID int64
+//   - fill func(builder InsertBuilder) error
+//   - startPos *msgpb.MsgPosition
+//   - endPos *msgpb.MsgPosition
+func (_e *MockWriteBuffer_Expecter) BufferRows(segmentID interface{}, fill interface{}, startPos interface{}, endPos interface{}) *MockWriteBuffer_BufferRows_Call {
+	return &MockWriteBuffer_BufferRows_Call{Call: _e.mock.On("BufferRows", segmentID, fill, startPos, endPos)}
+}
+
+func (_c *MockWriteBuffer_BufferRows_Call) Run(run func(segmentID int64, fill func(builder InsertBuilder) error, startPos *msgpb.MsgPosition, endPos *msgpb.MsgPosition)) *MockWriteBuffer_BufferRows_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64), args[1].(func(builder InsertBuilder) error), args[2].(*msgpb.MsgPosition), args[3].(*msgpb.MsgPosition))
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_BufferRows_Call) Return(_a0 error) *MockWriteBuffer_BufferRows_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_BufferRows_Call) RunAndReturn(run func(int64, func(builder InsertBuilder) error, *msgpb.MsgPosition, *msgpb.MsgPosition) error) *MockWriteBuffer_BufferRows_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// BufferStats provides a mock function with given fields:
+func (_m *MockWriteBuffer) BufferStats() ChannelBufferStats {
 	ret := _m.Called()
 
-	var r0 *msgpb.MsgPosition
-	if rf, ok := ret.Get(0).(func() *msgpb.MsgPosition); ok {
+	var r0 ChannelBufferStats
+	if rf, ok := ret.Get(0).(func() ChannelBufferStats); ok {
 		r0 = rf()
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*msgpb.MsgPosition)
-		}
+		r0 = ret.Get(0).(ChannelBufferStats)
 	}
 
 	return r0
 }
 
-// MockWriteBuffer_GetCheckpoint_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetCheckpoint'
-type MockWriteBuffer_GetCheckpoint_Call struct {
+// MockWriteBuffer_BufferStats_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'BufferStats'
+type MockWriteBuffer_BufferStats_Call struct {
 	*mock.Call
 }
 
-// GetCheckpoint is a helper method to define mock.On call
-func (_e *MockWriteBuffer_Expecter) GetCheckpoint() *MockWriteBuffer_GetCheckpoint_Call {
-	return &MockWriteBuffer_GetCheckpoint_Call{Call: _e.mock.On("GetCheckpoint")}
+// BufferStats is a helper method to define mock.On call
+func (_e *MockWriteBuffer_Expecter) BufferStats() *MockWriteBuffer_BufferStats_Call {
+	return &MockWriteBuffer_BufferStats_Call{Call: _e.mock.On("BufferStats")}
 }
 
-func (_c *MockWriteBuffer_GetCheckpoint_Call) Run(run func()) *MockWriteBuffer_GetCheckpoint_Call {
+func (_c *MockWriteBuffer_BufferStats_Call) Run(run func()) *MockWriteBuffer_BufferStats_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		run()
 	})
 	return _c
 }
 
-func (_c *MockWriteBuffer_GetCheckpoint_Call) Return(_a0 *msgpb.MsgPosition) *MockWriteBuffer_GetCheckpoint_Call {
+func (_c *MockWriteBuffer_BufferStats_Call) Return(_a0 ChannelBufferStats) *MockWriteBuffer_BufferStats_Call {
 	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *MockWriteBuffer_GetCheckpoint_Call) RunAndReturn(run func() *msgpb.MsgPosition) *MockWriteBuffer_GetCheckpoint_Call {
+func (_c *MockWriteBuffer_BufferStats_Call) RunAndReturn(run func() ChannelBufferStats) *MockWriteBuffer_BufferStats_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// GetFlushTimestamp provides a mock function with given fields:
-func (_m *MockWriteBuffer) GetFlushTimestamp() uint64 {
+// BufferedPartitionCount provides a mock function with given fields:
+func (_m *MockWriteBuffer) BufferedPartitionCount() int {
 	ret := _m.Called()
 
-	var r0 uint64
-	if rf, ok := ret.Get(0).(func() uint64); ok {
+	var r0 int
+	if rf, ok := ret.Get(0).(func() int); ok {
 		r0 = rf()
 	} else {
-		r0 = ret.Get(0).(uint64)
+		r0 = ret.Get(0).(int)
 	}
 
 	return r0
 }
 
-// MockWriteBuffer_GetFlushTimestamp_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFlushTimestamp'
-type MockWriteBuffer_GetFlushTimestamp_Call struct {
+// MockWriteBuffer_BufferedPartitionCount_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'BufferedPartitionCount'
+type MockWriteBuffer_BufferedPartitionCount_Call struct {
 	*mock.Call
 }
 
-// GetFlushTimestamp is a helper method to define mock.On call
-func (_e *MockWriteBuffer_Expecter) GetFlushTimestamp() *MockWriteBuffer_GetFlushTimestamp_Call {
-	return &MockWriteBuffer_GetFlushTimestamp_Call{Call: _e.mock.On("GetFlushTimestamp")}
+// BufferedPartitionCount is a helper method to define mock.On call
+func (_e *MockWriteBuffer_Expecter) BufferedPartitionCount() *MockWriteBuffer_BufferedPartitionCount_Call {
+	return &MockWriteBuffer_BufferedPartitionCount_Call{Call: _e.mock.On("BufferedPartitionCount")}
 }
 
-func (_c *MockWriteBuffer_GetFlushTimestamp_Call) Run(run func()) *MockWriteBuffer_GetFlushTimestamp_Call {
+func (_c *MockWriteBuffer_BufferedPartitionCount_Call) Run(run func()) *MockWriteBuffer_BufferedPartitionCount_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		run()
 	})
 	return _c
 }
 
-func (_c *MockWriteBuffer_GetFlushTimestamp_Call) Return(_a0 uint64) *MockWriteBuffer_GetFlushTimestamp_Call {
+func (_c *MockWriteBuffer_BufferedPartitionCount_Call) Return(_a0 int) *MockWriteBuffer_BufferedPartitionCount_Call {
 	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *MockWriteBuffer_GetFlushTimestamp_Call) RunAndReturn(run func() uint64) *MockWriteBuffer_GetFlushTimestamp_Call {
+func (_c *MockWriteBuffer_BufferedPartitionCount_Call) RunAndReturn(run func() int) *MockWriteBuffer_BufferedPartitionCount_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// HasSegment provides a mock function with given fields: segmentID
-func (_m *MockWriteBuffer) HasSegment(segmentID int64) bool {
-	ret := _m.Called(segmentID)
+// ClearFlushTimestamp provides a mock function with given fields:
+func (_m *MockWriteBuffer) ClearFlushTimestamp() {
+	_m.Called()
+}
+
+// MockWriteBuffer_ClearFlushTimestamp_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ClearFlushTimestamp'
+type MockWriteBuffer_ClearFlushTimestamp_Call struct {
+	*mock.Call
+}
+
+// ClearFlushTimestamp is a helper method to define mock.On call
+func (_e *MockWriteBuffer_Expecter) ClearFlushTimestamp() *MockWriteBuffer_ClearFlushTimestamp_Call {
+	return &MockWriteBuffer_ClearFlushTimestamp_Call{Call: _e.mock.On("ClearFlushTimestamp")}
+}
+
+func (_c *MockWriteBuffer_ClearFlushTimestamp_Call) Run(run func()) *MockWriteBuffer_ClearFlushTimestamp_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_ClearFlushTimestamp_Call) Return() *MockWriteBuffer_ClearFlushTimestamp_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockWriteBuffer_ClearFlushTimestamp_Call) RunAndReturn(run func()) *MockWriteBuffer_ClearFlushTimestamp_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Close provides a mock function with given fields: drop
+func (_m *MockWriteBuffer) Close(drop bool) error {
+	ret := _m.Called(drop)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bool) error); ok {
+		r0 = rf(drop)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_Close_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Close'
+type MockWriteBuffer_Close_Call struct {
+	*mock.Call
+}
+
+// Close is a helper method to define mock.On call
+//   - drop bool
+func (_e *MockWriteBuffer_Expecter) Close(drop interface{}) *MockWriteBuffer_Close_Call {
+	return &MockWriteBuffer_Close_Call{Call: _e.mock.On("Close", drop)}
+}
+
+func (_c *MockWriteBuffer_Close_Call) Run(run func(drop bool)) *MockWriteBuffer_Close_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(bool))
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_Close_Call) Return(_a0 error) *MockWriteBuffer_Close_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_Close_Call) RunAndReturn(run func(bool) error) *MockWriteBuffer_Close_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteAppliesTo provides a mock function with given fields: segmentID, pk
+func (_m *MockWriteBuffer) DeleteAppliesTo(segmentID int64, pk storage.PrimaryKey) bool {
+	ret := _m.Called(segmentID, pk)
 
 	var r0 bool
-	if rf, ok := ret.Get(0).(func(int64) bool); ok {
-		r0 = rf(segmentID)
+	if rf, ok := ret.Get(0).(func(int64, storage.PrimaryKey) bool); ok {
+		r0 = rf(segmentID, pk)
 	} else {
 		r0 = ret.Get(0).(bool)
 	}
@@ -243,63 +424,1578 @@ func (_m *MockWriteBuffer) HasSegment(segmentID int64) bool {
 	return r0
 }
 
-// MockWriteBuffer_HasSegment_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'HasSegment'
-type MockWriteBuffer_HasSegment_Call struct {
+// MockWriteBuffer_DeleteAppliesTo_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteAppliesTo'
+type MockWriteBuffer_DeleteAppliesTo_Call struct {
 	*mock.Call
 }
 
-// HasSegment is a helper method to define mock.On call
+// DeleteAppliesTo is a helper method to define mock.On call
 //   - segmentID int64
-func (_e *MockWriteBuffer_Expecter) HasSegment(segmentID interface{}) *MockWriteBuffer_HasSegment_Call {
-	return &MockWriteBuffer_HasSegment_Call{Call: _e.mock.On("HasSegment", segmentID)}
+//   - pk storage.PrimaryKey
+func (_e *MockWriteBuffer_Expecter) DeleteAppliesTo(segmentID interface{}, pk interface{}) *MockWriteBuffer_DeleteAppliesTo_Call {
+	return &MockWriteBuffer_DeleteAppliesTo_Call{Call: _e.mock.On("DeleteAppliesTo", segmentID, pk)}
 }
 
-func (_c *MockWriteBuffer_HasSegment_Call) Run(run func(segmentID int64)) *MockWriteBuffer_HasSegment_Call {
+func (_c *MockWriteBuffer_DeleteAppliesTo_Call) Run(run func(segmentID int64, pk storage.PrimaryKey)) *MockWriteBuffer_DeleteAppliesTo_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(int64))
+		run(args[0].(int64), args[1].(storage.PrimaryKey))
 	})
 	return _c
 }
 
-func (_c *MockWriteBuffer_HasSegment_Call) Return(_a0 bool) *MockWriteBuffer_HasSegment_Call {
+func (_c *MockWriteBuffer_DeleteAppliesTo_Call) Return(_a0 bool) *MockWriteBuffer_DeleteAppliesTo_Call {
 	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *MockWriteBuffer_HasSegment_Call) RunAndReturn(run func(int64) bool) *MockWriteBuffer_HasSegment_Call {
+func (_c *MockWriteBuffer_DeleteAppliesTo_Call) RunAndReturn(run func(int64, storage.PrimaryKey) bool) *MockWriteBuffer_DeleteAppliesTo_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// SetFlushTimestamp provides a mock function with given fields: flushTs
-func (_m *MockWriteBuffer) SetFlushTimestamp(flushTs uint64) {
-	_m.Called(flushTs)
+// DeleteTimeRange provides a mock function with given fields: segmentID
+func (_m *MockWriteBuffer) DeleteTimeRange(segmentID int64) (*TimeRange, bool) {
+	ret := _m.Called(segmentID)
+
+	var r0 *TimeRange
+	var r1 bool
+	if rf, ok := ret.Get(0).(func(int64) (*TimeRange, bool)); ok {
+		return rf(segmentID)
+	}
+	if rf, ok := ret.Get(0).(func(int64) *TimeRange); ok {
+		r0 = rf(segmentID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*TimeRange)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int64) bool); ok {
+		r1 = rf(segmentID)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	return r0, r1
 }
 
-// MockWriteBuffer_SetFlushTimestamp_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetFlushTimestamp'
-type MockWriteBuffer_SetFlushTimestamp_Call struct {
+// MockWriteBuffer_DeleteTimeRange_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteTimeRange'
+type MockWriteBuffer_DeleteTimeRange_Call struct {
 	*mock.Call
 }
 
-// SetFlushTimestamp is a helper method to define mock.On call
-//   - flushTs uint64
-func (_e *MockWriteBuffer_Expecter) SetFlushTimestamp(flushTs interface{}) *MockWriteBuffer_SetFlushTimestamp_Call {
-	return &MockWriteBuffer_SetFlushTimestamp_Call{Call: _e.mock.On("SetFlushTimestamp", flushTs)}
+// DeleteTimeRange is a helper method to define mock.On call
+//   - segmentID int64
+func (_e *MockWriteBuffer_Expecter) DeleteTimeRange(segmentID interface{}) *MockWriteBuffer_DeleteTimeRange_Call {
+	return &MockWriteBuffer_DeleteTimeRange_Call{Call: _e.mock.On("DeleteTimeRange", segmentID)}
 }
 
-func (_c *MockWriteBuffer_SetFlushTimestamp_Call) Run(run func(flushTs uint64)) *MockWriteBuffer_SetFlushTimestamp_Call {
+func (_c *MockWriteBuffer_DeleteTimeRange_Call) Run(run func(segmentID int64)) *MockWriteBuffer_DeleteTimeRange_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(uint64))
+		run(args[0].(int64))
 	})
 	return _c
 }
 
-func (_c *MockWriteBuffer_SetFlushTimestamp_Call) Return() *MockWriteBuffer_SetFlushTimestamp_Call {
-	_c.Call.Return()
+func (_c *MockWriteBuffer_DeleteTimeRange_Call) Return(_a0 *TimeRange, _a1 bool) *MockWriteBuffer_DeleteTimeRange_Call {
+	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *MockWriteBuffer_SetFlushTimestamp_Call) RunAndReturn(run func(uint64)) *MockWriteBuffer_SetFlushTimestamp_Call {
+func (_c *MockWriteBuffer_DeleteTimeRange_Call) RunAndReturn(run func(int64) (*TimeRange, bool)) *MockWriteBuffer_DeleteTimeRange_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DrainSegment provides a mock function with given fields: ctx, segmentID
+func (_m *MockWriteBuffer) DrainSegment(ctx context.Context, segmentID int64) error {
+	ret := _m.Called(ctx, segmentID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, segmentID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_DrainSegment_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DrainSegment'
+type MockWriteBuffer_DrainSegment_Call struct {
+	*mock.Call
+}
+
+// DrainSegment is a helper method to define mock.On call
+//   - ctx context.Context
+//   - segmentID int64
+func (_e *MockWriteBuffer_Expecter) DrainSegment(ctx interface{}, segmentID interface{}) *MockWriteBuffer_DrainSegment_Call {
+	return &MockWriteBuffer_DrainSegment_Call{Call: _e.mock.On("DrainSegment", ctx, segmentID)}
+}
+
+func (_c *MockWriteBuffer_DrainSegment_Call) Run(run func(ctx context.Context, segmentID int64)) *MockWriteBuffer_DrainSegment_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int64))
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_DrainSegment_Call) Return(_a0 error) *MockWriteBuffer_DrainSegment_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_DrainSegment_Call) RunAndReturn(run func(context.Context, int64) error) *MockWriteBuffer_DrainSegment_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DroppedSyncCount provides a mock function with given fields:
+func (_m *MockWriteBuffer) DroppedSyncCount() int64 {
+	ret := _m.Called()
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func() int64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_DroppedSyncCount_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DroppedSyncCount'
+type MockWriteBuffer_DroppedSyncCount_Call struct {
+	*mock.Call
+}
+
+// DroppedSyncCount is a helper method to define mock.On call
+func (_e *MockWriteBuffer_Expecter) DroppedSyncCount() *MockWriteBuffer_DroppedSyncCount_Call {
+	return &MockWriteBuffer_DroppedSyncCount_Call{Call: _e.mock.On("DroppedSyncCount")}
+}
+
+func (_c *MockWriteBuffer_DroppedSyncCount_Call) Run(run func()) *MockWriteBuffer_DroppedSyncCount_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_DroppedSyncCount_Call) Return(_a0 int64) *MockWriteBuffer_DroppedSyncCount_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_DroppedSyncCount_Call) RunAndReturn(run func() int64) *MockWriteBuffer_DroppedSyncCount_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// EmptySegmentIDs provides a mock function with given fields:
+func (_m *MockWriteBuffer) EmptySegmentIDs() []int64 {
+	ret := _m.Called()
+
+	var r0 []int64
+	if rf, ok := ret.Get(0).(func() []int64); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]int64)
+		}
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_EmptySegmentIDs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'EmptySegmentIDs'
+type MockWriteBuffer_EmptySegmentIDs_Call struct {
+	*mock.Call
+}
+
+// EmptySegmentIDs is a helper method to define mock.On call
+func (_e *MockWriteBuffer_Expecter) EmptySegmentIDs() *MockWriteBuffer_EmptySegmentIDs_Call {
+	return &MockWriteBuffer_EmptySegmentIDs_Call{Call: _e.mock.On("EmptySegmentIDs")}
+}
+
+func (_c *MockWriteBuffer_EmptySegmentIDs_Call) Run(run func()) *MockWriteBuffer_EmptySegmentIDs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_EmptySegmentIDs_Call) Return(_a0 []int64) *MockWriteBuffer_EmptySegmentIDs_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_EmptySegmentIDs_Call) RunAndReturn(run func() []int64) *MockWriteBuffer_EmptySegmentIDs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// EstimateFlushSize provides a mock function with given fields: segmentID
+func (_m *MockWriteBuffer) EstimateFlushSize(segmentID int64) int64 {
+	ret := _m.Called(segmentID)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(int64) int64); ok {
+		r0 = rf(segmentID)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_EstimateFlushSize_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'EstimateFlushSize'
+type MockWriteBuffer_EstimateFlushSize_Call struct {
+	*mock.Call
+}
+
+// EstimateFlushSize is a helper method to define mock.On call
+//   - segmentID int64
+func (_e *MockWriteBuffer_Expecter) EstimateFlushSize(segmentID interface{}) *MockWriteBuffer_EstimateFlushSize_Call {
+	return &MockWriteBuffer_EstimateFlushSize_Call{Call: _e.mock.On("EstimateFlushSize", segmentID)}
+}
+
+func (_c *MockWriteBuffer_EstimateFlushSize_Call) Run(run func(segmentID int64)) *MockWriteBuffer_EstimateFlushSize_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_EstimateFlushSize_Call) Return(_a0 int64) *MockWriteBuffer_EstimateFlushSize_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_EstimateFlushSize_Call) RunAndReturn(run func(int64) int64) *MockWriteBuffer_EstimateFlushSize_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ExportCheckpoint provides a mock function with given fields:
+func (_m *MockWriteBuffer) ExportCheckpoint() ([]byte, error) {
+	ret := _m.Called()
+
+	var r0 []byte
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]byte, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []byte); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockWriteBuffer_ExportCheckpoint_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ExportCheckpoint'
+type MockWriteBuffer_ExportCheckpoint_Call struct {
+	*mock.Call
+}
+
+// ExportCheckpoint is a helper method to define mock.On call
+func (_e *MockWriteBuffer_Expecter) ExportCheckpoint() *MockWriteBuffer_ExportCheckpoint_Call {
+	return &MockWriteBuffer_ExportCheckpoint_Call{Call: _e.mock.On("ExportCheckpoint")}
+}
+
+func (_c *MockWriteBuffer_ExportCheckpoint_Call) Run(run func()) *MockWriteBuffer_ExportCheckpoint_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_ExportCheckpoint_Call) Return(_a0 []byte, _a1 error) *MockWriteBuffer_ExportCheckpoint_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockWriteBuffer_ExportCheckpoint_Call) RunAndReturn(run func() ([]byte, error)) *MockWriteBuffer_ExportCheckpoint_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// FieldMemorySize provides a mock function with given fields: segmentID
+func (_m *MockWriteBuffer) FieldMemorySize(segmentID int64) map[int64]int64 {
+	ret := _m.Called(segmentID)
+
+	var r0 map[int64]int64
+	if rf, ok := ret.Get(0).(func(int64) map[int64]int64); ok {
+		r0 = rf(segmentID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[int64]int64)
+		}
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_FieldMemorySize_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FieldMemorySize'
+type MockWriteBuffer_FieldMemorySize_Call struct {
+	*mock.Call
+}
+
+// FieldMemorySize is a helper method to define mock.On call
+//   - segmentID int64
+func (_e *MockWriteBuffer_Expecter) FieldMemorySize(segmentID interface{}) *MockWriteBuffer_FieldMemorySize_Call {
+	return &MockWriteBuffer_FieldMemorySize_Call{Call: _e.mock.On("FieldMemorySize", segmentID)}
+}
+
+func (_c *MockWriteBuffer_FieldMemorySize_Call) Run(run func(segmentID int64)) *MockWriteBuffer_FieldMemorySize_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_FieldMemorySize_Call) Return(_a0 map[int64]int64) *MockWriteBuffer_FieldMemorySize_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_FieldMemorySize_Call) RunAndReturn(run func(int64) map[int64]int64) *MockWriteBuffer_FieldMemorySize_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// FlushSegments provides a mock function with given fields: ctx, segmentIDs
+func (_m *MockWriteBuffer) FlushSegments(ctx context.Context, segmentIDs []int64) error {
+	ret := _m.Called(ctx, segmentIDs)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) error); ok {
+		r0 = rf(ctx, segmentIDs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_FlushSegments_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FlushSegments'
+type MockWriteBuffer_FlushSegments_Call struct {
+	*mock.Call
+}
+
+// FlushSegments is a helper method to define mock.On call
+//   - ctx context.Context
+//   - segmentIDs []int64
+func (_e *MockWriteBuffer_Expecter) FlushSegments(ctx interface{}, segmentIDs interface{}) *MockWriteBuffer_FlushSegments_Call {
+	return &MockWriteBuffer_FlushSegments_Call{Call: _e.mock.On("FlushSegments", ctx, segmentIDs)}
+}
+
+func (_c *MockWriteBuffer_FlushSegments_Call) Run(run func(ctx context.Context, segmentIDs []int64)) *MockWriteBuffer_FlushSegments_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]int64))
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_FlushSegments_Call) Return(_a0 error) *MockWriteBuffer_FlushSegments_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_FlushSegments_Call) RunAndReturn(run func(context.Context, []int64) error) *MockWriteBuffer_FlushSegments_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// FlushSegmentsStrict provides a mock function with given fields: ctx, segmentIDs
+func (_m *MockWriteBuffer) FlushSegmentsStrict(ctx context.Context, segmentIDs []int64) error {
+	ret := _m.Called(ctx, segmentIDs)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) error); ok {
+		r0 = rf(ctx, segmentIDs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_FlushSegmentsStrict_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FlushSegmentsStrict'
+type MockWriteBuffer_FlushSegmentsStrict_Call struct {
+	*mock.Call
+}
+
+// FlushSegmentsStrict is a helper method to define mock.On call
+//   - ctx context.Context
+//   - segmentIDs []int64
+func (_e *MockWriteBuffer_Expecter) FlushSegmentsStrict(ctx interface{}, segmentIDs interface{}) *MockWriteBuffer_FlushSegmentsStrict_Call {
+	return &MockWriteBuffer_FlushSegmentsStrict_Call{Call: _e.mock.On("FlushSegmentsStrict", ctx, segmentIDs)}
+}
+
+func (_c *MockWriteBuffer_FlushSegmentsStrict_Call) Run(run func(ctx context.Context, segmentIDs []int64)) *MockWriteBuffer_FlushSegmentsStrict_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]int64))
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_FlushSegmentsStrict_Call) Return(_a0 error) *MockWriteBuffer_FlushSegmentsStrict_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_FlushSegmentsStrict_Call) RunAndReturn(run func(context.Context, []int64) error) *MockWriteBuffer_FlushSegmentsStrict_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// FlushSegmentsToPrefix provides a mock function with given fields: ctx, segmentIDs, prefix
+func (_m *MockWriteBuffer) FlushSegmentsToPrefix(ctx context.Context, segmentIDs []int64, prefix string) error {
+	ret := _m.Called(ctx, segmentIDs, prefix)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int64, string) error); ok {
+		r0 = rf(ctx, segmentIDs, prefix)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_FlushSegmentsToPrefix_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FlushSegmentsToPrefix'
+type MockWriteBuffer_FlushSegmentsToPrefix_Call struct {
+	*mock.Call
+}
+
+// FlushSegmentsToPrefix is a helper method to define mock.On call
+//   - ctx context.Context
+//   - segmentIDs []int64
+//   - prefix string
+func (_e *MockWriteBuffer_Expecter) FlushSegmentsToPrefix(ctx interface{}, segmentIDs interface{}, prefix interface{}) *MockWriteBuffer_FlushSegmentsToPrefix_Call {
+	return &MockWriteBuffer_FlushSegmentsToPrefix_Call{Call: _e.mock.On("FlushSegmentsToPrefix", ctx, segmentIDs, prefix)}
+}
+
+func (_c *MockWriteBuffer_FlushSegmentsToPrefix_Call) Run(run func(ctx context.Context, segmentIDs []int64, prefix string)) *MockWriteBuffer_FlushSegmentsToPrefix_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]int64), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_FlushSegmentsToPrefix_Call) Return(_a0 error) *MockWriteBuffer_FlushSegmentsToPrefix_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_FlushSegmentsToPrefix_Call) RunAndReturn(run func(context.Context, []int64, string) error) *MockWriteBuffer_FlushSegmentsToPrefix_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetCheckpoint provides a mock function with given fields:
+func (_m *MockWriteBuffer) GetCheckpoint() *msgpb.MsgPosition {
+	ret := _m.Called()
+
+	var r0 *msgpb.MsgPosition
+	if rf, ok := ret.Get(0).(func() *msgpb.MsgPosition); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*msgpb.MsgPosition)
+		}
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_GetCheckpoint_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetCheckpoint'
+type MockWriteBuffer_GetCheckpoint_Call struct {
+	*mock.Call
+}
+
+// GetCheckpoint is a helper method to define mock.On call
+func (_e *MockWriteBuffer_Expecter) GetCheckpoint() *MockWriteBuffer_GetCheckpoint_Call {
+	return &MockWriteBuffer_GetCheckpoint_Call{Call: _e.mock.On("GetCheckpoint")}
+}
+
+func (_c *MockWriteBuffer_GetCheckpoint_Call) Run(run func()) *MockWriteBuffer_GetCheckpoint_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_GetCheckpoint_Call) Return(_a0 *msgpb.MsgPosition) *MockWriteBuffer_GetCheckpoint_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_GetCheckpoint_Call) RunAndReturn(run func() *msgpb.MsgPosition) *MockWriteBuffer_GetCheckpoint_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetFlushTimestamp provides a mock function with given fields:
+func (_m *MockWriteBuffer) GetFlushTimestamp() uint64 {
+	ret := _m.Called()
+
+	var r0 uint64
+	if rf, ok := ret.Get(0).(func() uint64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_GetFlushTimestamp_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFlushTimestamp'
+type MockWriteBuffer_GetFlushTimestamp_Call struct {
+	*mock.Call
+}
+
+// GetFlushTimestamp is a helper method to define mock.On call
+func (_e *MockWriteBuffer_Expecter) GetFlushTimestamp() *MockWriteBuffer_GetFlushTimestamp_Call {
+	return &MockWriteBuffer_GetFlushTimestamp_Call{Call: _e.mock.On("GetFlushTimestamp")}
+}
+
+func (_c *MockWriteBuffer_GetFlushTimestamp_Call) Run(run func()) *MockWriteBuffer_GetFlushTimestamp_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_GetFlushTimestamp_Call) Return(_a0 uint64) *MockWriteBuffer_GetFlushTimestamp_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_GetFlushTimestamp_Call) RunAndReturn(run func() uint64) *MockWriteBuffer_GetFlushTimestamp_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// HasSegment provides a mock function with given fields: segmentID
+func (_m *MockWriteBuffer) HasSegment(segmentID int64) bool {
+	ret := _m.Called(segmentID)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(int64) bool); ok {
+		r0 = rf(segmentID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_HasSegment_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'HasSegment'
+type MockWriteBuffer_HasSegment_Call struct {
+	*mock.Call
+}
+
+// HasSegment is a helper method to define mock.On call
+//   - segmentID int64
+func (_e *MockWriteBuffer_Expecter) HasSegment(segmentID interface{}) *MockWriteBuffer_HasSegment_Call {
+	return &MockWriteBuffer_HasSegment_Call{Call: _e.mock.On("HasSegment", segmentID)}
+}
+
+func (_c *MockWriteBuffer_HasSegment_Call) Run(run func(segmentID int64)) *MockWriteBuffer_HasSegment_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_HasSegment_Call) Return(_a0 bool) *MockWriteBuffer_HasSegment_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_HasSegment_Call) RunAndReturn(run func(int64) bool) *MockWriteBuffer_HasSegment_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ImportCheckpoint provides a mock function with given fields: data
+func (_m *MockWriteBuffer) ImportCheckpoint(data []byte) error {
+	ret := _m.Called(data)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]byte) error); ok {
+		r0 = rf(data)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_ImportCheckpoint_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ImportCheckpoint'
+type MockWriteBuffer_ImportCheckpoint_Call struct {
+	*mock.Call
+}
+
+// ImportCheckpoint is a helper method to define mock.On call
+//   - data []byte
+func (_e *MockWriteBuffer_Expecter) ImportCheckpoint(data interface{}) *MockWriteBuffer_ImportCheckpoint_Call {
+	return &MockWriteBuffer_ImportCheckpoint_Call{Call: _e.mock.On("ImportCheckpoint", data)}
+}
+
+func (_c *MockWriteBuffer_ImportCheckpoint_Call) Run(run func(data []byte)) *MockWriteBuffer_ImportCheckpoint_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]byte))
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_ImportCheckpoint_Call) Return(_a0 error) *MockWriteBuffer_ImportCheckpoint_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_ImportCheckpoint_Call) RunAndReturn(run func([]byte) error) *MockWriteBuffer_ImportCheckpoint_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// InsertDeleteRatio provides a mock function with given fields: segmentID
+func (_m *MockWriteBuffer) InsertDeleteRatio(segmentID int64) float64 {
+	ret := _m.Called(segmentID)
+
+	var r0 float64
+	if rf, ok := ret.Get(0).(func(int64) float64); ok {
+		r0 = rf(segmentID)
+	} else {
+		r0 = ret.Get(0).(float64)
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_InsertDeleteRatio_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'InsertDeleteRatio'
+type MockWriteBuffer_InsertDeleteRatio_Call struct {
+	*mock.Call
+}
+
+// InsertDeleteRatio is a helper method to define mock.On call
+//   - segmentID int64
+func (_e *MockWriteBuffer_Expecter) InsertDeleteRatio(segmentID interface{}) *MockWriteBuffer_InsertDeleteRatio_Call {
+	return &MockWriteBuffer_InsertDeleteRatio_Call{Call: _e.mock.On("InsertDeleteRatio", segmentID)}
+}
+
+func (_c *MockWriteBuffer_InsertDeleteRatio_Call) Run(run func(segmentID int64)) *MockWriteBuffer_InsertDeleteRatio_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_InsertDeleteRatio_Call) Return(_a0 float64) *MockWriteBuffer_InsertDeleteRatio_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_InsertDeleteRatio_Call) RunAndReturn(run func(int64) float64) *MockWriteBuffer_InsertDeleteRatio_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// LastCheckpointAdvanceCause provides a mock function with given fields:
+func (_m *MockWriteBuffer) LastCheckpointAdvanceCause() (int64, string) {
+	ret := _m.Called()
+
+	var r0 int64
+	var r1 string
+	if rf, ok := ret.Get(0).(func() (int64, string)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() int64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func() string); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	return r0, r1
+}
+
+// MockWriteBuffer_LastCheckpointAdvanceCause_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'LastCheckpointAdvanceCause'
+type MockWriteBuffer_LastCheckpointAdvanceCause_Call struct {
+	*mock.Call
+}
+
+// LastCheckpointAdvanceCause is a helper method to define mock.On call
+func (_e *MockWriteBuffer_Expecter) LastCheckpointAdvanceCause() *MockWriteBuffer_LastCheckpointAdvanceCause_Call {
+	return &MockWriteBuffer_LastCheckpointAdvanceCause_Call{Call: _e.mock.On("LastCheckpointAdvanceCause")}
+}
+
+func (_c *MockWriteBuffer_LastCheckpointAdvanceCause_Call) Run(run func()) *MockWriteBuffer_LastCheckpointAdvanceCause_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_LastCheckpointAdvanceCause_Call) Return(_a0 int64, _a1 string) *MockWriteBuffer_LastCheckpointAdvanceCause_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockWriteBuffer_LastCheckpointAdvanceCause_Call) RunAndReturn(run func() (int64, string)) *MockWriteBuffer_LastCheckpointAdvanceCause_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// LastSyncError provides a mock function with given fields: segmentID
+func (_m *MockWriteBuffer) LastSyncError(segmentID int64) error {
+	ret := _m.Called(segmentID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int64) error); ok {
+		r0 = rf(segmentID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_LastSyncError_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'LastSyncError'
+type MockWriteBuffer_LastSyncError_Call struct {
+	*mock.Call
+}
+
+// LastSyncError is a helper method to define mock.On call
+//   - segmentID int64
+func (_e *MockWriteBuffer_Expecter) LastSyncError(segmentID interface{}) *MockWriteBuffer_LastSyncError_Call {
+	return &MockWriteBuffer_LastSyncError_Call{Call: _e.mock.On("LastSyncError", segmentID)}
+}
+
+func (_c *MockWriteBuffer_LastSyncError_Call) Run(run func(segmentID int64)) *MockWriteBuffer_LastSyncError_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_LastSyncError_Call) Return(_a0 error) *MockWriteBuffer_LastSyncError_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_LastSyncError_Call) RunAndReturn(run func(int64) error) *MockWriteBuffer_LastSyncError_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// LookupDelete provides a mock function with given fields: segmentID, pk
+func (_m *MockWriteBuffer) LookupDelete(segmentID int64, pk storage.PrimaryKey) (typeutil.Timestamp, bool, error) {
+	ret := _m.Called(segmentID, pk)
+
+	var r0 typeutil.Timestamp
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(int64, storage.PrimaryKey) (typeutil.Timestamp, bool, error)); ok {
+		return rf(segmentID, pk)
+	}
+	if rf, ok := ret.Get(0).(func(int64, storage.PrimaryKey) typeutil.Timestamp); ok {
+		r0 = rf(segmentID, pk)
+	} else {
+		r0 = ret.Get(0).(typeutil.Timestamp)
+	}
+
+	if rf, ok := ret.Get(1).(func(int64, storage.PrimaryKey) bool); ok {
+		r1 = rf(segmentID, pk)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(int64, storage.PrimaryKey) error); ok {
+		r2 = rf(segmentID, pk)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// MockWriteBuffer_LookupDelete_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'LookupDelete'
+type MockWriteBuffer_LookupDelete_Call struct {
+	*mock.Call
+}
+
+// LookupDelete is a helper method to define mock.On call
+//   - segmentID int64
+//   - pk storage.PrimaryKey
+func (_e *MockWriteBuffer_Expecter) LookupDelete(segmentID interface{}, pk interface{}) *MockWriteBuffer_LookupDelete_Call {
+	return &MockWriteBuffer_LookupDelete_Call{Call: _e.mock.On("LookupDelete", segmentID, pk)}
+}
+
+func (_c *MockWriteBuffer_LookupDelete_Call) Run(run func(segmentID int64, pk storage.PrimaryKey)) *MockWriteBuffer_LookupDelete_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64), args[1].(storage.PrimaryKey))
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_LookupDelete_Call) Return(_a0 typeutil.Timestamp, _a1 bool, _a2 error) *MockWriteBuffer_LookupDelete_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *MockWriteBuffer_LookupDelete_Call) RunAndReturn(run func(int64, storage.PrimaryKey) (typeutil.Timestamp, bool, error)) *MockWriteBuffer_LookupDelete_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// MemorySplit provides a mock function with given fields:
+func (_m *MockWriteBuffer) MemorySplit() (int64, int64) {
+	ret := _m.Called()
+
+	var r0 int64
+	var r1 int64
+	if rf, ok := ret.Get(0).(func() (int64, int64)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() int64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func() int64); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Get(1).(int64)
+	}
+
+	return r0, r1
+}
+
+// MockWriteBuffer_MemorySplit_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'MemorySplit'
+type MockWriteBuffer_MemorySplit_Call struct {
+	*mock.Call
+}
+
+// MemorySplit is a helper method to define mock.On call
+func (_e *MockWriteBuffer_Expecter) MemorySplit() *MockWriteBuffer_MemorySplit_Call {
+	return &MockWriteBuffer_MemorySplit_Call{Call: _e.mock.On("MemorySplit")}
+}
+
+func (_c *MockWriteBuffer_MemorySplit_Call) Run(run func()) *MockWriteBuffer_MemorySplit_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_MemorySplit_Call) Return(_a0 int64, _a1 int64) *MockWriteBuffer_MemorySplit_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockWriteBuffer_MemorySplit_Call) RunAndReturn(run func() (int64, int64)) *MockWriteBuffer_MemorySplit_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// OldestUnsyncedAge provides a mock function with given fields: now
+func (_m *MockWriteBuffer) OldestUnsyncedAge(now uint64) time.Duration {
+	ret := _m.Called(now)
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func(uint64) time.Duration); ok {
+		r0 = rf(now)
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_OldestUnsyncedAge_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'OldestUnsyncedAge'
+type MockWriteBuffer_OldestUnsyncedAge_Call struct {
+	*mock.Call
+}
+
+// OldestUnsyncedAge is a helper method to define mock.On call
+//   - now uint64
+func (_e *MockWriteBuffer_Expecter) OldestUnsyncedAge(now interface{}) *MockWriteBuffer_OldestUnsyncedAge_Call {
+	return &MockWriteBuffer_OldestUnsyncedAge_Call{Call: _e.mock.On("OldestUnsyncedAge", now)}
+}
+
+func (_c *MockWriteBuffer_OldestUnsyncedAge_Call) Run(run func(now uint64)) *MockWriteBuffer_OldestUnsyncedAge_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(uint64))
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_OldestUnsyncedAge_Call) Return(_a0 time.Duration) *MockWriteBuffer_OldestUnsyncedAge_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_OldestUnsyncedAge_Call) RunAndReturn(run func(uint64) time.Duration) *MockWriteBuffer_OldestUnsyncedAge_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Reconcile provides a mock function with given fields:
+func (_m *MockWriteBuffer) Reconcile() []int64 {
+	ret := _m.Called()
+
+	var r0 []int64
+	if rf, ok := ret.Get(0).(func() []int64); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]int64)
+		}
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_Reconcile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Reconcile'
+type MockWriteBuffer_Reconcile_Call struct {
+	*mock.Call
+}
+
+// Reconcile is a helper method to define mock.On call
+func (_e *MockWriteBuffer_Expecter) Reconcile() *MockWriteBuffer_Reconcile_Call {
+	return &MockWriteBuffer_Reconcile_Call{Call: _e.mock.On("Reconcile")}
+}
+
+func (_c *MockWriteBuffer_Reconcile_Call) Run(run func()) *MockWriteBuffer_Reconcile_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_Reconcile_Call) Return(_a0 []int64) *MockWriteBuffer_Reconcile_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_Reconcile_Call) RunAndReturn(run func() []int64) *MockWriteBuffer_Reconcile_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ResetThresholds provides a mock function with given fields:
+func (_m *MockWriteBuffer) ResetThresholds() {
+	_m.Called()
+}
+
+// MockWriteBuffer_ResetThresholds_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ResetThresholds'
+type MockWriteBuffer_ResetThresholds_Call struct {
+	*mock.Call
+}
+
+// ResetThresholds is a helper method to define mock.On call
+func (_e *MockWriteBuffer_Expecter) ResetThresholds() *MockWriteBuffer_ResetThresholds_Call {
+	return &MockWriteBuffer_ResetThresholds_Call{Call: _e.mock.On("ResetThresholds")}
+}
+
+func (_c *MockWriteBuffer_ResetThresholds_Call) Run(run func()) *MockWriteBuffer_ResetThresholds_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_ResetThresholds_Call) Return() *MockWriteBuffer_ResetThresholds_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockWriteBuffer_ResetThresholds_Call) RunAndReturn(run func()) *MockWriteBuffer_ResetThresholds_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RowLagStats provides a mock function with given fields:
+func (_m *MockWriteBuffer) RowLagStats() map[int64]int64 {
+	ret := _m.Called()
+
+	var r0 map[int64]int64
+	if rf, ok := ret.Get(0).(func() map[int64]int64); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[int64]int64)
+		}
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_RowLagStats_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RowLagStats'
+type MockWriteBuffer_RowLagStats_Call struct {
+	*mock.Call
+}
+
+// RowLagStats is a helper method to define mock.On call
+func (_e *MockWriteBuffer_Expecter) RowLagStats() *MockWriteBuffer_RowLagStats_Call {
+	return &MockWriteBuffer_RowLagStats_Call{Call: _e.mock.On("RowLagStats")}
+}
+
+func (_c *MockWriteBuffer_RowLagStats_Call) Run(run func()) *MockWriteBuffer_RowLagStats_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_RowLagStats_Call) Return(_a0 map[int64]int64) *MockWriteBuffer_RowLagStats_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_RowLagStats_Call) RunAndReturn(run func() map[int64]int64) *MockWriteBuffer_RowLagStats_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SealSegment provides a mock function with given fields: segmentID
+func (_m *MockWriteBuffer) SealSegment(segmentID int64) error {
+	ret := _m.Called(segmentID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int64) error); ok {
+		r0 = rf(segmentID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_SealSegment_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SealSegment'
+type MockWriteBuffer_SealSegment_Call struct {
+	*mock.Call
+}
+
+// SealSegment is a helper method to define mock.On call
+//   - segmentID int64
+func (_e *MockWriteBuffer_Expecter) SealSegment(segmentID interface{}) *MockWriteBuffer_SealSegment_Call {
+	return &MockWriteBuffer_SealSegment_Call{Call: _e.mock.On("SealSegment", segmentID)}
+}
+
+func (_c *MockWriteBuffer_SealSegment_Call) Run(run func(segmentID int64)) *MockWriteBuffer_SealSegment_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_SealSegment_Call) Return(_a0 error) *MockWriteBuffer_SealSegment_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_SealSegment_Call) RunAndReturn(run func(int64) error) *MockWriteBuffer_SealSegment_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SegmentPartition provides a mock function with given fields: segmentID
+func (_m *MockWriteBuffer) SegmentPartition(segmentID int64) (int64, bool) {
+	ret := _m.Called(segmentID)
+
+	var r0 int64
+	var r1 bool
+	if rf, ok := ret.Get(0).(func(int64) (int64, bool)); ok {
+		return rf(segmentID)
+	}
+	if rf, ok := ret.Get(0).(func(int64) int64); ok {
+		r0 = rf(segmentID)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(int64) bool); ok {
+		r1 = rf(segmentID)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	return r0, r1
+}
+
+// MockWriteBuffer_SegmentPartition_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SegmentPartition'
+type MockWriteBuffer_SegmentPartition_Call struct {
+	*mock.Call
+}
+
+// SegmentPartition is a helper method to define mock.On call
+//   - segmentID int64
+func (_e *MockWriteBuffer_Expecter) SegmentPartition(segmentID interface{}) *MockWriteBuffer_SegmentPartition_Call {
+	return &MockWriteBuffer_SegmentPartition_Call{Call: _e.mock.On("SegmentPartition", segmentID)}
+}
+
+func (_c *MockWriteBuffer_SegmentPartition_Call) Run(run func(segmentID int64)) *MockWriteBuffer_SegmentPartition_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64))
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_SegmentPartition_Call) Return(_a0 int64, _a1 bool) *MockWriteBuffer_SegmentPartition_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockWriteBuffer_SegmentPartition_Call) RunAndReturn(run func(int64) (int64, bool)) *MockWriteBuffer_SegmentPartition_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetFlushTimestamp provides a mock function with given fields: flushTs
+func (_m *MockWriteBuffer) SetFlushTimestamp(flushTs uint64) {
+	_m.Called(flushTs)
+}
+
+// MockWriteBuffer_SetFlushTimestamp_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetFlushTimestamp'
+type MockWriteBuffer_SetFlushTimestamp_Call struct {
+	*mock.Call
+}
+
+// SetFlushTimestamp is a helper method to define mock.On call
+//   - flushTs uint64
+func (_e *MockWriteBuffer_Expecter) SetFlushTimestamp(flushTs interface{}) *MockWriteBuffer_SetFlushTimestamp_Call {
+	return &MockWriteBuffer_SetFlushTimestamp_Call{Call: _e.mock.On("SetFlushTimestamp", flushTs)}
+}
+
+func (_c *MockWriteBuffer_SetFlushTimestamp_Call) Run(run func(flushTs uint64)) *MockWriteBuffer_SetFlushTimestamp_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(uint64))
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_SetFlushTimestamp_Call) Return() *MockWriteBuffer_SetFlushTimestamp_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockWriteBuffer_SetFlushTimestamp_Call) RunAndReturn(run func(uint64)) *MockWriteBuffer_SetFlushTimestamp_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetThresholdMultiplier provides a mock function with given fields: m
+func (_m *MockWriteBuffer) SetThresholdMultiplier(m float64) {
+	_m.Called(m)
+}
+
+// MockWriteBuffer_SetThresholdMultiplier_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetThresholdMultiplier'
+type MockWriteBuffer_SetThresholdMultiplier_Call struct {
+	*mock.Call
+}
+
+// SetThresholdMultiplier is a helper method to define mock.On call
+//   - m float64
+func (_e *MockWriteBuffer_Expecter) SetThresholdMultiplier(m interface{}) *MockWriteBuffer_SetThresholdMultiplier_Call {
+	return &MockWriteBuffer_SetThresholdMultiplier_Call{Call: _e.mock.On("SetThresholdMultiplier", m)}
+}
+
+func (_c *MockWriteBuffer_SetThresholdMultiplier_Call) Run(run func(m float64)) *MockWriteBuffer_SetThresholdMultiplier_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(float64))
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_SetThresholdMultiplier_Call) Return() *MockWriteBuffer_SetThresholdMultiplier_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockWriteBuffer_SetThresholdMultiplier_Call) RunAndReturn(run func(float64)) *MockWriteBuffer_SetThresholdMultiplier_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SnapshotFlush provides a mock function with given fields: ctx, ts
+func (_m *MockWriteBuffer) SnapshotFlush(ctx context.Context, ts uint64) ([]int64, error) {
+	ret := _m.Called(ctx, ts)
+
+	var r0 []int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) ([]int64, error)); ok {
+		return rf(ctx, ts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) []int64); ok {
+		r0 = rf(ctx, ts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]int64)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint64) error); ok {
+		r1 = rf(ctx, ts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockWriteBuffer_SnapshotFlush_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SnapshotFlush'
+type MockWriteBuffer_SnapshotFlush_Call struct {
+	*mock.Call
+}
+
+// SnapshotFlush is a helper method to define mock.On call
+//   - ctx context.Context
+//   - ts uint64
+func (_e *MockWriteBuffer_Expecter) SnapshotFlush(ctx interface{}, ts interface{}) *MockWriteBuffer_SnapshotFlush_Call {
+	return &MockWriteBuffer_SnapshotFlush_Call{Call: _e.mock.On("SnapshotFlush", ctx, ts)}
+}
+
+func (_c *MockWriteBuffer_SnapshotFlush_Call) Run(run func(ctx context.Context, ts uint64)) *MockWriteBuffer_SnapshotFlush_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(uint64))
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_SnapshotFlush_Call) Return(_a0 []int64, _a1 error) *MockWriteBuffer_SnapshotFlush_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockWriteBuffer_SnapshotFlush_Call) RunAndReturn(run func(context.Context, uint64) ([]int64, error)) *MockWriteBuffer_SnapshotFlush_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SyncBacklog provides a mock function with given fields:
+func (_m *MockWriteBuffer) SyncBacklog() []SyncBacklogEntry {
+	ret := _m.Called()
+
+	var r0 []SyncBacklogEntry
+	if rf, ok := ret.Get(0).(func() []SyncBacklogEntry); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]SyncBacklogEntry)
+		}
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_SyncBacklog_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SyncBacklog'
+type MockWriteBuffer_SyncBacklog_Call struct {
+	*mock.Call
+}
+
+// SyncBacklog is a helper method to define mock.On call
+func (_e *MockWriteBuffer_Expecter) SyncBacklog() *MockWriteBuffer_SyncBacklog_Call {
+	return &MockWriteBuffer_SyncBacklog_Call{Call: _e.mock.On("SyncBacklog")}
+}
+
+func (_c *MockWriteBuffer_SyncBacklog_Call) Run(run func()) *MockWriteBuffer_SyncBacklog_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_SyncBacklog_Call) Return(_a0 []SyncBacklogEntry) *MockWriteBuffer_SyncBacklog_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_SyncBacklog_Call) RunAndReturn(run func() []SyncBacklogEntry) *MockWriteBuffer_SyncBacklog_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SyncManagerEarliestPosition provides a mock function with given fields:
+func (_m *MockWriteBuffer) SyncManagerEarliestPosition() (int64, *msgpb.MsgPosition) {
+	ret := _m.Called()
+
+	var r0 int64
+	var r1 *msgpb.MsgPosition
+	if rf, ok := ret.Get(0).(func() (int64, *msgpb.MsgPosition)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() int64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func() *msgpb.MsgPosition); ok {
+		r1 = rf()
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*msgpb.MsgPosition)
+		}
+	}
+
+	return r0, r1
+}
+
+// MockWriteBuffer_SyncManagerEarliestPosition_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SyncManagerEarliestPosition'
+type MockWriteBuffer_SyncManagerEarliestPosition_Call struct {
+	*mock.Call
+}
+
+// SyncManagerEarliestPosition is a helper method to define mock.On call
+func (_e *MockWriteBuffer_Expecter) SyncManagerEarliestPosition() *MockWriteBuffer_SyncManagerEarliestPosition_Call {
+	return &MockWriteBuffer_SyncManagerEarliestPosition_Call{Call: _e.mock.On("SyncManagerEarliestPosition")}
+}
+
+func (_c *MockWriteBuffer_SyncManagerEarliestPosition_Call) Run(run func()) *MockWriteBuffer_SyncManagerEarliestPosition_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_SyncManagerEarliestPosition_Call) Return(_a0 int64, _a1 *msgpb.MsgPosition) *MockWriteBuffer_SyncManagerEarliestPosition_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockWriteBuffer_SyncManagerEarliestPosition_Call) RunAndReturn(run func() (int64, *msgpb.MsgPosition)) *MockWriteBuffer_SyncManagerEarliestPosition_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// TopSegmentsByMemory provides a mock function with given fields: n
+func (_m *MockWriteBuffer) TopSegmentsByMemory(n int) []SegmentBufferStats {
+	ret := _m.Called(n)
+
+	var r0 []SegmentBufferStats
+	if rf, ok := ret.Get(0).(func(int) []SegmentBufferStats); ok {
+		r0 = rf(n)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]SegmentBufferStats)
+		}
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_TopSegmentsByMemory_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'TopSegmentsByMemory'
+type MockWriteBuffer_TopSegmentsByMemory_Call struct {
+	*mock.Call
+}
+
+// TopSegmentsByMemory is a helper method to define mock.On call
+//   - n int
+func (_e *MockWriteBuffer_Expecter) TopSegmentsByMemory(n interface{}) *MockWriteBuffer_TopSegmentsByMemory_Call {
+	return &MockWriteBuffer_TopSegmentsByMemory_Call{Call: _e.mock.On("TopSegmentsByMemory", n)}
+}
+
+func (_c *MockWriteBuffer_TopSegmentsByMemory_Call) Run(run func(n int)) *MockWriteBuffer_TopSegmentsByMemory_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int))
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_TopSegmentsByMemory_Call) Return(_a0 []SegmentBufferStats) *MockWriteBuffer_TopSegmentsByMemory_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_TopSegmentsByMemory_Call) RunAndReturn(run func(int) []SegmentBufferStats) *MockWriteBuffer_TopSegmentsByMemory_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// TotalFlushedRows provides a mock function with given fields:
+func (_m *MockWriteBuffer) TotalFlushedRows() int64 {
+	ret := _m.Called()
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func() int64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_TotalFlushedRows_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'TotalFlushedRows'
+type MockWriteBuffer_TotalFlushedRows_Call struct {
+	*mock.Call
+}
+
+// TotalFlushedRows is a helper method to define mock.On call
+func (_e *MockWriteBuffer_Expecter) TotalFlushedRows() *MockWriteBuffer_TotalFlushedRows_Call {
+	return &MockWriteBuffer_TotalFlushedRows_Call{Call: _e.mock.On("TotalFlushedRows")}
+}
+
+func (_c *MockWriteBuffer_TotalFlushedRows_Call) Run(run func()) *MockWriteBuffer_TotalFlushedRows_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_TotalFlushedRows_Call) Return(_a0 int64) *MockWriteBuffer_TotalFlushedRows_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_TotalFlushedRows_Call) RunAndReturn(run func() int64) *MockWriteBuffer_TotalFlushedRows_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateSchema provides a mock function with given fields: schema
+func (_m *MockWriteBuffer) UpdateSchema(schema *schemapb.CollectionSchema) error {
+	ret := _m.Called(schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*schemapb.CollectionSchema) error); ok {
+		r0 = rf(schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_UpdateSchema_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateSchema'
+type MockWriteBuffer_UpdateSchema_Call struct {
+	*mock.Call
+}
+
+// UpdateSchema is a helper method to define mock.On call
+//   - schema *schemapb.CollectionSchema
+func (_e *MockWriteBuffer_Expecter) UpdateSchema(schema interface{}) *MockWriteBuffer_UpdateSchema_Call {
+	return &MockWriteBuffer_UpdateSchema_Call{Call: _e.mock.On("UpdateSchema", schema)}
+}
+
+func (_c *MockWriteBuffer_UpdateSchema_Call) Run(run func(schema *schemapb.CollectionSchema)) *MockWriteBuffer_UpdateSchema_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*schemapb.CollectionSchema))
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_UpdateSchema_Call) Return(_a0 error) *MockWriteBuffer_UpdateSchema_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_UpdateSchema_Call) RunAndReturn(run func(*schemapb.CollectionSchema) error) *MockWriteBuffer_UpdateSchema_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// WarmupBloomFilters provides a mock function with given fields: ctx
+func (_m *MockWriteBuffer) WarmupBloomFilters(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_WarmupBloomFilters_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'WarmupBloomFilters'
+type MockWriteBuffer_WarmupBloomFilters_Call struct {
+	*mock.Call
+}
+
+// WarmupBloomFilters is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockWriteBuffer_Expecter) WarmupBloomFilters(ctx interface{}) *MockWriteBuffer_WarmupBloomFilters_Call {
+	return &MockWriteBuffer_WarmupBloomFilters_Call{Call: _e.mock.On("WarmupBloomFilters", ctx)}
+}
+
+func (_c *MockWriteBuffer_WarmupBloomFilters_Call) Run(run func(ctx context.Context)) *MockWriteBuffer_WarmupBloomFilters_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_WarmupBloomFilters_Call) Return(_a0 error) *MockWriteBuffer_WarmupBloomFilters_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_WarmupBloomFilters_Call) RunAndReturn(run func(context.Context) error) *MockWriteBuffer_WarmupBloomFilters_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// WatchBufferSize provides a mock function with given fields:
+func (_m *MockWriteBuffer) WatchBufferSize() <-chan int64 {
+	ret := _m.Called()
+
+	var r0 <-chan int64
+	if rf, ok := ret.Get(0).(func() <-chan int64); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(<-chan int64)
+		}
+	}
+
+	return r0
+}
+
+// MockWriteBuffer_WatchBufferSize_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'WatchBufferSize'
+type MockWriteBuffer_WatchBufferSize_Call struct {
+	*mock.Call
+}
+
+// WatchBufferSize is a helper method to define mock.On call
+func (_e *MockWriteBuffer_Expecter) WatchBufferSize() *MockWriteBuffer_WatchBufferSize_Call {
+	return &MockWriteBuffer_WatchBufferSize_Call{Call: _e.mock.On("WatchBufferSize")}
+}
+
+func (_c *MockWriteBuffer_WatchBufferSize_Call) Run(run func()) *MockWriteBuffer_WatchBufferSize_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockWriteBuffer_WatchBufferSize_Call) Return(_a0 <-chan int64) *MockWriteBuffer_WatchBufferSize_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWriteBuffer_WatchBufferSize_Call) RunAndReturn(run func() <-chan int64) *MockWriteBuffer_WatchBufferSize_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -1,11 +1,15 @@
 package writebuffer
 
 import (
+	"context"
 	"time"
 
+	"github.com/milvus-io/milvus-proto/go-api/v2/msgpb"
 	"github.com/milvus-io/milvus/internal/allocator"
 	"github.com/milvus-io/milvus/internal/datanode/metacache"
 	"github.com/milvus-io/milvus/internal/datanode/syncmgr"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/milvus-io/milvus/internal/storage"
 	"github.com/milvus-io/milvus/pkg/util/paramtable"
 )
 
@@ -17,6 +21,13 @@ const (
 	DeletePolicyL0Delta = `l0_delta`
 )
 
+const (
+	// BinlogFormatV1 is the binlog format written by storage v1 sync task.
+	BinlogFormatV1 = 1
+	// BinlogFormatV2 is the space format written by storage v2 sync task.
+	BinlogFormatV2 = 2
+)
+
 type WriteBufferOption func(opt *writeBufferOption)
 
 type writeBufferOption struct {
@@ -26,6 +37,78 @@ type writeBufferOption struct {
 
 	pkStatsFactory metacache.PkStatsFactory
 	metaWriter     syncmgr.MetaWriter
+
+	// decoupleDeltaFlush keeps delta data buffered when insert data is synced.
+	decoupleDeltaFlush bool
+	// arrowNativeBuffering builds arrow record while buffering insert data when storage v2 is used.
+	arrowNativeBuffering bool
+	// orderedSync submits delta sync task after insert sync task of the same segment finishes.
+	orderedSync bool
+	// flushOnSchemaChange syncs all buffered data before UpdateSchema swaps in new schema.
+	flushOnSchemaChange bool
+	// keepEmptyInserts buffers insert msgs without any row instead of skipping them.
+	keepEmptyInserts bool
+	// noAutoSegmentCreate rejects insert data of segments not in metacache instead of creating them.
+	noAutoSegmentCreate bool
+	// closeParallelism bounds the number of sync tasks in flight when Close(true) syncs buffered data, unbounded if not positive.
+	closeParallelism int
+	// dropWithoutFlush makes Close(true) discard buffered data instead of syncing it before dropping channel.
+	dropWithoutFlush bool
+	// syncDeduplication skips segments with unfinished sync task when syncing selected segments.
+	syncDeduplication bool
+	// deleteIndexDir is the directory to spill delete index into, delete index is disabled if empty.
+	deleteIndexDir string
+	// deleteIndexMaxEntries is the number of pks kept in memory by delete index of each segment before spilling.
+	deleteIndexMaxEntries int
+	// deletePruning drops buffered deletes older than all insert data of segment before syncing.
+	deletePruning bool
+	// segmentIDAllocator allocates segment id for new segments instead of using msg segment id.
+	segmentIDAllocator func() int64
+	// pkExtractor extracts primary keys from insert data for bloom filter.
+	pkExtractor func(*storage.InsertData) []storage.PrimaryKey
+	// autoSyncInterval is the interval to evaluate sync policies periodically, disabled if not positive.
+	autoSyncInterval time.Duration
+	// spaceCreateAttempts & spaceCreateDelay configure retry of storage v2 space creation.
+	spaceCreateAttempts int
+	spaceCreateDelay    time.Duration
+	// ingestRateLimit limits bytes buffered per second, disabled if not positive.
+	ingestRateLimit int64
+	// ingestRateLimitBlock makes buffering wait for quota instead of returning ErrRateLimited.
+	ingestRateLimitBlock bool
+	// statsLoader loads persisted pk statistics of segment for bloom filter warmup.
+	statsLoader StatsLoader
+	// binlogFormatVersion pins the format of logs written by sync tasks, follows storage v2 config if zero.
+	binlogFormatVersion int
+	// bufferSizeWatchDelta is the min change of buffered size to emit by WatchBufferSize.
+	bufferSizeWatchDelta int64
+	// maxDeleteBatch is the max number of pks buffered into one segment per call before delta flush, disabled if not positive.
+	maxDeleteBatch int
+	// skipDimValidation skips checking vector dim and element count of insert data against schema.
+	skipDimValidation bool
+	// dropUnknownFields drops insert field data absent from schema instead of rejecting the msg.
+	dropUnknownFields bool
+	// maxBufferedPartitions is the max number of partitions buffered before the least recently written one is flushed, disabled if not positive.
+	maxBufferedPartitions int
+	// timeRangeGranularity quantizes time range of sync tasks to buckets of this granularity, disabled if not positive.
+	timeRangeGranularity time.Duration
+	// checkpointComparator compares positions when selecting checkpoint, compares timestamp if nil.
+	checkpointComparator func(a, b *msgpb.MsgPosition) int
+	// conservativeCheckpoint holds checkpoint at start positions of sync tasks until they succeed.
+	conservativeCheckpoint bool
+	// checkpointFromSyncManagerOnly ignores buffer positions when evaluating checkpoint.
+	checkpointFromSyncManagerOnly bool
+	// checkpointUnblockCallback is notified when completed flush advances channel checkpoint.
+	checkpointUnblockCallback func(oldCP, newCP *msgpb.MsgPosition)
+	// levelPriority maps segment level to sync task priority, levels not in map have zero priority.
+	levelPriority map[int32]int
+	// flushTsInclusive makes flush ts policy select buffers starting exactly at flush ts.
+	flushTsInclusive bool
+	// flushEventSink receives an event for each completed sync task.
+	flushEventSink func(FlushEvent)
+	// preSyncTransform transforms data yielded from buffer before sync task is built.
+	preSyncTransform func(segmentID int64, insert *storage.InsertData, delete *storage.DeleteData) error
+	// postFlushVerifier reads back binlogs written by sync tasks to verify their row count.
+	postFlushVerifier FlushVerifyReader
 }
 
 func defaultWBOption(metacache metacache.MetaCache) *writeBufferOption {
@@ -43,6 +126,10 @@ func defaultWBOption(metacache metacache.MetaCache) *writeBufferOption {
 			GetCompactedSegmentsPolicy(metacache),
 			GetFlushingSegmentsPolicy(metacache),
 		},
+		// L0 delta shall be flushed ahead of L1 segments
+		levelPriority: map[int32]int{
+			int32(datapb.SegmentLevel_L0): 1,
+		},
 	}
 }
 
@@ -75,3 +162,277 @@ func WithSyncPolicy(policy SyncPolicy) WriteBufferOption {
 		opt.syncPolicies = append(opt.syncPolicies, policy)
 	}
 }
+
+// WithCoupledDeltaFlush controls whether buffered delta data is synced along with insert data.
+// When disabled, delta data of growing segment stays in buffer until its own threshold is reached.
+func WithCoupledDeltaFlush(coupled bool) WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.decoupleDeltaFlush = !coupled
+	}
+}
+
+// WithFlushOnSchemaChange makes UpdateSchema sync all buffered data with the old schema before swapping in the new one,
+// so that data buffered under different schemas never mixes in one buffer.
+func WithFlushOnSchemaChange() WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.flushOnSchemaChange = true
+	}
+}
+
+// WithConservativeCheckpoint makes checkpoint advance past the position of synced data only after its sync task succeeded,
+// regardless of the positions tracked by sync manager.
+func WithConservativeCheckpoint() WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.conservativeCheckpoint = true
+	}
+}
+
+// WithSkipEmptyInserts controls whether insert msgs without any row are skipped, which is the default.
+// If not skipped, such msgs create segments in metacache and buffers in write buffer as normal ones.
+func WithSkipEmptyInserts(skip bool) WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.keepEmptyInserts = !skip
+	}
+}
+
+// WithSegmentIDAllocator makes write buffer allocate ids for new segments instead of trusting segment id in msg.
+func WithSegmentIDAllocator(allocator func() int64) WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.segmentIDAllocator = allocator
+	}
+}
+
+// WithArrowNativeBuffering makes write buffer append insert data into arrow record builders while buffering
+// when storage v2 is enabled, so that sync task writes the record without converting insert data.
+func WithArrowNativeBuffering() WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.arrowNativeBuffering = true
+	}
+}
+
+// WithOrderedSync makes write buffer submit delta sync task of a segment only after its insert sync task finishes.
+func WithOrderedSync() WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.orderedSync = true
+	}
+}
+
+// WithSyncDeduplication makes write buffer not submit another sync task of a segment until its previous one finishes,
+// so that segments selected by both explicit flush and sync policies are not synced twice concurrently.
+func WithSyncDeduplication() WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.syncDeduplication = true
+	}
+}
+
+// WithDeletePruning makes write buffer drop buffered deletes of a segment whose timestamp is below the earliest insert
+// timestamp of the segment before syncing, since such deletes could not apply to any row of it.
+// Only segments created by write buffer are pruned, the insert timestamps of recovered segments are unknown.
+func WithDeletePruning() WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.deletePruning = true
+	}
+}
+
+// WithDeleteIndexSpill makes delta buffers index buffered deletes by pk for LookupDelete.
+// Each segment keeps at most maxEntries pks of the index in memory, the rest is spilled into sorted files under dir,
+// which are removed once the buffer is synced or dropped. Index is never spilled if maxEntries is not positive.
+func WithDeleteIndexSpill(dir string, maxEntries int) WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.deleteIndexDir = dir
+		opt.deleteIndexMaxEntries = maxEntries
+	}
+}
+
+// WithPKExtractor sets the function extracting primary keys from buffered insert data.
+func WithPKExtractor(extractor func(*storage.InsertData) []storage.PrimaryKey) WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.pkExtractor = extractor
+	}
+}
+
+// WithAutoSyncInterval makes write buffer evaluate sync policies periodically with provided interval.
+func WithAutoSyncInterval(interval time.Duration) WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.autoSyncInterval = interval
+	}
+}
+
+// WithSpaceCreateRetry makes write buffer retry storage v2 space creation with provided attempts and initial delay.
+func WithSpaceCreateRetry(attempts int, delay time.Duration) WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.spaceCreateAttempts = attempts
+		opt.spaceCreateDelay = delay
+	}
+}
+
+// WithIngestRateLimit limits bytes buffered per second, BufferData returns ErrRateLimited when exceeded.
+func WithIngestRateLimit(bytesPerSec int64) WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.ingestRateLimit = bytesPerSec
+	}
+}
+
+// WithIngestRateLimitBlocking makes BufferData block until quota is available when ingest rate limit exceeded.
+func WithIngestRateLimitBlocking() WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.ingestRateLimitBlock = true
+	}
+}
+
+// StatsLoader loads persisted pk statistics of provided segment from storage.
+type StatsLoader func(ctx context.Context, segmentID int64) ([]*storage.PkStatistics, error)
+
+// WithStatsLoader sets the loader used by WarmupBloomFilters.
+func WithStatsLoader(loader StatsLoader) WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.statsLoader = loader
+	}
+}
+
+// WithBinlogFormatVersion pins the format of logs written by sync tasks, see BinlogFormatV1 & BinlogFormatV2.
+func WithBinlogFormatVersion(v int) WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.binlogFormatVersion = v
+	}
+}
+
+// WithBufferSizeWatchDelta makes WatchBufferSize emit only when buffered size changes by more than delta bytes.
+func WithBufferSizeWatchDelta(delta int64) WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.bufferSizeWatchDelta = delta
+	}
+}
+
+// WithMaxDeleteBatch makes write buffer sync segment immediately once n delete pks buffered in one call,
+// the remaining pks are buffered after the sync.
+func WithMaxDeleteBatch(n int) WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.maxDeleteBatch = n
+	}
+}
+
+// WithDimValidation controls whether vector dim and element count of insert data are checked against schema dim,
+// which is the default. Mismatched insert msgs are rejected with ErrDimMismatch.
+func WithDimValidation(enabled bool) WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.skipDimValidation = !enabled
+	}
+}
+
+// WithDropUnknownFields makes write buffer log and drop insert field data absent from collection schema,
+// instead of rejecting the insert msg with ErrSchemaDrift.
+func WithDropUnknownFields() WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.dropUnknownFields = true
+	}
+}
+
+// WithMaxBufferedPartitions limits the number of partitions buffered in write buffer,
+// segments of the least recently written partitions are flushed once more than n partitions are buffered.
+func WithMaxBufferedPartitions(n int) WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.maxBufferedPartitions = n
+	}
+}
+
+// WithTimeRangeGranularity makes write buffer align time range of sync tasks to buckets of provided granularity.
+func WithTimeRangeGranularity(granularity time.Duration) WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.timeRangeGranularity = granularity
+	}
+}
+
+// WithLevelPriority sets the sync task priority of each segment level, tasks with larger priority are submitted earlier.
+func WithLevelPriority(priorities map[int32]int) WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.levelPriority = priorities
+	}
+}
+
+// WithInclusiveFlushTs makes flush ts policy select buffers starting at or before flush ts,
+// instead of only those starting strictly before it.
+func WithInclusiveFlushTs() WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.flushTsInclusive = true
+	}
+}
+
+// WithFlushEventSink sets the sink receiving an event for each completed sync task.
+// The sink is invoked by sync task and shall not block.
+func WithFlushEventSink(sink func(FlushEvent)) WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.flushEventSink = sink
+	}
+}
+
+// WithPreSyncTransform sets the transform applied in place to buffered data before sync task of the segment is built,
+// insert or delete is nil if there is no such data to sync. Returning error aborts the sync and keeps data buffered.
+func WithPreSyncTransform(transform func(segmentID int64, insert *storage.InsertData, delete *storage.DeleteData) error) WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.preSyncTransform = transform
+	}
+}
+
+// FlushVerifyReader reads insert binlogs written by sync task of provided segment and returns the number of rows in them.
+type FlushVerifyReader func(ctx context.Context, segmentID int64, binlogs map[int64]*datapb.FieldBinlog) (int64, error)
+
+// WithPostFlushVerify makes write buffer read back insert binlogs after each sync task completes,
+// comparing their row count with synced rows before the sync is regarded succeeded by write buffer.
+// Read failure or mismatch is recorded as the last sync error of the segment and returned by Close.
+// Only tasks writing storage v1 binlogs are verified.
+func WithPostFlushVerify(reader FlushVerifyReader) WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.postFlushVerifier = reader
+	}
+}
+
+// WithCheckpointComparator makes write buffer select checkpoint with provided comparator,
+// which returns negative value if a is earlier than b, zero if equal and positive otherwise.
+func WithCheckpointComparator(comparator func(a, b *msgpb.MsgPosition) int) WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.checkpointComparator = comparator
+	}
+}
+
+// WithCheckpointFromSyncManagerOnly makes GetCheckpoint ignore positions of buffered data and use the earliest
+// position in sync manager, or the latest consumed position if there is none.
+// It is an escape hatch for channels whose buffer positions are unreliable, buffered data could be skipped
+// by the checkpoint and shall be recovered by other means.
+func WithCheckpointFromSyncManagerOnly() WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.checkpointFromSyncManagerOnly = true
+	}
+}
+
+// WithCheckpointUnblockCallback sets the callback notified when channel checkpoint evaluated by GetCheckpoint advances
+// since flush of the segment holding previous checkpoint completed.
+// The callback is invoked after write buffer lock released, so it could call write buffer methods.
+func WithCheckpointUnblockCallback(callback func(oldCP, newCP *msgpb.MsgPosition)) WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.checkpointUnblockCallback = callback
+	}
+}
+
+// WithNoAutoSegmentCreate makes write buffer reject insert data of unknown segments instead of creating them.
+func WithNoAutoSegmentCreate() WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.noAutoSegmentCreate = true
+	}
+}
+
+// WithCloseParallelism makes Close(true) keep at most n sync tasks of buffered data in flight,
+// so that draining a channel with lots of segments does not flood the storage.
+func WithCloseParallelism(n int) WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.closeParallelism = n
+	}
+}
+
+// WithDropWithoutFlush makes Close(true) discard all buffered data and only drop the channel,
+// which is used to remove channel with corrupted data. Discarded data is lost.
+func WithDropWithoutFlush() WriteBufferOption {
+	return func(opt *writeBufferOption) {
+		opt.dropWithoutFlush = true
+	}
+}
@@ -0,0 +1,58 @@
+package writebuffer
+
+import (
+	"sort"
+	"sync"
+)
+
+// partitionWriteTracker tracks the order in which buffered partitions were last written.
+type partitionWriteTracker struct {
+	mut sync.Mutex
+
+	seq        uint64
+	lastWrites map[int64]uint64 // partitionID => sequence of last write
+}
+
+func newPartitionWriteTracker() *partitionWriteTracker {
+	return &partitionWriteTracker{
+		lastWrites: make(map[int64]uint64),
+	}
+}
+
+// Touch records provided partition is written just now.
+func (t *partitionWriteTracker) Touch(partitionID int64) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	t.seq++
+	t.lastWrites[partitionID] = t.seq
+}
+
+// Retain removes records of partitions not provided, e.g. partitions no longer buffered.
+func (t *partitionWriteTracker) Retain(partitionIDs []int64) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	alive := make(map[int64]uint64, len(partitionIDs))
+	for _, partitionID := range partitionIDs {
+		if seq, ok := t.lastWrites[partitionID]; ok {
+			alive[partitionID] = seq
+		}
+	}
+	t.lastWrites = alive
+}
+
+// LeastRecent returns provided partitions ordered from the least recently written one,
+// partitions never written are regarded least recent.
+func (t *partitionWriteTracker) LeastRecent(partitionIDs []int64) []int64 {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	result := append([]int64(nil), partitionIDs...)
+	sort.Slice(result, func(i, j int) bool {
+		if t.lastWrites[result[i]] != t.lastWrites[result[j]] {
+			return t.lastWrites[result[i]] < t.lastWrites[result[j]]
+		}
+		return result[i] < result[j]
+	})
+	return result
+}
@@ -0,0 +1,52 @@
+package writebuffer
+
+import "sync"
+
+// pendingSyncTracker tracks the number of sync tasks not finished yet for each segment.
+// tasks are finished by sync task callbacks, which run outside write buffer lock.
+type pendingSyncTracker struct {
+	mut sync.Mutex
+
+	pendings map[int64]int // segmentID => unfinished sync task number
+}
+
+func newPendingSyncTracker() *pendingSyncTracker {
+	return &pendingSyncTracker{
+		pendings: make(map[int64]int),
+	}
+}
+
+// Add records a sync task of provided segment is created.
+func (t *pendingSyncTracker) Add(segmentID int64) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	t.pendings[segmentID]++
+}
+
+// Done records a sync task of provided segment finished.
+func (t *pendingSyncTracker) Done(segmentID int64) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	t.pendings[segmentID]--
+	if t.pendings[segmentID] <= 0 {
+		delete(t.pendings, segmentID)
+	}
+}
+
+// Pending returns whether provided segment has any unfinished sync task.
+func (t *pendingSyncTracker) Pending(segmentID int64) bool {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	return t.pendings[segmentID] > 0
+}
+
+// Total returns the number of unfinished sync tasks of all segments.
+func (t *pendingSyncTracker) Total() int {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	var total int
+	for _, n := range t.pendings {
+		total += n
+	}
+	return total
+}
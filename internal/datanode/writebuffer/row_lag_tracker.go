@@ -0,0 +1,60 @@
+package writebuffer
+
+import (
+	"sync"
+)
+
+// rowLagTracker tracks rows buffered but not synced yet for each segment.
+// synced rows are reported by sync task success callback, which runs outside write buffer lock.
+type rowLagTracker struct {
+	mut sync.Mutex
+
+	lags map[int64]int64 // segmentID => buffered rows - synced rows
+}
+
+func newRowLagTracker() *rowLagTracker {
+	return &rowLagTracker{
+		lags: make(map[int64]int64),
+	}
+}
+
+// Buffered records rows buffered into provided segment.
+func (t *rowLagTracker) Buffered(segmentID int64, rows int64) {
+	if rows == 0 {
+		return
+	}
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	t.lags[segmentID] += rows
+}
+
+// Synced records rows of provided segment persisted by sync task.
+func (t *rowLagTracker) Synced(segmentID int64, rows int64) {
+	if rows == 0 {
+		return
+	}
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	t.lags[segmentID] -= rows
+	if t.lags[segmentID] <= 0 {
+		delete(t.lags, segmentID)
+	}
+}
+
+// Remove clears row lag of provided segment.
+func (t *rowLagTracker) Remove(segmentID int64) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	delete(t.lags, segmentID)
+}
+
+// Stats returns a snapshot of row lag for segments with rows not synced.
+func (t *rowLagTracker) Stats() map[int64]int64 {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	result := make(map[int64]int64, len(t.lags))
+	for segmentID, lag := range t.lags {
+		result[segmentID] = lag
+	}
+	return result
+}
@@ -3,6 +3,8 @@ package writebuffer
 import (
 	"math"
 
+	"go.uber.org/atomic"
+
 	"github.com/milvus-io/milvus-proto/go-api/v2/msgpb"
 	"github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
 	"github.com/milvus-io/milvus/internal/storage"
@@ -28,14 +30,55 @@ func newSegmentBuffer(segmentID int64, collSchema *schemapb.CollectionSchema) (*
 	}, nil
 }
 
+// setLimitScale makes insert & delta buffer scale their limits with provided multiplier.
+func (buf *segmentBuffer) setLimitScale(scale *atomic.Float64) {
+	buf.insertBuffer.limitScale = scale
+	buf.deltaBuffer.limitScale = scale
+}
+
 func (buf *segmentBuffer) IsFull() bool {
 	return buf.insertBuffer.IsFull() || buf.deltaBuffer.IsFull()
 }
 
+// IsEmpty returns whether neither insert nor delta data is buffered.
+func (buf *segmentBuffer) IsEmpty() bool {
+	return buf.insertBuffer.IsEmpty() && buf.deltaBuffer.IsEmpty()
+}
+
+// release releases resources held by buffer, e.g. arrow record builder and spilled delete index files.
+// Yielded data and record stay valid after release.
+func (buf *segmentBuffer) release() {
+	buf.insertBuffer.releaseRecord()
+	buf.deltaBuffer.releaseIndex()
+}
+
+// MemorySize returns buffered size of insert & delta data.
+func (buf *segmentBuffer) MemorySize() int64 {
+	return buf.insertBuffer.size + buf.deltaBuffer.size
+}
+
 func (buf *segmentBuffer) Yield() (insert *storage.InsertData, delete *storage.DeleteData) {
 	return buf.insertBuffer.Yield(), buf.deltaBuffer.Yield()
 }
 
+// yieldInsert returns buffered insert data and resets the insert buffer,
+// the delta buffer is left untouched.
+func (buf *segmentBuffer) yieldInsert() (*storage.InsertData, error) {
+	insertBuffer, err := NewInsertBuffer(buf.insertBuffer.collSchema)
+	if err != nil {
+		return nil, err
+	}
+	insertBuffer.pkExtractor = buf.insertBuffer.pkExtractor
+	insertBuffer.limitScale = buf.insertBuffer.limitScale
+	if buf.insertBuffer.recordBuilder != nil {
+		insertBuffer.enableRecord(buf.insertBuffer.recordBuilder.Schema())
+	}
+	insert := buf.insertBuffer.Yield()
+	buf.insertBuffer.releaseRecord()
+	buf.insertBuffer = insertBuffer
+	return insert, nil
+}
+
 func (buf *segmentBuffer) MinTimestamp() typeutil.Timestamp {
 	insertTs := buf.insertBuffer.MinTimestamp()
 	deltaTs := buf.deltaBuffer.MinTimestamp()
@@ -71,6 +114,16 @@ type TimeRange struct {
 	timestampMax typeutil.Timestamp
 }
 
+// Min returns the min timestamp of the range.
+func (tr *TimeRange) Min() typeutil.Timestamp {
+	return tr.timestampMin
+}
+
+// Max returns the max timestamp of the range.
+func (tr *TimeRange) Max() typeutil.Timestamp {
+	return tr.timestampMax
+}
+
 func (tr *TimeRange) Merge(other *TimeRange) {
 	if other.timestampMin < tr.timestampMin {
 		tr.timestampMin = other.timestampMin
@@ -0,0 +1,31 @@
+package writebuffer
+
+import (
+	"sync"
+)
+
+// segmentLockStripes is the number of locks segment ids are striped into.
+const segmentLockStripes = 64
+
+// segmentLocks is a striped lock map guarding contents of segment buffers,
+// segments sharing the same stripe are serialized while others proceed in parallel.
+type segmentLocks struct {
+	stripes [segmentLockStripes]sync.Mutex
+}
+
+func (l *segmentLocks) stripe(segmentID int64) *sync.Mutex {
+	idx := segmentID % segmentLockStripes
+	if idx < 0 {
+		idx = -idx
+	}
+	return &l.stripes[idx]
+}
+
+// Lock locks the stripe of provided segment, at most one stripe shall be held at a time.
+func (l *segmentLocks) Lock(segmentID int64) {
+	l.stripe(segmentID).Lock()
+}
+
+func (l *segmentLocks) Unlock(segmentID int64) {
+	l.stripe(segmentID).Unlock()
+}
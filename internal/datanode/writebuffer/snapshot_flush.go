@@ -0,0 +1,238 @@
+package writebuffer
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cockroachdb/errors"
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/commonpb"
+	"github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
+	"github.com/milvus-io/milvus/internal/storage"
+	"github.com/milvus-io/milvus/pkg/common"
+	"github.com/milvus-io/milvus/pkg/log"
+	"github.com/milvus-io/milvus/pkg/util/conc"
+	"github.com/milvus-io/milvus/pkg/util/merr"
+	"github.com/milvus-io/milvus/pkg/util/typeutil"
+)
+
+// SnapshotFlush syncs all buffered data with timestamp not after ts and waits for the sync tasks,
+// returns the ids of synced segments.
+// Buffers crossing ts are split, data after ts stays buffered so that no segment partially crosses the barrier.
+func (wb *writeBufferBase) SnapshotFlush(ctx context.Context, ts typeutil.Timestamp) ([]int64, error) {
+	futures, err := wb.snapshotSync(ctx, ts)
+	if err != nil {
+		return nil, err
+	}
+
+	segmentIDs := make([]int64, 0, len(futures))
+	var errs []error
+	for segmentID, f := range futures {
+		segmentIDs = append(segmentIDs, segmentID)
+		// sync task error is returned as future value
+		taskErr, err := f.Await()
+		if err == nil {
+			err = taskErr
+		}
+		if err != nil {
+			log.Ctx(ctx).Warn("failed to sync snapshot of segment", zap.String("channel", wb.channelName), zap.Int64("segmentID", segmentID), zap.Error(err))
+			errs = append(errs, errors.Wrapf(err, "failed to sync snapshot of segment %d", segmentID))
+		}
+	}
+	sort.Slice(segmentIDs, func(i, j int) bool { return segmentIDs[i] < segmentIDs[j] })
+
+	return segmentIDs, merr.Combine(errs...)
+}
+
+// snapshotSync submits sync tasks of buffered data not after ts under write lock,
+// so that no data is buffered into selected segments while splitting.
+// Flushing segments are synced whole, since data left in their buffers could never be synced after flush.
+func (wb *writeBufferBase) snapshotSync(ctx context.Context, ts typeutil.Timestamp) (map[int64]*conc.Future[error], error) {
+	wb.mut.Lock()
+	defer wb.mut.Unlock()
+
+	if wb.closed {
+		return nil, ErrBufferClosed
+	}
+
+	// all data before barrier shall be synced, delta data could not be kept in buffer
+	decoupleDeltaFlush := wb.decoupleDeltaFlush
+	wb.decoupleDeltaFlush = false
+	defer func() { wb.decoupleDeltaFlush = decoupleDeltaFlush }()
+
+	var segmentIDs []int64
+	// original & remaining buffers of split segments
+	origins := make(map[int64]*segmentBuffer)
+	remains := make(map[int64]*segmentBuffer)
+	for _, segmentID := range wb.sortedSegmentIDs() {
+		buffer := wb.buffers[segmentID]
+		timeRange := buffer.GetTimeRange()
+		if timeRange.timestampMin > ts {
+			continue
+		}
+		segmentIDs = append(segmentIDs, segmentID)
+
+		segment, ok := wb.metaCache.GetSegmentByID(segmentID)
+		if timeRange.timestampMax <= ts || (ok && segment.State() == commonpb.SegmentState_Flushing) {
+			continue
+		}
+		before, after, err := wb.splitBuffer(buffer, ts)
+		if err != nil {
+			wb.restoreSplitBuffers(origins, remains)
+			return nil, err
+		}
+		wb.buffers[segmentID] = before
+		origins[segmentID] = buffer
+		remains[segmentID] = after
+	}
+
+	futures := wb.syncSegments(ctx, segmentIDs)
+	var errs []error
+	for _, segmentID := range segmentIDs {
+		if _, ok := futures[segmentID]; ok {
+			if remain, ok := remains[segmentID]; ok {
+				// data before barrier is yielded, data after it stays buffered
+				wb.buffers[segmentID] = remain
+				origins[segmentID].release()
+				delete(origins, segmentID)
+				delete(remains, segmentID)
+			}
+			continue
+		}
+		if _, ok := wb.metaCache.GetSegmentByID(segmentID); !ok {
+			// buffer of segment not in meta could never be synced
+			continue
+		}
+		// data before barrier stays buffered, snapshot could not be taken
+		errs = append(errs, errors.Wrapf(ErrSnapshotIncomplete, "segment %d not synced", segmentID))
+	}
+	// split data not yielded is restored into the whole buffer
+	wb.restoreSplitBuffers(origins, remains)
+	if len(errs) > 0 {
+		return nil, merr.Combine(errs...)
+	}
+	return futures, nil
+}
+
+// restoreSplitBuffers puts original buffers of split segments back and releases split buffers.
+func (wb *writeBufferBase) restoreSplitBuffers(origins, remains map[int64]*segmentBuffer) {
+	for segmentID, origin := range origins {
+		if split, ok := wb.buffers[segmentID]; ok && split != origin {
+			split.release()
+		}
+		remains[segmentID].release()
+		wb.buffers[segmentID] = origin
+	}
+}
+
+// splitBuffer splits buffered data into two segment buffers by timestamp,
+// the former one holds data not after ts and the latter one holds the rest.
+// Both of them inherit buffer positions so that checkpoint never passes the split data.
+func (wb *writeBufferBase) splitBuffer(buffer *segmentBuffer, ts typeutil.Timestamp) (*segmentBuffer, *segmentBuffer, error) {
+	before, err := newSegmentBuffer(buffer.segmentID, wb.collSchema)
+	if err != nil {
+		return nil, nil, err
+	}
+	after, err := newSegmentBuffer(buffer.segmentID, wb.collSchema)
+	if err != nil {
+		before.release()
+		return nil, nil, err
+	}
+	before.insertBuffer.pkExtractor = buffer.insertBuffer.pkExtractor
+	after.insertBuffer.pkExtractor = buffer.insertBuffer.pkExtractor
+	before.setLimitScale(buffer.insertBuffer.limitScale)
+	after.setLimitScale(buffer.insertBuffer.limitScale)
+	if buffer.insertBuffer.recordBuilder != nil {
+		before.insertBuffer.enableRecord(buffer.insertBuffer.recordBuilder.Schema())
+		after.insertBuffer.enableRecord(buffer.insertBuffer.recordBuilder.Schema())
+	}
+	if index := buffer.deltaBuffer.index; index != nil {
+		before.deltaBuffer.index = newDeleteIndex(index.dir, index.maxEntries)
+		after.deltaBuffer.index = newDeleteIndex(index.dir, index.maxEntries)
+	}
+
+	if err := wb.fillSplitBuffers(buffer, before, after, ts); err != nil {
+		before.release()
+		after.release()
+		return nil, nil, err
+	}
+	return before, after, nil
+}
+
+// fillSplitBuffers buffers data of buffer not after ts into before and the rest into after.
+func (wb *writeBufferBase) fillSplitBuffers(buffer, before, after *segmentBuffer, ts typeutil.Timestamp) error {
+	if !buffer.insertBuffer.IsEmpty() {
+		beforeData, afterData, err := splitInsertData(wb.collSchema, buffer.insertBuffer.buffer, ts)
+		if err != nil {
+			return err
+		}
+		startPos, endPos := buffer.insertBuffer.startPos, buffer.insertBuffer.endPos
+		if beforeData.GetRowNum() > 0 {
+			if _, err := before.insertBuffer.bufferInsertData(beforeData, startPos, endPos); err != nil {
+				return err
+			}
+		}
+		if afterData.GetRowNum() > 0 {
+			if _, err := after.insertBuffer.bufferInsertData(afterData, startPos, endPos); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !buffer.deltaBuffer.IsEmpty() {
+		beforeDelta, afterDelta := splitDeleteData(buffer.deltaBuffer.buffer, ts)
+		startPos, endPos := buffer.deltaBuffer.startPos, buffer.deltaBuffer.endPos
+		if beforeDelta.RowCount > 0 {
+			before.deltaBuffer.Buffer(beforeDelta.Pks, beforeDelta.Tss, startPos, endPos)
+		}
+		if afterDelta.RowCount > 0 {
+			after.deltaBuffer.Buffer(afterDelta.Pks, afterDelta.Tss, startPos, endPos)
+		}
+	}
+
+	return nil
+}
+
+func splitInsertData(collSchema *schemapb.CollectionSchema, data *storage.InsertData, ts typeutil.Timestamp) (*storage.InsertData, *storage.InsertData, error) {
+	before, err := storage.NewInsertData(collSchema)
+	if err != nil {
+		return nil, nil, err
+	}
+	after, err := storage.NewInsertData(collSchema)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tsData, ok := data.Data[common.TimeStampField].(*storage.Int64FieldData)
+	if !ok {
+		return nil, nil, merr.WrapErrServiceInternal("timestamp field not found in insert data")
+	}
+	for i, rowTs := range tsData.Data {
+		row := make(map[storage.FieldID]interface{}, len(data.Data))
+		for fieldID, fieldData := range data.Data {
+			row[fieldID] = fieldData.GetRow(i)
+		}
+		target := before
+		if typeutil.Timestamp(rowTs) > ts {
+			target = after
+		}
+		if err := target.Append(row); err != nil {
+			return nil, nil, err
+		}
+	}
+	return before, after, nil
+}
+
+func splitDeleteData(data *storage.DeleteData, ts typeutil.Timestamp) (*storage.DeleteData, *storage.DeleteData) {
+	before := storage.NewDeleteData(nil, nil)
+	after := storage.NewDeleteData(nil, nil)
+	for i, pk := range data.Pks {
+		if data.Tss[i] > ts {
+			after.Append(pk, data.Tss[i])
+		} else {
+			before.Append(pk, data.Tss[i])
+		}
+	}
+	return before, after
+}
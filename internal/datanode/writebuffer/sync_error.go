@@ -0,0 +1,38 @@
+package writebuffer
+
+import "sync"
+
+// syncErrorTracker keeps the last sync failure of each segment until its next successful sync.
+// failures are recorded by sync task callbacks, which run outside write buffer lock.
+type syncErrorTracker struct {
+	mut sync.Mutex
+
+	errs map[int64]error // segmentID => last sync error
+}
+
+func newSyncErrorTracker() *syncErrorTracker {
+	return &syncErrorTracker{
+		errs: make(map[int64]error),
+	}
+}
+
+// Failed records the sync failure of provided segment.
+func (t *syncErrorTracker) Failed(segmentID int64, err error) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	t.errs[segmentID] = err
+}
+
+// Succeeded clears the sync failure of provided segment.
+func (t *syncErrorTracker) Succeeded(segmentID int64) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	delete(t.errs, segmentID)
+}
+
+// Get returns the last sync failure of provided segment, nil if there is none.
+func (t *syncErrorTracker) Get(segmentID int64) error {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	return t.errs[segmentID]
+}
@@ -72,7 +72,22 @@ func GetFlushingSegmentsPolicy(meta metacache.MetaCache) SyncPolicy {
 	}, "segment flushing")
 }
 
+// GetSealedSegmentsPolicy selects buffered segments sealed explicitly.
+func GetSealedSegmentsPolicy(sealed *typeutil.ConcurrentSet[int64]) SyncPolicy {
+	return wrapSelectSegmentFuncPolicy(func(buffers []*segmentBuffer, _ typeutil.Timestamp) []int64 {
+		return lo.FilterMap(buffers, func(buf *segmentBuffer, _ int) (int64, bool) {
+			return buf.segmentID, sealed.Contain(buf.segmentID)
+		})
+	}, "segment sealed")
+}
+
 func GetFlushTsPolicy(flushTimestamp *atomic.Uint64, meta metacache.MetaCache) SyncPolicy {
+	return GetFlushTsPolicyWithInclusive(flushTimestamp, meta, false)
+}
+
+// GetFlushTsPolicyWithInclusive returns the flush ts policy, buffers starting exactly at flush ts are selected
+// only if inclusive is true.
+func GetFlushTsPolicyWithInclusive(flushTimestamp *atomic.Uint64, meta metacache.MetaCache, inclusive bool) SyncPolicy {
 	return wrapSelectSegmentFuncPolicy(func(buffers []*segmentBuffer, ts typeutil.Timestamp) []int64 {
 		flushTs := flushTimestamp.Load()
 		if flushTs != nonFlushTS && ts >= flushTs {
@@ -84,7 +99,8 @@ func GetFlushTsPolicy(flushTimestamp *atomic.Uint64, meta metacache.MetaCache) S
 				}
 				inRange := seg.State() == commonpb.SegmentState_Flushed ||
 					seg.Level() == datapb.SegmentLevel_L0
-				return buf.segmentID, inRange && buf.MinTimestamp() < flushTs
+				beforeFlushTs := buf.MinTimestamp() < flushTs || (inclusive && buf.MinTimestamp() == flushTs)
+				return buf.segmentID, inRange && beforeFlushTs
 			})
 			// set segment flushing
 			meta.UpdateSegments(metacache.UpdateState(commonpb.SegmentState_Flushing),
@@ -96,3 +112,32 @@ func GetFlushTsPolicy(flushTimestamp *atomic.Uint64, meta metacache.MetaCache) S
 		return nil
 	}, "flush ts")
 }
+
+// GetMaxBufferedPartitionsPolicy selects all segments of the least recently written partitions
+// once the number of buffered partitions exceeds maxPartitions.
+func GetMaxBufferedPartitionsPolicy(meta metacache.MetaCache, tracker *partitionWriteTracker, maxPartitions int) SyncPolicy {
+	return wrapSelectSegmentFuncPolicy(func(buffers []*segmentBuffer, _ typeutil.Timestamp) []int64 {
+		partitionSegments := groupByPartition(meta, buffers)
+		partitionIDs := lo.Keys(partitionSegments)
+		// partitions no longer buffered are forgotten
+		tracker.Retain(partitionIDs)
+		if len(partitionSegments) <= maxPartitions {
+			return nil
+		}
+		evicted := tracker.LeastRecent(partitionIDs)[:len(partitionSegments)-maxPartitions]
+		return lo.FlatMap(evicted, func(partitionID int64, _ int) []int64 { return partitionSegments[partitionID] })
+	}, "buffered partitions exceed limit")
+}
+
+// groupByPartition groups buffered segments by partition, segments not in meta are ignored.
+func groupByPartition(meta metacache.MetaCache, buffers []*segmentBuffer) map[int64][]int64 {
+	result := make(map[int64][]int64)
+	for _, buf := range buffers {
+		segment, ok := meta.GetSegmentByID(buf.segmentID)
+		if !ok {
+			continue
+		}
+		result[segment.PartitionID()] = append(result[segment.PartitionID()], buf.segmentID)
+	}
+	return result
+}
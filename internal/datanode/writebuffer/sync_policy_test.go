@@ -6,14 +6,17 @@ import (
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"go.uber.org/atomic"
 
 	"github.com/milvus-io/milvus-proto/go-api/v2/commonpb"
 	"github.com/milvus-io/milvus-proto/go-api/v2/msgpb"
 	"github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
 	"github.com/milvus-io/milvus/internal/datanode/metacache"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
 	"github.com/milvus-io/milvus/pkg/common"
 	"github.com/milvus-io/milvus/pkg/util/paramtable"
 	"github.com/milvus-io/milvus/pkg/util/tsoutil"
+	"github.com/milvus-io/milvus/pkg/util/typeutil"
 )
 
 type SyncPolicySuite struct {
@@ -86,6 +89,42 @@ func (s *SyncPolicySuite) TestCompactedSegmentsPolicy() {
 	s.ElementsMatch(ids, result)
 }
 
+func (s *SyncPolicySuite) TestFlushTsPolicyInclusive() {
+	var flushTs typeutil.Timestamp = 1000
+	meta := metacache.NewMockMetaCache(s.T())
+	meta.EXPECT().GetSegmentByID(int64(100)).Return(
+		metacache.NewSegmentInfo(&datapb.SegmentInfo{ID: 100, State: commonpb.SegmentState_Flushed}, metacache.NewBloomFilterSet()), true)
+	meta.EXPECT().UpdateSegments(mock.Anything, mock.Anything, mock.Anything).Return()
+
+	buffer, err := newSegmentBuffer(100, s.collSchema)
+	s.Require().NoError(err)
+	// buffered data exactly at flush ts
+	buffer.insertBuffer.UpdateStatistics(1, 64, TimeRange{timestampMin: flushTs, timestampMax: flushTs},
+		&msgpb.MsgPosition{Timestamp: flushTs}, &msgpb.MsgPosition{Timestamp: flushTs})
+
+	exclusive := GetFlushTsPolicy(atomic.NewUint64(flushTs), meta)
+	s.Empty(exclusive.SelectSegments([]*segmentBuffer{buffer}, flushTs+1))
+
+	inclusive := GetFlushTsPolicyWithInclusive(atomic.NewUint64(flushTs), meta, true)
+	s.ElementsMatch([]int64{100}, inclusive.SelectSegments([]*segmentBuffer{buffer}, flushTs+1))
+}
+
+func (s *SyncPolicySuite) TestPartitionWriteTracker() {
+	tracker := newPartitionWriteTracker()
+	tracker.Touch(1)
+	tracker.Touch(2)
+	tracker.Touch(1)
+	s.Equal([]int64{3, 2, 1}, tracker.LeastRecent([]int64{1, 2, 3}))
+
+	// query keeps records of partitions not provided
+	s.Equal([]int64{2}, tracker.LeastRecent([]int64{2}))
+	s.Equal([]int64{2, 1}, tracker.LeastRecent([]int64{1, 2}))
+
+	// forgotten partition is regarded never written
+	tracker.Retain([]int64{2})
+	s.Equal([]int64{1, 2}, tracker.LeastRecent([]int64{1, 2}))
+}
+
 func TestSyncPolicy(t *testing.T) {
 	suite.Run(t, new(SyncPolicySuite))
 }
@@ -0,0 +1,60 @@
+package writebuffer
+
+import (
+	"sync"
+
+	"github.com/samber/lo"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/msgpb"
+)
+
+// syncPositionTracker tracks start positions of sync tasks not finished yet for each segment.
+// tasks succeed in sync task callbacks, which run outside write buffer lock.
+type syncPositionTracker struct {
+	mut sync.Mutex
+
+	positions map[int64][]*msgpb.MsgPosition // segmentID => start positions of unfinished sync tasks
+}
+
+func newSyncPositionTracker() *syncPositionTracker {
+	return &syncPositionTracker{
+		positions: make(map[int64][]*msgpb.MsgPosition),
+	}
+}
+
+// Add records a sync task starting at provided position is created.
+func (t *syncPositionTracker) Add(segmentID int64, position *msgpb.MsgPosition) {
+	if position == nil {
+		return
+	}
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	t.positions[segmentID] = append(t.positions[segmentID], position)
+}
+
+// Done records the sync task starting at provided position succeeded.
+func (t *syncPositionTracker) Done(segmentID int64, position *msgpb.MsgPosition) {
+	if position == nil {
+		return
+	}
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	positions := t.positions[segmentID]
+	if idx := lo.IndexOf(positions, position); idx >= 0 {
+		positions = append(positions[:idx], positions[idx+1:]...)
+	}
+	if len(positions) == 0 {
+		delete(t.positions, segmentID)
+		return
+	}
+	t.positions[segmentID] = positions
+}
+
+// Earliest returns the earliest start position of unfinished sync tasks for each segment.
+func (t *syncPositionTracker) Earliest() map[int64]*msgpb.MsgPosition {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	return lo.MapValues(t.positions, func(positions []*msgpb.MsgPosition, _ int64) *msgpb.MsgPosition {
+		return lo.MinBy(positions, func(a, b *msgpb.MsgPosition) bool { return a.GetTimestamp() < b.GetTimestamp() })
+	})
+}
@@ -0,0 +1,212 @@
+package testutil
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/msgpb"
+	"github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
+	"github.com/milvus-io/milvus/internal/datanode/writebuffer"
+	"github.com/milvus-io/milvus/internal/storage"
+	"github.com/milvus-io/milvus/pkg/mq/msgstream"
+	"github.com/milvus-io/milvus/pkg/util/typeutil"
+)
+
+var _ writebuffer.WriteBuffer = (*noopWriteBuffer)(nil)
+
+// noopWriteBuffer is the write buffer accepting all data without buffering it, which never syncs.
+type noopWriteBuffer struct {
+	channelName string
+	flushTs     *atomic.Uint64
+	sizeCh      chan int64
+}
+
+// NewNoopWriteBuffer returns a write buffer stub for unit tests of components depending on write buffer.
+// It accepts all buffering operations, never syncs and returns a fixed checkpoint of provided channel.
+func NewNoopWriteBuffer(channel string) writebuffer.WriteBuffer {
+	return &noopWriteBuffer{
+		channelName: channel,
+		flushTs:     atomic.NewUint64(0),
+		sizeCh:      make(chan int64),
+	}
+}
+
+func (wb *noopWriteBuffer) HasSegment(segmentID int64) bool {
+	return false
+}
+
+func (wb *noopWriteBuffer) SegmentPartition(segmentID int64) (int64, bool) {
+	return 0, false
+}
+
+func (wb *noopWriteBuffer) BufferedPartitionCount() int {
+	return 0
+}
+
+func (wb *noopWriteBuffer) BufferData(insertMsgs []*msgstream.InsertMsg, deleteMsgs []*msgstream.DeleteMsg, startPos, endPos *msgpb.MsgPosition) error {
+	return nil
+}
+
+func (wb *noopWriteBuffer) BufferDataGrouped(insertBySegment map[int64][]*msgstream.InsertMsg, deleteBySegment map[int64][]*msgstream.DeleteMsg, startPos, endPos *msgpb.MsgPosition) error {
+	return nil
+}
+
+func (wb *noopWriteBuffer) BufferDataSeq(insertMsgs []*msgstream.InsertMsg, deleteMsgs []*msgstream.DeleteMsg, startPos, endPos *msgpb.MsgPosition, seq uint64) error {
+	return nil
+}
+
+func (wb *noopWriteBuffer) BufferRows(segmentID int64, fill func(builder writebuffer.InsertBuilder) error, startPos, endPos *msgpb.MsgPosition) error {
+	return nil
+}
+
+func (wb *noopWriteBuffer) SetFlushTimestamp(flushTs uint64) {
+	wb.flushTs.Store(flushTs)
+}
+
+func (wb *noopWriteBuffer) GetFlushTimestamp() uint64 {
+	return wb.flushTs.Load()
+}
+
+func (wb *noopWriteBuffer) ClearFlushTimestamp() {
+	wb.flushTs.Store(0)
+}
+
+func (wb *noopWriteBuffer) UpdateSchema(schema *schemapb.CollectionSchema) error {
+	return nil
+}
+
+func (wb *noopWriteBuffer) SealSegment(segmentID int64) error {
+	return nil
+}
+
+func (wb *noopWriteBuffer) SetThresholdMultiplier(m float64) {}
+
+func (wb *noopWriteBuffer) ResetThresholds() {}
+
+func (wb *noopWriteBuffer) FlushSegments(ctx context.Context, segmentIDs []int64) error {
+	return nil
+}
+
+func (wb *noopWriteBuffer) FlushSegmentsStrict(ctx context.Context, segmentIDs []int64) error {
+	return nil
+}
+
+func (wb *noopWriteBuffer) DrainSegment(ctx context.Context, segmentID int64) error {
+	return nil
+}
+
+func (wb *noopWriteBuffer) FlushSegmentsToPrefix(ctx context.Context, segmentIDs []int64, prefix string) error {
+	return nil
+}
+
+func (wb *noopWriteBuffer) SnapshotFlush(ctx context.Context, ts uint64) ([]int64, error) {
+	return nil, nil
+}
+
+// GetCheckpoint returns the zero position of channel, as nothing is ever buffered.
+func (wb *noopWriteBuffer) GetCheckpoint() *msgpb.MsgPosition {
+	return &msgpb.MsgPosition{ChannelName: wb.channelName}
+}
+
+func (wb *noopWriteBuffer) SyncManagerEarliestPosition() (int64, *msgpb.MsgPosition) {
+	return 0, nil
+}
+
+func (wb *noopWriteBuffer) SyncBacklog() []writebuffer.SyncBacklogEntry {
+	return nil
+}
+
+func (wb *noopWriteBuffer) LastCheckpointAdvanceCause() (int64, string) {
+	return 0, ""
+}
+
+func (wb *noopWriteBuffer) TotalFlushedRows() int64 {
+	return 0
+}
+
+func (wb *noopWriteBuffer) OldestUnsyncedAge(now uint64) time.Duration {
+	return 0
+}
+
+func (wb *noopWriteBuffer) RowLagStats() map[int64]int64 {
+	return map[int64]int64{}
+}
+
+func (wb *noopWriteBuffer) FieldMemorySize(segmentID int64) map[int64]int64 {
+	return map[int64]int64{}
+}
+
+func (wb *noopWriteBuffer) EstimateFlushSize(segmentID int64) int64 {
+	return 0
+}
+
+func (wb *noopWriteBuffer) InsertDeleteRatio(segmentID int64) float64 {
+	return 0
+}
+
+func (wb *noopWriteBuffer) LookupDelete(segmentID int64, pk storage.PrimaryKey) (typeutil.Timestamp, bool, error) {
+	return 0, false, nil
+}
+
+func (wb *noopWriteBuffer) DeleteTimeRange(segmentID int64) (*writebuffer.TimeRange, bool) {
+	return nil, false
+}
+
+func (wb *noopWriteBuffer) TopSegmentsByMemory(n int) []writebuffer.SegmentBufferStats {
+	return nil
+}
+
+func (wb *noopWriteBuffer) EmptySegmentIDs() []int64 {
+	return nil
+}
+
+func (wb *noopWriteBuffer) BufferStats() writebuffer.ChannelBufferStats {
+	return writebuffer.ChannelBufferStats{}
+}
+
+func (wb *noopWriteBuffer) MemorySplit() (int64, int64) {
+	return 0, 0
+}
+
+// WatchBufferSize returns the channel which never receives, since buffer size never changes.
+func (wb *noopWriteBuffer) WatchBufferSize() <-chan int64 {
+	return wb.sizeCh
+}
+
+func (wb *noopWriteBuffer) WarmupBloomFilters(ctx context.Context) error {
+	return nil
+}
+
+func (wb *noopWriteBuffer) LastSyncError(segmentID int64) error {
+	return nil
+}
+
+func (wb *noopWriteBuffer) DroppedSyncCount() int64 {
+	return 0
+}
+
+func (wb *noopWriteBuffer) DeleteAppliesTo(segmentID int64, pk storage.PrimaryKey) bool {
+	return false
+}
+
+func (wb *noopWriteBuffer) BFFalsePositiveDeletes() int64 {
+	return 0
+}
+
+func (wb *noopWriteBuffer) ExportCheckpoint() ([]byte, error) {
+	return nil, nil
+}
+
+func (wb *noopWriteBuffer) ImportCheckpoint(data []byte) error {
+	return nil
+}
+
+func (wb *noopWriteBuffer) Reconcile() []int64 {
+	return nil
+}
+
+func (wb *noopWriteBuffer) Close(drop bool) error {
+	return nil
+}
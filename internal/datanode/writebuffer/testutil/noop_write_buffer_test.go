@@ -0,0 +1,123 @@
+package testutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/msgpb"
+	"github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
+	"github.com/milvus-io/milvus/internal/datanode/writebuffer"
+	"github.com/milvus-io/milvus/internal/storage"
+	"github.com/milvus-io/milvus/pkg/mq/msgstream"
+)
+
+type NoopWriteBufferSuite struct {
+	suite.Suite
+	channelName string
+	wb          writebuffer.WriteBuffer
+}
+
+func (s *NoopWriteBufferSuite) SetupTest() {
+	s.channelName = "by-dev-rootcoord-dml_0v0"
+	s.wb = NewNoopWriteBuffer(s.channelName)
+}
+
+func (s *NoopWriteBufferSuite) TestBuffering() {
+	startPos, endPos := &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200}
+	insertMsgs := []*msgstream.InsertMsg{{}}
+	deleteMsgs := []*msgstream.DeleteMsg{{}}
+
+	s.NoError(s.wb.BufferData(insertMsgs, deleteMsgs, startPos, endPos))
+	s.NoError(s.wb.BufferDataGrouped(map[int64][]*msgstream.InsertMsg{1001: insertMsgs}, map[int64][]*msgstream.DeleteMsg{1001: deleteMsgs}, startPos, endPos))
+	s.NoError(s.wb.BufferDataSeq(insertMsgs, deleteMsgs, startPos, endPos, 1))
+	s.NoError(s.wb.BufferRows(1001, func(builder writebuffer.InsertBuilder) error { return nil }, startPos, endPos))
+	s.NoError(s.wb.UpdateSchema(&schemapb.CollectionSchema{}))
+	s.NoError(s.wb.SealSegment(1001))
+	s.NoError(s.wb.BufferData(insertMsgs, deleteMsgs, startPos, endPos))
+
+	s.False(s.wb.HasSegment(1001))
+	_, ok := s.wb.SegmentPartition(1001)
+	s.False(ok)
+	s.Zero(s.wb.BufferedPartitionCount())
+	s.Zero(s.wb.EstimateFlushSize(1001))
+	s.Zero(s.wb.InsertDeleteRatio(1001))
+	s.Empty(s.wb.FieldMemorySize(1001))
+	_, found, err := s.wb.LookupDelete(1001, storage.NewInt64PrimaryKey(1))
+	s.NoError(err)
+	s.False(found)
+	_, ok = s.wb.DeleteTimeRange(1001)
+	s.False(ok)
+	s.Empty(s.wb.TopSegmentsByMemory(10))
+	s.Empty(s.wb.EmptySegmentIDs())
+	s.Empty(s.wb.RowLagStats())
+	s.Equal(writebuffer.ChannelBufferStats{}, s.wb.BufferStats())
+	insertBytes, deltaBytes := s.wb.MemorySplit()
+	s.Zero(insertBytes)
+	s.Zero(deltaBytes)
+	s.Zero(s.wb.OldestUnsyncedAge(300))
+}
+
+func (s *NoopWriteBufferSuite) TestSync() {
+	ctx := context.Background()
+	s.NoError(s.wb.FlushSegments(ctx, []int64{1001}))
+	s.NoError(s.wb.FlushSegmentsStrict(ctx, []int64{1001}))
+	s.NoError(s.wb.FlushSegmentsToPrefix(ctx, []int64{1001}, "prefix"))
+	s.NoError(s.wb.DrainSegment(ctx, 1001))
+	flushed, err := s.wb.SnapshotFlush(ctx, 100)
+	s.NoError(err)
+	s.Empty(flushed)
+	s.NoError(s.wb.WarmupBloomFilters(ctx))
+
+	s.Zero(s.wb.TotalFlushedRows())
+	s.Zero(s.wb.DroppedSyncCount())
+	s.NoError(s.wb.LastSyncError(1001))
+	s.Empty(s.wb.SyncBacklog())
+	s.Empty(s.wb.Reconcile())
+	segmentID, pos := s.wb.SyncManagerEarliestPosition()
+	s.Zero(segmentID)
+	s.Nil(pos)
+	segmentID, reason := s.wb.LastCheckpointAdvanceCause()
+	s.Zero(segmentID)
+	s.Empty(reason)
+
+	select {
+	case <-s.wb.WatchBufferSize():
+		s.Fail("noop write buffer shall never notify buffer size")
+	default:
+	}
+}
+
+func (s *NoopWriteBufferSuite) TestFlushTimestamp() {
+	s.Zero(s.wb.GetFlushTimestamp())
+	s.wb.SetFlushTimestamp(100)
+	s.EqualValues(100, s.wb.GetFlushTimestamp())
+	s.wb.ClearFlushTimestamp()
+	s.Zero(s.wb.GetFlushTimestamp())
+
+	s.wb.SetThresholdMultiplier(2)
+	s.wb.ResetThresholds()
+}
+
+func (s *NoopWriteBufferSuite) TestCheckpoint() {
+	cp := s.wb.GetCheckpoint()
+	s.Equal(s.channelName, cp.GetChannelName())
+	s.Zero(cp.GetTimestamp())
+
+	s.NoError(s.wb.BufferData(nil, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200}))
+	s.Equal(cp, s.wb.GetCheckpoint())
+
+	data, err := s.wb.ExportCheckpoint()
+	s.NoError(err)
+	s.NoError(s.wb.ImportCheckpoint(data))
+}
+
+func (s *NoopWriteBufferSuite) TestClose() {
+	s.NoError(s.wb.Close(false))
+	s.NoError(s.wb.Close(true))
+}
+
+func TestNoopWriteBuffer(t *testing.T) {
+	suite.Run(t, new(NoopWriteBufferSuite))
+}
@@ -0,0 +1,50 @@
+package writebuffer
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/milvus-io/milvus/pkg/mq/msgstream"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+	"github.com/milvus-io/milvus/pkg/util/typeutil"
+)
+
+// noopSpan is returned when write buffer tracing is disabled.
+var noopSpan = trace.SpanFromContext(context.Background())
+
+// startSpan starts a span carrying channel & collection attributes if write buffer tracing is enabled.
+func (wb *writeBufferBase) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if !paramtable.Get().DataNodeCfg.WriteBufferTracingEnable.GetAsBool() {
+		return ctx, noopSpan
+	}
+
+	attrs = append(attrs,
+		attribute.String("channel", wb.channelName),
+		attribute.Int64("collectionID", wb.collectionID),
+	)
+	return otel.Tracer(typeutil.DataNodeRole).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// msgsTraceCtx returns the trace context of any insert msg carrying one.
+func msgsTraceCtx(insertBySegment map[int64][]*msgstream.InsertMsg) context.Context {
+	for _, msgs := range insertBySegment {
+		for _, msg := range msgs {
+			if ctx := msg.TraceCtx(); ctx != nil {
+				return ctx
+			}
+		}
+	}
+	return context.Background()
+}
+
+// traceIDFromContext returns the trace id carried by ctx, empty if there is none.
+func traceIDFromContext(ctx context.Context) string {
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if !spanCtx.HasTraceID() {
+		return ""
+	}
+	return spanCtx.TraceID().String()
+}
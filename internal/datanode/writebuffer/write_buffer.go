@@ -3,10 +3,15 @@ package writebuffer
 import (
 	"context"
 	"fmt"
+	"math"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/apache/arrow/go/v12/arrow"
+	"github.com/cockroachdb/errors"
 	"github.com/samber/lo"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
 
@@ -24,9 +29,13 @@ import (
 	"github.com/milvus-io/milvus/internal/storage"
 	"github.com/milvus-io/milvus/pkg/common"
 	"github.com/milvus-io/milvus/pkg/log"
+	"github.com/milvus-io/milvus/pkg/metrics"
 	"github.com/milvus-io/milvus/pkg/mq/msgstream"
 	"github.com/milvus-io/milvus/pkg/util/conc"
 	"github.com/milvus-io/milvus/pkg/util/merr"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+	"github.com/milvus-io/milvus/pkg/util/retry"
+	"github.com/milvus-io/milvus/pkg/util/tsoutil"
 	"github.com/milvus-io/milvus/pkg/util/typeutil"
 )
 
@@ -39,20 +48,113 @@ const (
 type WriteBuffer interface {
 	// HasSegment checks whether certain segment exists in this buffer.
 	HasSegment(segmentID int64) bool
+	// SegmentPartition returns the partition id of segment, false is returned if segment is unknown.
+	SegmentPartition(segmentID int64) (int64, bool)
+	// BufferedPartitionCount returns the number of distinct partitions of buffered segments.
+	BufferedPartitionCount() int
 	// BufferData is the method to buffer dml data msgs.
 	BufferData(insertMsgs []*msgstream.InsertMsg, deleteMsgs []*msgstream.DeleteMsg, startPos, endPos *msgpb.MsgPosition) error
+	// BufferDataGrouped buffers dml data msgs already grouped by segment id.
+	// delete msgs are still distributed by delete policy, the group key only decides the processing order.
+	BufferDataGrouped(insertBySegment map[int64][]*msgstream.InsertMsg, deleteBySegment map[int64][]*msgstream.DeleteMsg, startPos, endPos *msgpb.MsgPosition) error
+	// BufferDataSeq buffers dml data msgs with sequence id, batch is rejected if seq is not greater than
+	// the last accepted one of any inserted segment.
+	BufferDataSeq(insertMsgs []*msgstream.InsertMsg, deleteMsgs []*msgstream.DeleteMsg, startPos, endPos *msgpb.MsgPosition, seq uint64) error
+	// BufferRows buffers rows appended by fill callback into an existing segment without intermediate insert msgs.
+	BufferRows(segmentID int64, fill func(builder InsertBuilder) error, startPos, endPos *msgpb.MsgPosition) error
 	// FlushTimestamp set flush timestamp for write buffer
 	SetFlushTimestamp(flushTs uint64)
 	// GetFlushTimestamp get current flush timestamp
 	GetFlushTimestamp() uint64
+	// ClearFlushTimestamp resets flush timestamp so that flush ts policy no longer selects segments
+	ClearFlushTimestamp()
+	// UpdateSchema swaps in new collection schema for data buffered afterwards.
+	// It returns ErrSchemaChangeBuffered if insert data is buffered, unless buffered data is synced by WithFlushOnSchemaChange.
+	UpdateSchema(schema *schemapb.CollectionSchema) error
+	// SealSegment seals segment so that further insert data of it is rejected with ErrSegmentSealed,
+	// and its buffered data is flushed by next sync. The segment is unsealed once its flush sync succeeds.
+	SealSegment(segmentID int64) error
+	// SetThresholdMultiplier scales row & size sync thresholds of all buffers by m until ResetThresholds,
+	// non-positive multiplier is ignored.
+	SetThresholdMultiplier(m float64)
+	// ResetThresholds restores the configured sync thresholds.
+	ResetThresholds()
 	// FlushSegments is the method to perform `Sync` operation with provided options.
 	FlushSegments(ctx context.Context, segmentIDs []int64) error
+	// FlushSegmentsStrict flushes segments like FlushSegments but rejects the whole call with error listing
+	// the segments neither buffered nor in metacache.
+	FlushSegmentsStrict(ctx context.Context, segmentIDs []int64) error
+	// DrainSegment flushes one segment and blocks until its sync task finishes, returning the sync error if any.
+	DrainSegment(ctx context.Context, segmentID int64) error
+	// FlushSegmentsToPrefix flushes segments like FlushSegments, logs of them are written under provided storage path prefix.
+	// It is not supported by storage v2 sync.
+	FlushSegmentsToPrefix(ctx context.Context, segmentIDs []int64, prefix string) error
+	// SnapshotFlush syncs all buffered data not after ts and waits for it, returns the synced segment ids.
+	// Data after ts stays buffered even if it belongs to a synced segment.
+	SnapshotFlush(ctx context.Context, ts uint64) ([]int64, error)
 	// GetCheckpoint returns current channel checkpoint.
 	// If there are any non-empty segment buffer, returns the earliest buffer start position.
 	// Otherwise, returns latest buffered checkpoint.
 	GetCheckpoint() *msgpb.MsgPosition
+	// SyncManagerEarliestPosition returns the earliest position of syncing segments of this channel in sync manager.
+	SyncManagerEarliestPosition() (int64, *msgpb.MsgPosition)
+	// SyncBacklog returns the segments of this channel being synced in sync manager, ordered by position.
+	SyncBacklog() []SyncBacklogEntry
+	// LastCheckpointAdvanceCause returns the segment and the sync policy reason which advanced the checkpoint last time.
+	LastCheckpointAdvanceCause() (segmentID int64, reason string)
+	// TotalFlushedRows returns the number of rows yielded to sync tasks since the buffer was created.
+	TotalFlushedRows() int64
+	// OldestUnsyncedAge returns how long ago, relative to hybrid ts now, the earliest buffered msg was produced,
+	// zero if nothing buffered.
+	OldestUnsyncedAge(now uint64) time.Duration
+	// RowLagStats returns the number of rows buffered but not synced yet for each segment.
+	RowLagStats() map[int64]int64
+	// FieldMemorySize returns buffered insert data size of each field in provided segment.
+	FieldMemorySize(segmentID int64) map[int64]int64
+	// EstimateFlushSize estimates the serialized binlog & deltalog bytes a sync of provided segment would write,
+	// 0 if segment not buffered.
+	EstimateFlushSize(segmentID int64) int64
+	// InsertDeleteRatio returns buffered insert rows divided by buffered delete count of provided segment,
+	// +Inf if no delete buffered, -1 if segment not buffered.
+	InsertDeleteRatio(segmentID int64) float64
+	// LookupDelete returns the latest timestamp of buffered deletes of pk in segment.
+	LookupDelete(segmentID int64, pk storage.PrimaryKey) (typeutil.Timestamp, bool, error)
+	// DeleteTimeRange returns the time range of buffered delete data of provided segment,
+	// false is returned if segment not buffered or has no buffered delete.
+	DeleteTimeRange(segmentID int64) (*TimeRange, bool)
+	// TopSegmentsByMemory returns at most n buffered segments with largest memory size in descending order.
+	TopSegmentsByMemory(n int) []SegmentBufferStats
+	// EmptySegmentIDs returns ids of buffered segments without any insert or delete data in ascending order,
+	// which are created but never filled and usually indicate routing bugs.
+	EmptySegmentIDs() []int64
+	// BufferStats returns the buffer & sync statistics of the whole channel.
+	BufferStats() ChannelBufferStats
+	// MemorySplit returns buffered size of insert & delta data across all segments.
+	MemorySplit() (insertBytes, deltaBytes int64)
+	// WatchBufferSize returns a channel emitting total buffered bytes of this channel when it changes
+	// by more than configured delta, the channel is closed when write buffer closed.
+	WatchBufferSize() <-chan int64
+	// WarmupBloomFilters loads persisted pk statistics of buffered segments into their bloom filter sets.
+	WarmupBloomFilters(ctx context.Context) error
+	// LastSyncError returns the last sync failure of segment, nil if its latest sync succeeded or it never failed.
+	LastSyncError(segmentID int64) error
+	// DroppedSyncCount returns the number of syncs dropped since segment meta not found.
+	DroppedSyncCount() int64
+	// DeleteAppliesTo checks whether delete of provided pk may apply to segment by its bloom filter set in metacache,
+	// false is returned if segment not found.
+	DeleteAppliesTo(segmentID int64, pk storage.PrimaryKey) bool
+	// BFFalsePositiveDeletes returns the number of buffered deletes which matched bloom filter but found no corresponding insert.
+	// Segments with synced or syncing data are not checked.
+	BFFalsePositiveDeletes() int64
+	// ExportCheckpoint serializes channel checkpoint and earliest positions of buffered segments in a versioned format.
+	ExportCheckpoint() ([]byte, error)
+	// ImportCheckpoint restores the state exported by ExportCheckpoint.
+	ImportCheckpoint(data []byte) error
+	// Reconcile drops buffers of segments which are not in metacache or already dropped, returns the dropped segment ids.
+	// buffers of flushed segments are kept, since deletes of them are still buffered and synced as delta logs.
+	Reconcile() []int64
 	// Close is the method to close and sink current buffer data.
-	Close(drop bool)
+	Close(drop bool) error
 }
 
 func NewWriteBuffer(channel string, metacache metacache.MetaCache, storageV2Cache *metacache.StorageV2Cache, syncMgr syncmgr.SyncManager, opts ...WriteBufferOption) (WriteBuffer, error) {
@@ -61,6 +163,15 @@ func NewWriteBuffer(channel string, metacache metacache.MetaCache, storageV2Cach
 		opt(option)
 	}
 
+	if option.binlogFormatVersion != 0 && option.binlogFormatVersion != BinlogFormatV1 && option.binlogFormatVersion != BinlogFormatV2 {
+		return nil, merr.WrapErrParameterInvalidMsg("unsupported binlog format version %d", option.binlogFormatVersion)
+	}
+
+	if metacache.Schema() == nil {
+		log.Warn("failed to create write buffer, collection schema is nil", zap.String("channel", channel))
+		return nil, ErrNilSchema
+	}
+
 	switch option.deletePolicy {
 	case DeletePolicyBFPkOracle:
 		return NewBFWriteBuffer(channel, metacache, nil, syncMgr, option)
@@ -71,15 +182,64 @@ func NewWriteBuffer(channel string, metacache metacache.MetaCache, storageV2Cach
 	}
 }
 
-// writeBufferBase is the common component for buffering data
+// FlushEvent describes one completed sync of segment buffer data, emitted to flush event sink for audit.
+type FlushEvent struct {
+	SegmentID     int64
+	Rows          int64
+	Bytes         int64
+	TimestampFrom typeutil.Timestamp
+	TimestampTo   typeutil.Timestamp
+	// Reason is the reason of sync policy selecting the segment, empty if synced by other operations.
+	Reason string
+	// Timestamp is the time when sync completed.
+	Timestamp time.Time
+}
+
+// SyncBacklogEntry is one segment being synced in sync manager, with the earliest start position of its sync tasks.
+type SyncBacklogEntry struct {
+	SegmentID int64
+	Position  *msgpb.MsgPosition
+	// Age is the time elapsed since position timestamp.
+	Age time.Duration
+}
+
+// SegmentBufferStats is the buffer statistics of one segment.
+type SegmentBufferStats struct {
+	SegmentID  int64
+	InsertRows int64
+	DeltaRows  int64
+	MemorySize int64
+}
+
+// ChannelBufferStats is the buffer & sync statistics of one channel write buffer.
+type ChannelBufferStats struct {
+	InsertRows   int64
+	DeltaRows    int64
+	MemorySize   int64
+	PendingSyncs int
+	// SyncCount & SyncLatency are the number and total latency of succeeded sync tasks, from creation to completion.
+	SyncCount   int64
+	SyncLatency time.Duration
+	// Checkpoint is the channel checkpoint evaluated without unblocking checkpoint callers, nil if never set.
+	Checkpoint *msgpb.MsgPosition
+}
+
+// writeBufferBase is the common component for buffering data.
+//
+// Locking: holding mut exclusively protects everything, including buffer map structure, checkpoint & sync.
+// Contents of an existing segment buffer may also be mutated with mut held shared plus the lock of its segment
+// in segmentLocks, so readers holding mut shared shall take the segment lock before reading buffer contents.
+// At most one segment lock is held at a time, and it is always acquired after mut.
 type writeBufferBase struct {
-	mut sync.RWMutex
+	mut          sync.RWMutex
+	segmentLocks segmentLocks
 
 	collectionID int64
 	channelName  string
 
 	metaWriter syncmgr.MetaWriter
 	collSchema *schemapb.CollectionSchema
+	validator  *fieldValidator
 	metaCache  metacache.MetaCache
 	syncMgr    syncmgr.SyncManager
 	broker     broker.Broker
@@ -88,26 +248,153 @@ type writeBufferBase struct {
 	syncPolicies   []SyncPolicy
 	checkpoint     *msgpb.MsgPosition
 	flushTimestamp *atomic.Uint64
+	limitScale     *atomic.Float64
+	cpTracker      *checkpointTracker
+	rowLagTracker  *rowLagTracker
+	pendingSyncs   *pendingSyncTracker
+	syncErrors     *syncErrorTracker
+	syncPositions  *syncPositionTracker
+	orderedDeltas  *syncPositionTracker
+	sizeWatcher    *bufferSizeWatcher
+	partitionLRU   *partitionWriteTracker
+
+	decoupleDeltaFlush  bool
+	orderedSync         bool
+	noAutoSegmentCreate bool
+	dropWithoutFlush    bool
+	syncDeduplication   bool
+	arrowBuffering      bool
+	deletePruning       bool
+	syncMgrCheckpoint   bool
+	keepEmptyInserts    bool
+	schemaChangeFlush   bool
+
+	segmentIDAllocator func() int64
+	pkExtractor        func(*storage.InsertData) []storage.PrimaryKey
+	allocatedSegments  map[int64]int64  // msg segmentID => allocated segmentID
+	segmentSeqs        map[int64]uint64 // segmentID => last accepted sequence id
+	flushPrefixes      map[int64]string // segmentID => storage path prefix of flush
+	syncReasons        map[int64]string // segmentID => reason of sync policy selecting it in current sync
+	insertWatermarks   map[int64]uint64 // segmentID => earliest insert timestamp of segment created by write buffer
+	sealedSegments     *typeutil.ConcurrentSet[int64]
+
+	storagev2Cache      *metacache.StorageV2Cache
+	spaceCreator        func(segmentID int64, collSchema *schemapb.CollectionSchema, arrowSchema *arrow.Schema) func() (*milvus_storage.Space, error)
+	spaceCreateAttempts int
+	spaceCreateDelay    time.Duration
 
-	storagev2Cache *metacache.StorageV2Cache
+	cpComparator   func(a, b *msgpb.MsgPosition) int
+	tsGranularity  time.Duration
+	maxDeleteBatch int
+	deleteIndexDir string
+	deleteIndexMax int
+	closeParallel  int
+	binlogVersion  int
+	statsLoader    StatsLoader
+	ingestLimit    *ingestLimiter
+
+	cpUnblockCallback func(oldCP, newCP *msgpb.MsgPosition)
+	levelPriority     map[int32]int // segment level => sync task priority
+	flushEventSink    func(FlushEvent)
+	preSyncTransform  func(segmentID int64, insert *storage.InsertData, delete *storage.DeleteData) error
+	flushVerifier     FlushVerifyReader
+	// onSyncTask is invoked with segment id of every sync task built, whichever path submits it, with lock held.
+	onSyncTask func(segmentID int64)
+
+	totalFlushedRows atomic.Int64
+	droppedSyncCount atomic.Int64
+	syncCount        atomic.Int64
+	syncLatencyNanos atomic.Int64
+
+	autoSyncInterval time.Duration
+	closeCh          chan struct{}
+	closeOnce        sync.Once
+	closeWg          sync.WaitGroup
+	// closing is set once Close starts sinking buffers, sync failures are returned by Close instead of panicking
+	closing atomic.Bool
+
+	closed bool
 }
 
 func newWriteBufferBase(channel string, metacache metacache.MetaCache, storageV2Cache *metacache.StorageV2Cache, syncMgr syncmgr.SyncManager, option *writeBufferOption) *writeBufferBase {
 	flushTs := atomic.NewUint64(nonFlushTS)
-	flushTsPolicy := GetFlushTsPolicy(flushTs, metacache)
-	option.syncPolicies = append(option.syncPolicies, flushTsPolicy)
+	flushTsPolicy := GetFlushTsPolicyWithInclusive(flushTs, metacache, option.flushTsInclusive)
+	sealedSegments := typeutil.NewConcurrentSet[int64]()
+	option.syncPolicies = append(option.syncPolicies, flushTsPolicy, GetSealedSegmentsPolicy(sealedSegments))
+	var syncPositions *syncPositionTracker
+	if option.conservativeCheckpoint {
+		syncPositions = newSyncPositionTracker()
+	}
+	var partitionLRU *partitionWriteTracker
+	if option.maxBufferedPartitions > 0 {
+		partitionLRU = newPartitionWriteTracker()
+		option.syncPolicies = append(option.syncPolicies, GetMaxBufferedPartitionsPolicy(metacache, partitionLRU, option.maxBufferedPartitions))
+	}
+	cpComparator := option.checkpointComparator
+	if cpComparator == nil {
+		cpComparator = compareTimestamp
+	}
 
 	return &writeBufferBase{
 		channelName:    channel,
 		collectionID:   metacache.Collection(),
 		collSchema:     metacache.Schema(),
+		validator:      newFieldValidator(metacache.Schema(), option.dropUnknownFields, !option.skipDimValidation),
 		syncMgr:        syncMgr,
 		metaWriter:     option.metaWriter,
 		buffers:        make(map[int64]*segmentBuffer),
 		metaCache:      metacache,
 		syncPolicies:   option.syncPolicies,
 		flushTimestamp: flushTs,
+		limitScale:     atomic.NewFloat64(1),
+		cpTracker:      newCheckpointTracker(),
+		rowLagTracker:  newRowLagTracker(),
+		pendingSyncs:   newPendingSyncTracker(),
+		syncErrors:     newSyncErrorTracker(),
+		syncPositions:  syncPositions,
+		orderedDeltas:  newSyncPositionTracker(),
+		sizeWatcher:    newBufferSizeWatcher(option.bufferSizeWatchDelta),
+		partitionLRU:   partitionLRU,
 		storagev2Cache: storageV2Cache,
+		spaceCreator:   SpaceCreatorFunc,
+		cpComparator:   cpComparator,
+		tsGranularity:  option.timeRangeGranularity,
+		maxDeleteBatch: option.maxDeleteBatch,
+		deleteIndexDir: option.deleteIndexDir,
+		deleteIndexMax: option.deleteIndexMaxEntries,
+		closeParallel:  option.closeParallelism,
+		binlogVersion:  option.binlogFormatVersion,
+		statsLoader:    option.statsLoader,
+		ingestLimit:    newIngestLimiter(option.ingestRateLimit, option.ingestRateLimitBlock),
+
+		spaceCreateAttempts: option.spaceCreateAttempts,
+		spaceCreateDelay:    option.spaceCreateDelay,
+		cpUnblockCallback:   option.checkpointUnblockCallback,
+		levelPriority:       option.levelPriority,
+
+		decoupleDeltaFlush:  option.decoupleDeltaFlush,
+		orderedSync:         option.orderedSync,
+		noAutoSegmentCreate: option.noAutoSegmentCreate,
+		dropWithoutFlush:    option.dropWithoutFlush,
+		syncDeduplication:   option.syncDeduplication,
+		arrowBuffering:      option.arrowNativeBuffering,
+		deletePruning:       option.deletePruning,
+		syncMgrCheckpoint:   option.checkpointFromSyncManagerOnly,
+		keepEmptyInserts:    option.keepEmptyInserts,
+		schemaChangeFlush:   option.flushOnSchemaChange,
+		pkExtractor:         option.pkExtractor,
+		autoSyncInterval:    option.autoSyncInterval,
+		closeCh:             make(chan struct{}),
+		segmentIDAllocator:  option.segmentIDAllocator,
+		allocatedSegments:   make(map[int64]int64),
+		segmentSeqs:         make(map[int64]uint64),
+		flushPrefixes:       make(map[int64]string),
+		syncReasons:         make(map[int64]string),
+		insertWatermarks:    make(map[int64]uint64),
+		sealedSegments:      sealedSegments,
+		flushEventSink:      option.flushEventSink,
+		preSyncTransform:    option.preSyncTransform,
+		flushVerifier:       option.postFlushVerifier,
 	}
 }
 
@@ -119,13 +406,114 @@ func (wb *writeBufferBase) HasSegment(segmentID int64) bool {
 	return ok
 }
 
-func (wb *writeBufferBase) FlushSegments(ctx context.Context, segmentIDs []int64) error {
+func (wb *writeBufferBase) SegmentPartition(segmentID int64) (int64, bool) {
+	segment, ok := wb.metaCache.GetSegmentByID(segmentID)
+	if !ok {
+		return 0, false
+	}
+	return segment.PartitionID(), true
+}
+
+func (wb *writeBufferBase) BufferedPartitionCount() int {
 	wb.mut.RLock()
 	defer wb.mut.RUnlock()
 
+	return len(groupByPartition(wb.metaCache, lo.Values(wb.buffers)))
+}
+
+func (wb *writeBufferBase) FlushSegments(ctx context.Context, segmentIDs []int64) error {
+	// flushing removes segment id mappings, write lock is required
+	wb.mut.Lock()
+	defer wb.mut.Unlock()
+
+	if wb.closed {
+		return ErrBufferClosed
+	}
 	return wb.flushSegments(ctx, segmentIDs)
 }
 
+func (wb *writeBufferBase) FlushSegmentsStrict(ctx context.Context, segmentIDs []int64) error {
+	wb.mut.Lock()
+	defer wb.mut.Unlock()
+
+	if wb.closed {
+		return ErrBufferClosed
+	}
+
+	var errs []error
+	for _, segmentID := range segmentIDs {
+		if _, ok := wb.buffers[segmentID]; ok {
+			continue
+		}
+		if _, ok := wb.metaCache.GetSegmentByID(segmentID); ok {
+			continue
+		}
+		errs = append(errs, merr.WrapErrSegmentNotFound(segmentID, "segment to flush unknown to write buffer"))
+	}
+	if len(errs) > 0 {
+		log.Ctx(ctx).Warn("reject flushing unknown segments", zap.String("channel", wb.channelName), zap.Int("unknown", len(errs)))
+		return merr.Combine(errs...)
+	}
+	return wb.flushSegments(ctx, segmentIDs)
+}
+
+func (wb *writeBufferBase) DrainSegment(ctx context.Context, segmentID int64) error {
+	future, err := wb.drainSegment(ctx, segmentID)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-future.Inner():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	// sync task error is returned as future value
+	err, _ = future.Await()
+	return err
+}
+
+// drainSegment marks provided segment flushing and submits its sync task right away.
+func (wb *writeBufferBase) drainSegment(ctx context.Context, segmentID int64) (*conc.Future[error], error) {
+	wb.mut.Lock()
+	defer wb.mut.Unlock()
+
+	if wb.closed {
+		return nil, ErrBufferClosed
+	}
+	if _, ok := wb.metaCache.GetSegmentByID(segmentID); !ok {
+		return nil, merr.WrapErrSegmentNotFound(segmentID, "segment to drain unknown to write buffer")
+	}
+	if err := wb.flushSegments(ctx, []int64{segmentID}); err != nil {
+		return nil, err
+	}
+
+	syncTask, err := wb.getSyncTask(ctx, segmentID)
+	if err != nil {
+		return nil, err
+	}
+	return wb.syncMgr.SyncData(ctx, syncTask), nil
+}
+
+func (wb *writeBufferBase) FlushSegmentsToPrefix(ctx context.Context, segmentIDs []int64, prefix string) error {
+	wb.mut.Lock()
+	defer wb.mut.Unlock()
+
+	if wb.closed {
+		return ErrBufferClosed
+	}
+	if wb.useStorageV2() {
+		return merr.WrapErrParameterInvalidMsg("flush path prefix is not supported by storage v2 sync")
+	}
+	if err := wb.flushSegments(ctx, segmentIDs); err != nil {
+		return err
+	}
+	for _, segmentID := range segmentIDs {
+		wb.flushPrefixes[segmentID] = prefix
+	}
+	return nil
+}
+
 func (wb *writeBufferBase) SetFlushTimestamp(flushTs uint64) {
 	wb.flushTimestamp.Store(flushTs)
 }
@@ -134,12 +522,151 @@ func (wb *writeBufferBase) GetFlushTimestamp() uint64 {
 	return wb.flushTimestamp.Load()
 }
 
+func (wb *writeBufferBase) ClearFlushTimestamp() {
+	wb.flushTimestamp.Store(nonFlushTS)
+}
+
+func (wb *writeBufferBase) UpdateSchema(schema *schemapb.CollectionSchema) error {
+	wb.mut.Lock()
+	defer wb.mut.Unlock()
+
+	if wb.closed {
+		return ErrBufferClosed
+	}
+
+	if wb.schemaChangeFlush {
+		// sync tasks are built with the old schema before it is swapped
+		segmentIDs := wb.sortedSegmentIDs()
+		log.Info("sync buffered data before schema change", zap.String("channel", wb.channelName), zap.Int64s("segmentIDs", segmentIDs))
+		wb.syncSegments(context.Background(), segmentIDs)
+	}
+	// insert data of old schema could not be synced with new schema
+	buffered := lo.Filter(wb.sortedSegmentIDs(), func(segmentID int64, _ int) bool {
+		return !wb.buffers[segmentID].insertBuffer.IsEmpty()
+	})
+	if len(buffered) > 0 {
+		log.Warn("refuse schema change with insert data buffered", zap.String("channel", wb.channelName), zap.Int64s("segmentIDs", buffered))
+		return errors.Wrapf(ErrSchemaChangeBuffered, "segments %v", buffered)
+	}
+
+	wb.collSchema = schema
+	wb.metaCache.UpdateSchema(schema)
+	wb.validator = newFieldValidator(schema, wb.validator.dropUnknown, wb.validator.checkDim)
+	return nil
+}
+
+func (wb *writeBufferBase) SealSegment(segmentID int64) error {
+	wb.mut.Lock()
+	defer wb.mut.Unlock()
+
+	if wb.closed {
+		return ErrBufferClosed
+	}
+	// synced as flush so that segment is flushed after sealed data persisted
+	if err := wb.flushSegments(context.Background(), []int64{segmentID}); err != nil {
+		log.Warn("failed to mark sealed segment flushing", zap.Int64("segmentID", segmentID), zap.Error(err))
+		return err
+	}
+	wb.sealedSegments.Insert(segmentID)
+	log.Info("segment sealed", zap.String("channel", wb.channelName), zap.Int64("segmentID", segmentID))
+	return nil
+}
+
+func (wb *writeBufferBase) SetThresholdMultiplier(m float64) {
+	if m <= 0 {
+		log.Warn("ignore non-positive sync threshold multiplier", zap.String("channel", wb.channelName), zap.Float64("multiplier", m))
+		return
+	}
+	wb.limitScale.Store(m)
+}
+
+func (wb *writeBufferBase) ResetThresholds() {
+	wb.limitScale.Store(1)
+}
+
+func (wb *writeBufferBase) SyncManagerEarliestPosition() (int64, *msgpb.MsgPosition) {
+	return wb.syncMgr.GetEarliestPosition(wb.channelName)
+}
+
+func (wb *writeBufferBase) SyncBacklog() []SyncBacklogEntry {
+	positions := wb.syncMgr.GetSegmentPositions(wb.channelName)
+	now := time.Now()
+	entries := make([]SyncBacklogEntry, 0, len(positions))
+	for segmentID, pos := range positions {
+		age := now.Sub(tsoutil.PhysicalTime(pos.GetTimestamp()))
+		if age < 0 {
+			age = 0
+		}
+		entries = append(entries, SyncBacklogEntry{SegmentID: segmentID, Position: pos, Age: age})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Position.GetTimestamp() != entries[j].Position.GetTimestamp() {
+			return entries[i].Position.GetTimestamp() < entries[j].Position.GetTimestamp()
+		}
+		return entries[i].SegmentID < entries[j].SegmentID
+	})
+	return entries
+}
+
+func (wb *writeBufferBase) LastCheckpointAdvanceCause() (int64, string) {
+	// evaluate checkpoint so that cause reflects current state rather than the last poll,
+	// without observing it, which could unblock checkpoint callers
+	wb.mut.RLock()
+	segmentID, checkpoint := wb.evaluateCheckpoint()
+	wb.mut.RUnlock()
+	return wb.cpTracker.CauseAt(segmentID, checkpoint)
+}
+
+func (wb *writeBufferBase) TotalFlushedRows() int64 {
+	return wb.totalFlushedRows.Load()
+}
+
+func (wb *writeBufferBase) RowLagStats() map[int64]int64 {
+	return wb.rowLagTracker.Stats()
+}
+
+func (wb *writeBufferBase) OldestUnsyncedAge(now typeutil.Timestamp) time.Duration {
+	wb.mut.RLock()
+	defer wb.mut.RUnlock()
+
+	var oldest *msgpb.MsgPosition
+	for _, buf := range wb.buffers {
+		pos := wb.earliestPosition(buf)
+		if pos != nil && (oldest == nil || pos.GetTimestamp() < oldest.GetTimestamp()) {
+			oldest = pos
+		}
+	}
+	if oldest == nil {
+		return 0
+	}
+
+	age := tsoutil.PhysicalTime(now).Sub(tsoutil.PhysicalTime(oldest.GetTimestamp()))
+	if age < 0 {
+		return 0
+	}
+	return age
+}
+
 func (wb *writeBufferBase) GetCheckpoint() *msgpb.MsgPosition {
+	wb.mut.RLock()
+	segmentID, checkpoint := wb.evaluateCheckpoint()
+	unblocked := wb.cpTracker.Observe(segmentID, checkpoint)
+	wb.mut.RUnlock()
+
+	// callback is invoked outside lock so that it could call write buffer methods
+	if unblocked != nil && wb.cpUnblockCallback != nil {
+		wb.cpUnblockCallback(unblocked, checkpoint)
+	}
+	return checkpoint
+}
+
+// evaluateCheckpoint returns channel checkpoint and the segment it comes from,
+// segmentID is zero if checkpoint is the latest consumed position.
+// **NOTE** shall be invoked within mutex protection
+func (wb *writeBufferBase) evaluateCheckpoint() (int64, *msgpb.MsgPosition) {
 	log := log.Ctx(context.Background()).
 		With(zap.String("channel", wb.channelName)).
 		WithRateGroup(fmt.Sprintf("writebuffer_cp_%s", wb.channelName), 1, 60)
-	wb.mut.RLock()
-	defer wb.mut.RUnlock()
 
 	// syncCandidate from sync manager
 	syncSegmentID, syncCandidate := wb.syncMgr.GetEarliestPosition(wb.channelName)
@@ -151,15 +678,32 @@ func (wb *writeBufferBase) GetCheckpoint() *msgpb.MsgPosition {
 	var bufferCandidate *checkpointCandidate
 
 	candidates := lo.MapToSlice(wb.buffers, func(_ int64, buf *segmentBuffer) *checkpointCandidate {
-		return &checkpointCandidate{buf.segmentID, buf.EarliestPosition()}
+		return &checkpointCandidate{buf.segmentID, wb.earliestPosition(buf)}
 	})
 	candidates = lo.Filter(candidates, func(candidate *checkpointCandidate, _ int) bool {
-		return candidate.position != nil
+		return candidate.position != nil && !wb.syncMgrCheckpoint
 	})
+	// buffers without data are never synced by flush ts policy, they shall not hold checkpoint back while flushing
+	if wb.flushTimestamp.Load() != nonFlushTS {
+		candidates = lo.Filter(candidates, func(candidate *checkpointCandidate, _ int) bool {
+			return !wb.bufferEmpty(wb.buffers[candidate.segmentID])
+		})
+	}
+
+	// data being synced holds checkpoint until its sync task succeeds
+	if wb.syncPositions != nil {
+		candidates = append(candidates, lo.MapToSlice(wb.syncPositions.Earliest(), func(segmentID int64, pos *msgpb.MsgPosition) *checkpointCandidate {
+			return &checkpointCandidate{segmentID, pos}
+		})...)
+	}
+	// delta data waiting for its insert task to finish is neither buffered nor in sync manager
+	candidates = append(candidates, lo.MapToSlice(wb.orderedDeltas.Earliest(), func(segmentID int64, pos *msgpb.MsgPosition) *checkpointCandidate {
+		return &checkpointCandidate{segmentID, pos}
+	})...)
 
 	if len(candidates) > 0 {
 		bufferCandidate = lo.MinBy(candidates, func(a, b *checkpointCandidate) bool {
-			return a.position.GetTimestamp() < b.position.GetTimestamp()
+			return wb.cpComparator(a.position, b.position) < 0
 		})
 	}
 
@@ -170,7 +714,7 @@ func (wb *writeBufferBase) GetCheckpoint() *msgpb.MsgPosition {
 	case bufferCandidate == nil && syncCandidate == nil:
 		// all buffer are empty
 		log.RatedInfo(60, "checkpoint from latest consumed msg")
-		return wb.checkpoint
+		return 0, wb.checkpoint
 	case bufferCandidate == nil && syncCandidate != nil:
 		checkpoint = syncCandidate
 		segmentID = syncSegmentID
@@ -179,11 +723,11 @@ func (wb *writeBufferBase) GetCheckpoint() *msgpb.MsgPosition {
 		checkpoint = bufferCandidate.position
 		segmentID = bufferCandidate.segmentID
 		cpSource = "segmentBuffer"
-	case syncCandidate.GetTimestamp() >= bufferCandidate.position.GetTimestamp():
+	case wb.cpComparator(syncCandidate, bufferCandidate.position) >= 0:
 		checkpoint = bufferCandidate.position
 		segmentID = bufferCandidate.segmentID
 		cpSource = "segmentBuffer"
-	case syncCandidate.GetTimestamp() < bufferCandidate.position.GetTimestamp():
+	default:
 		checkpoint = syncCandidate
 		segmentID = syncSegmentID
 		cpSource = "syncManager"
@@ -193,17 +737,316 @@ func (wb *writeBufferBase) GetCheckpoint() *msgpb.MsgPosition {
 		zap.String("cpSource", cpSource),
 		zap.Int64("segmentID", segmentID),
 		zap.Uint64("cpTimestamp", checkpoint.GetTimestamp()))
-	return checkpoint
+	return segmentID, checkpoint
 }
 
-func (wb *writeBufferBase) triggerSync() (segmentIDs []int64) {
+// useStorageV2 returns whether sync tasks write storage v2 format.
+func (wb *writeBufferBase) useStorageV2() bool {
+	switch wb.binlogVersion {
+	case BinlogFormatV1:
+		return false
+	case BinlogFormatV2:
+		return true
+	default:
+		return params.Params.CommonCfg.EnableStorageV2.GetAsBool()
+	}
+}
+
+// quantizeTimeRange aligns tsFrom to the start and tsTo to the end of the granularity bucket they fall in.
+// Time range is returned as is if granularity is less than one millisecond.
+func quantizeTimeRange(tsFrom, tsTo uint64, granularity time.Duration) (uint64, uint64) {
+	bucket := granularity.Milliseconds()
+	if bucket <= 0 {
+		return tsFrom, tsTo
+	}
+	from, _ := tsoutil.ParseHybridTs(tsFrom)
+	to, _ := tsoutil.ParseHybridTs(tsTo)
+	return tsoutil.ComposeTS(from-from%bucket, 0), tsoutil.ComposeTS(to-to%bucket+bucket, 0) - 1
+}
+
+// compareTimestamp is the default checkpoint comparator comparing positions by timestamp.
+func compareTimestamp(a, b *msgpb.MsgPosition) int {
+	switch {
+	case a.GetTimestamp() < b.GetTimestamp():
+		return -1
+	case a.GetTimestamp() > b.GetTimestamp():
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (wb *writeBufferBase) triggerSync(ctx context.Context) (segmentIDs []int64, err error) {
+	if wb.closed {
+		return nil, ErrBufferClosed
+	}
+
 	segmentsToSync := wb.getSegmentsToSync(wb.checkpoint.GetTimestamp())
 	if len(segmentsToSync) > 0 {
 		log.Info("write buffer get segments to sync", zap.Int64s("segmentIDs", segmentsToSync))
-		wb.syncSegments(context.Background(), segmentsToSync)
+		wb.syncSegments(ctx, segmentsToSync)
+	}
+	wb.sizeWatcher.Observe(wb.bufferedSize())
+
+	return segmentsToSync, nil
+}
+
+// bufferedSize returns total size of insert & delta data in buffers, caller shall hold the lock.
+func (wb *writeBufferBase) bufferedSize() int64 {
+	var size int64
+	for _, buf := range wb.buffers {
+		size += buf.MemorySize()
+	}
+	return size
+}
+
+// startAutoSync runs syncFn periodically with write buffer lock held until write buffer is closed.
+func (wb *writeBufferBase) startAutoSync(syncFn func(ctx context.Context) error) {
+	if wb.autoSyncInterval <= 0 {
+		return
+	}
+
+	wb.runBackground(func(closeCh <-chan struct{}) {
+		ticker := time.NewTicker(wb.autoSyncInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-closeCh:
+				return
+			case <-ticker.C:
+				wb.mut.Lock()
+				err := syncFn(context.Background())
+				wb.mut.Unlock()
+				if errors.Is(err, ErrBufferClosed) {
+					return
+				}
+				if err != nil {
+					log.Warn("failed to auto sync write buffer", zap.String("channel", wb.channelName), zap.Error(err))
+				}
+			}
+		}
+	})
+}
+
+// runBackground runs fn in a goroutine joined by Close, fn shall return once closeCh is closed.
+// All background goroutines of write buffer shall be started by it so that none outlives Close.
+// Returns false without running fn if write buffer is closed, caller shall hold the lock.
+func (wb *writeBufferBase) runBackground(fn func(closeCh <-chan struct{})) bool {
+	if wb.closed {
+		return false
+	}
+	wb.closeWg.Add(1)
+	go func() {
+		defer wb.closeWg.Done()
+		fn(wb.closeCh)
+	}()
+	return true
+}
+
+func (wb *writeBufferBase) BufferStats() ChannelBufferStats {
+	wb.mut.RLock()
+	defer wb.mut.RUnlock()
+
+	stats := ChannelBufferStats{
+		PendingSyncs: wb.pendingSyncs.Total(),
+		SyncCount:    wb.syncCount.Load(),
+		SyncLatency:  time.Duration(wb.syncLatencyNanos.Load()),
+	}
+	_, stats.Checkpoint = wb.evaluateCheckpoint()
+	for _, buf := range wb.buffers {
+		wb.segmentLocks.Lock(buf.segmentID)
+		stats.InsertRows += buf.insertBuffer.rows
+		stats.DeltaRows += buf.deltaBuffer.rows
+		stats.MemorySize += buf.MemorySize()
+		wb.segmentLocks.Unlock(buf.segmentID)
+	}
+	return stats
+}
+
+func (wb *writeBufferBase) EmptySegmentIDs() []int64 {
+	wb.mut.RLock()
+	defer wb.mut.RUnlock()
+
+	return lo.Filter(wb.sortedSegmentIDs(), func(segmentID int64, _ int) bool {
+		return wb.bufferEmpty(wb.buffers[segmentID])
+	})
+}
+
+func (wb *writeBufferBase) MemorySplit() (insertBytes, deltaBytes int64) {
+	wb.mut.RLock()
+	defer wb.mut.RUnlock()
+
+	for _, buf := range wb.buffers {
+		wb.segmentLocks.Lock(buf.segmentID)
+		insertBytes += buf.insertBuffer.size
+		deltaBytes += buf.deltaBuffer.size
+		wb.segmentLocks.Unlock(buf.segmentID)
+	}
+	return insertBytes, deltaBytes
+}
+
+func (wb *writeBufferBase) TopSegmentsByMemory(n int) []SegmentBufferStats {
+	wb.mut.RLock()
+	defer wb.mut.RUnlock()
+
+	stats := lo.MapToSlice(wb.buffers, func(segmentID int64, buf *segmentBuffer) SegmentBufferStats {
+		wb.segmentLocks.Lock(segmentID)
+		defer wb.segmentLocks.Unlock(segmentID)
+		return SegmentBufferStats{
+			SegmentID:  segmentID,
+			InsertRows: buf.insertBuffer.rows,
+			DeltaRows:  buf.deltaBuffer.rows,
+			MemorySize: buf.MemorySize(),
+		}
+	})
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].MemorySize != stats[j].MemorySize {
+			return stats[i].MemorySize > stats[j].MemorySize
+		}
+		return stats[i].SegmentID < stats[j].SegmentID
+	})
+	if n < 0 {
+		n = 0
+	}
+	if len(stats) > n {
+		stats = stats[:n]
+	}
+	return stats
+}
+
+func (wb *writeBufferBase) WatchBufferSize() <-chan int64 {
+	return wb.sizeWatcher.Chan()
+}
+
+func (wb *writeBufferBase) WarmupBloomFilters(ctx context.Context) error {
+	if wb.statsLoader == nil {
+		return merr.WrapErrServiceInternal("stats loader not configured for write buffer")
+	}
+
+	// load stats without holding lock, buffering shall not be blocked by storage io
+	wb.mut.RLock()
+	segmentIDs := lo.Keys(wb.buffers)
+	wb.mut.RUnlock()
+
+	for _, segmentID := range segmentIDs {
+		segment, ok := wb.metaCache.GetSegmentByID(segmentID)
+		if !ok {
+			log.Ctx(ctx).Warn("segment not found in meta, skip bloom filter warmup", zap.Int64("segmentID", segmentID))
+			continue
+		}
+		stats, err := wb.statsLoader(ctx, segmentID)
+		if err != nil {
+			log.Ctx(ctx).Warn("failed to load segment stats", zap.Int64("segmentID", segmentID), zap.Error(err))
+			return err
+		}
+		segment.GetBloomFilterSet().AddHistory(stats...)
+	}
+	return nil
+}
+
+func (wb *writeBufferBase) LastSyncError(segmentID int64) error {
+	return wb.syncErrors.Get(segmentID)
+}
+
+func (wb *writeBufferBase) DroppedSyncCount() int64 {
+	return wb.droppedSyncCount.Load()
+}
+
+func (wb *writeBufferBase) DeleteAppliesTo(segmentID int64, pk storage.PrimaryKey) bool {
+	segment, ok := wb.metaCache.GetSegmentByID(segmentID)
+	if !ok {
+		return false
 	}
+	return segment.GetBloomFilterSet().PkExists(pk)
+}
+
+func (wb *writeBufferBase) recordDroppedSync() {
+	wb.droppedSyncCount.Inc()
+	metrics.DataNodeDroppedSyncCount.WithLabelValues(fmt.Sprint(paramtable.GetNodeID()), wb.channelName).Inc()
+}
+
+func (wb *writeBufferBase) FieldMemorySize(segmentID int64) map[int64]int64 {
+	wb.mut.RLock()
+	defer wb.mut.RUnlock()
+	wb.segmentLocks.Lock(segmentID)
+	defer wb.segmentLocks.Unlock(segmentID)
+
+	result := make(map[int64]int64)
+	buf, ok := wb.buffers[segmentID]
+	if !ok {
+		return result
+	}
+	for fieldID, fieldData := range buf.insertBuffer.buffer.Data {
+		result[fieldID] = int64(fieldData.GetMemorySize())
+	}
+	return result
+}
+
+func (wb *writeBufferBase) InsertDeleteRatio(segmentID int64) float64 {
+	wb.mut.RLock()
+	defer wb.mut.RUnlock()
+	wb.segmentLocks.Lock(segmentID)
+	defer wb.segmentLocks.Unlock(segmentID)
+
+	buf, ok := wb.buffers[segmentID]
+	if !ok || (buf.insertBuffer.IsEmpty() && buf.deltaBuffer.IsEmpty()) {
+		return -1
+	}
+	if buf.deltaBuffer.IsEmpty() {
+		return math.Inf(1)
+	}
+	return float64(buf.insertBuffer.rows) / float64(buf.deltaBuffer.rows)
+}
+
+func (wb *writeBufferBase) DeleteTimeRange(segmentID int64) (*TimeRange, bool) {
+	wb.mut.RLock()
+	defer wb.mut.RUnlock()
+	wb.segmentLocks.Lock(segmentID)
+	defer wb.segmentLocks.Unlock(segmentID)
+
+	buf, ok := wb.buffers[segmentID]
+	if !ok || buf.deltaBuffer.IsEmpty() {
+		return nil, false
+	}
+	return buf.deltaBuffer.GetTimeRange(), true
+}
 
-	return segmentsToSync
+func (wb *writeBufferBase) LookupDelete(segmentID int64, pk storage.PrimaryKey) (typeutil.Timestamp, bool, error) {
+	wb.mut.RLock()
+	defer wb.mut.RUnlock()
+	wb.segmentLocks.Lock(segmentID)
+	defer wb.segmentLocks.Unlock(segmentID)
+
+	buf, ok := wb.buffers[segmentID]
+	if !ok || buf.deltaBuffer.IsEmpty() {
+		return 0, false, nil
+	}
+	return buf.deltaBuffer.Lookup(pk)
+}
+
+func (wb *writeBufferBase) Reconcile() []int64 {
+	wb.mut.Lock()
+	defer wb.mut.Unlock()
+
+	var dropped []int64
+	for segmentID := range wb.buffers {
+		segment, ok := wb.metaCache.GetSegmentByID(segmentID)
+		if ok && segment.State() != commonpb.SegmentState_Dropped {
+			continue
+		}
+		wb.buffers[segmentID].release()
+		delete(wb.buffers, segmentID)
+		delete(wb.segmentSeqs, segmentID)
+		wb.rowLagTracker.Remove(segmentID)
+		dropped = append(dropped, segmentID)
+	}
+	wb.removeSegmentMappings(dropped...)
+
+	if len(dropped) > 0 {
+		log.Warn("drop buffers of segments not found or already dropped in meta",
+			zap.String("channel", wb.channelName), zap.Int64s("segmentIDs", dropped))
+	}
+	return dropped
 }
 
 func (wb *writeBufferBase) cleanupCompactedSegments() {
@@ -216,54 +1059,233 @@ func (wb *writeBufferBase) cleanupCompactedSegments() {
 	if len(targetIDs) == 0 {
 		return
 	}
-	removed := wb.metaCache.RemoveSegments(metacache.WithSegmentIDs(targetIDs...))
-	if len(removed) > 0 {
-		log.Info("remove compacted segments", zap.Int64s("removed", removed))
+	removed := wb.metaCache.RemoveSegments(metacache.WithSegmentIDs(targetIDs...))
+	for _, segmentID := range removed {
+		delete(wb.insertWatermarks, segmentID)
+		delete(wb.segmentSeqs, segmentID)
+		wb.sealedSegments.Remove(segmentID)
+	}
+	wb.removeSegmentMappings(removed...)
+	if len(removed) > 0 {
+		log.Info("remove compacted segments", zap.Int64s("removed", removed))
+	}
+}
+
+// flushSegments marks segments flushing and removes their segment id mappings, caller shall hold the write lock.
+func (wb *writeBufferBase) flushSegments(ctx context.Context, segmentIDs []int64) error {
+	// mark segment flushing if segment was growing or importing, in one update so that readers never see partial transition
+	wb.metaCache.UpdateSegments(metacache.UpdateState(commonpb.SegmentState_Flushing),
+		metacache.WithSegmentIDs(segmentIDs...),
+		metacache.WithAnyOf(
+			metacache.WithSegmentState(commonpb.SegmentState_Growing),
+			metacache.WithImporting(),
+		))
+	// flushing segments accept no new msg segment data
+	wb.removeSegmentMappings(segmentIDs...)
+
+	// buffered data of segments missing in metacache could never be synced
+	buffered := lo.Filter(segmentIDs, func(segmentID int64, _ int) bool {
+		_, ok := wb.buffers[segmentID]
+		return ok
+	})
+	if err := wb.checkSegmentsInMeta(buffered...); err != nil {
+		log.Ctx(ctx).Warn("buffered segments to flush missing in metacache", zap.String("channel", wb.channelName), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// checkSegmentsInMeta validates segments updated by preceding metacache update exist,
+// since metacache skips segments not found silently.
+func (wb *writeBufferBase) checkSegmentsInMeta(segmentIDs ...int64) error {
+	missing := lo.Filter(segmentIDs, func(segmentID int64, _ int) bool {
+		_, ok := wb.metaCache.GetSegmentByID(segmentID)
+		return !ok
+	})
+	if len(missing) > 0 {
+		return errors.Wrapf(ErrSegmentMetaUpdate, "segments %v not found in metacache", missing)
+	}
+	return nil
+}
+
+// syncSegments submits sync tasks of provided segments, returns futures of submitted tasks by segment id.
+func (wb *writeBufferBase) syncSegments(ctx context.Context, segmentIDs []int64) map[int64]*conc.Future[error] {
+	ctx, sp := wb.startSpan(ctx, "WriteBuffer-SyncSegments", attribute.Int64Slice("segmentIDs", segmentIDs))
+	defer sp.End()
+	// sync reasons only apply to tasks of this sync
+	defer func() {
+		for _, segmentID := range segmentIDs {
+			delete(wb.syncReasons, segmentID)
+		}
+	}()
+
+	if wb.syncDeduplication {
+		segmentIDs = wb.filterPendingSegments(ctx, segmentIDs)
+	}
+
+	if wb.orderedSync {
+		return wb.syncSegmentsOrdered(ctx, segmentIDs)
+	}
+
+	syncTasks := make([]syncmgr.Task, 0, len(segmentIDs))
+	for _, segmentID := range segmentIDs {
+		syncTask, err := wb.getSyncTask(ctx, segmentID)
+		if err != nil {
+			wb.handleSyncTaskError(ctx, segmentID, err)
+			continue
+		}
+		syncTasks = append(syncTasks, syncTask)
+	}
+
+	// submit urgent tasks first
+	sort.SliceStable(syncTasks, func(i, j int) bool {
+		return taskPriority(syncTasks[i]) > taskPriority(syncTasks[j])
+	})
+	futures := make(map[int64]*conc.Future[error], len(syncTasks))
+	for _, syncTask := range syncTasks {
+		// error is handled in callback, future is only returned for callers awaiting the sync
+		futures[syncTask.SegmentID()] = wb.syncMgr.SyncData(ctx, syncTask)
+	}
+	return futures
+}
+
+// handleSyncTaskError logs the failure of building sync task, only syncs of segments missing in meta are counted as dropped.
+// Data of segments failed to yield stays buffered and is synced next time.
+func (wb *writeBufferBase) handleSyncTaskError(ctx context.Context, segmentID int64, err error) {
+	if errors.Is(err, merr.ErrSegmentNotFound) {
+		log.Ctx(ctx).Warn("segment not found in meta", zap.Int64("segmentID", segmentID))
+		wb.recordDroppedSync()
+		return
 	}
+	log.Ctx(ctx).Warn("failed to build sync task", zap.Int64("segmentID", segmentID), zap.Error(err))
 }
 
-func (wb *writeBufferBase) flushSegments(ctx context.Context, segmentIDs []int64) error {
-	// mark segment flushing if segment was growing
-	wb.metaCache.UpdateSegments(metacache.UpdateState(commonpb.SegmentState_Flushing),
-		metacache.WithSegmentIDs(segmentIDs...),
-		metacache.WithSegmentState(commonpb.SegmentState_Growing))
-	// mark segment flushing if segment was importing
-	wb.metaCache.UpdateSegments(metacache.UpdateState(commonpb.SegmentState_Flushing),
-		metacache.WithSegmentIDs(segmentIDs...),
-		metacache.WithImporting())
-	return nil
+// filterPendingSegments removes segments with unfinished sync task, which stay buffered until selected
+// by sync policies again after the task finishes.
+func (wb *writeBufferBase) filterPendingSegments(ctx context.Context, segmentIDs []int64) []int64 {
+	return lo.Filter(segmentIDs, func(segmentID int64, _ int) bool {
+		if wb.pendingSyncs.Pending(segmentID) {
+			log.Ctx(ctx).Info("skip segment with pending sync task", zap.Int64("segmentID", segmentID))
+			delete(wb.syncReasons, segmentID)
+			return false
+		}
+		return true
+	})
+}
+
+// taskPriority returns the priority of sync task built by write buffer.
+func taskPriority(task syncmgr.Task) int {
+	switch t := task.(type) {
+	case *syncmgr.SyncTask:
+		return t.Priority()
+	case *syncmgr.SyncTaskV2:
+		return t.Priority()
+	default:
+		return 0
+	}
 }
 
-func (wb *writeBufferBase) syncSegments(ctx context.Context, segmentIDs []int64) {
+// syncSegmentsOrdered submits insert & delta sync tasks of segments separately,
+// delta task is submitted in background only after insert task of the same segment finishes,
+// start position of the delta task holds checkpoint until it is submitted.
+func (wb *writeBufferBase) syncSegmentsOrdered(ctx context.Context, segmentIDs []int64) map[int64]*conc.Future[error] {
+	futures := make(map[int64]*conc.Future[error], len(segmentIDs))
 	for _, segmentID := range segmentIDs {
-		syncTask := wb.getSyncTask(ctx, segmentID)
-		if syncTask == nil {
-			// segment info not found
-			log.Ctx(ctx).Warn("segment not found in meta", zap.Int64("segmentID", segmentID))
+		insertTask, deltaTask, err := wb.getOrderedSyncTasks(ctx, segmentID)
+		if err != nil {
+			wb.handleSyncTaskError(ctx, segmentID, err)
 			continue
 		}
 
-		// discard Future here, handle error in callback
-		_ = wb.syncMgr.SyncData(ctx, syncTask)
+		f := wb.syncMgr.SyncData(ctx, insertTask)
+		if deltaTask != nil {
+			f = wb.submitDeltaAfter(ctx, segmentID, f, deltaTask)
+		}
+		futures[segmentID] = f
+	}
+	return futures
+}
+
+// submitDeltaAfter submits delta task once insert task future finishes, without holding write buffer lock.
+// Delta of failed insert task is never synced, so its position keeps holding checkpoint.
+// The returned future finishes with the delta task, or with the insert task if it fails.
+func (wb *writeBufferBase) submitDeltaAfter(ctx context.Context, segmentID int64, insertFuture *conc.Future[error], deltaTask syncmgr.Task) *conc.Future[error] {
+	deltaPos := deltaTask.StartPosition()
+	wb.orderedDeltas.Add(segmentID, deltaPos)
+	submitted := make(chan *conc.Future[error], 1)
+	submit := func(_ <-chan struct{}) {
+		defer close(submitted)
+		// sync task error is returned as future value
+		err, _ := insertFuture.Await()
+		if err != nil {
+			log.Ctx(ctx).Warn("insert sync task failed, skip delta sync task", zap.Int64("segmentID", segmentID), zap.Error(err))
+			return
+		}
+		submitted <- wb.syncMgr.SyncData(ctx, deltaTask)
+		// sync manager holds checkpoint of submitted task
+		wb.orderedDeltas.Done(segmentID, deltaPos)
+	}
+	if !wb.runBackground(submit) {
+		// no goroutine shall be started once closed, submit it in place
+		submit(wb.closeCh)
 	}
+	return conc.Go(func() (error, error) {
+		deltaFuture, ok := <-submitted
+		if !ok {
+			return insertFuture.Await()
+		}
+		return deltaFuture.Await()
+	})
 }
 
 // getSegmentsToSync applies all policies to get segments list to sync.
 // **NOTE** shall be invoked within mutex protection
 func (wb *writeBufferBase) getSegmentsToSync(ts typeutil.Timestamp) []int64 {
-	buffers := lo.Values(wb.buffers)
+	buffers := lo.Map(wb.sortedSegmentIDs(), func(segmentID int64, _ int) *segmentBuffer { return wb.buffers[segmentID] })
 	segments := typeutil.NewSet[int64]()
 	for _, policy := range wb.syncPolicies {
 		result := policy.SelectSegments(buffers, ts)
 		if len(result) > 0 {
 			log.Info("SyncPolicy selects segments", zap.Int64s("segmentIDs", result), zap.String("reason", policy.Reason()))
+			for _, segmentID := range result {
+				if !segments.Contain(segmentID) {
+					wb.cpTracker.RecordSync(segmentID, policy.Reason())
+					wb.syncReasons[segmentID] = policy.Reason()
+				}
+			}
 			segments.Insert(result...)
 		}
 	}
 
-	return segments.Collect()
+	// sync tasks are submitted in id order so that flushes are reproducible
+	result := segments.Collect()
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
+
+// sortedSegmentIDs returns ids of buffered segments in ascending order.
+// **NOTE** shall be invoked within mutex protection
+func (wb *writeBufferBase) sortedSegmentIDs() []int64 {
+	segmentIDs := lo.Keys(wb.buffers)
+	sort.Slice(segmentIDs, func(i, j int) bool { return segmentIDs[i] < segmentIDs[j] })
+	return segmentIDs
+}
+
+// earliestPosition returns earliest position of buffer under its segment lock, caller shall hold the lock.
+func (wb *writeBufferBase) earliestPosition(buf *segmentBuffer) *msgpb.MsgPosition {
+	wb.segmentLocks.Lock(buf.segmentID)
+	defer wb.segmentLocks.Unlock(buf.segmentID)
+	return buf.EarliestPosition()
+}
+
+// bufferEmpty returns whether buffer is empty under its segment lock, caller shall hold the lock.
+func (wb *writeBufferBase) bufferEmpty(buf *segmentBuffer) bool {
+	wb.segmentLocks.Lock(buf.segmentID)
+	defer wb.segmentLocks.Unlock(buf.segmentID)
+	return buf.IsEmpty()
 }
 
+// getOrCreateBuffer returns buffer of provided segment, creating it if absent, caller shall hold the write lock.
 func (wb *writeBufferBase) getOrCreateBuffer(segmentID int64) *segmentBuffer {
 	buffer, ok := wb.buffers[segmentID]
 	if !ok {
@@ -273,50 +1295,210 @@ func (wb *writeBufferBase) getOrCreateBuffer(segmentID int64) *segmentBuffer {
 			// TODO avoid panic here
 			panic(err)
 		}
+		buffer.insertBuffer.pkExtractor = wb.pkExtractor
+		buffer.setLimitScale(wb.limitScale)
+		if wb.deleteIndexDir != "" {
+			buffer.deltaBuffer.index = newDeleteIndex(wb.deleteIndexDir, wb.deleteIndexMax)
+		}
+		if wb.arrowBuffering && wb.useStorageV2() {
+			buffer.insertBuffer.enableRecord(wb.storagev2Cache.ArrowSchema())
+		}
 		wb.buffers[segmentID] = buffer
 	}
 
 	return buffer
 }
 
-func (wb *writeBufferBase) yieldBuffer(segmentID int64) (*storage.InsertData, *storage.DeleteData, *TimeRange, *msgpb.MsgPosition) {
+// yieldBuffer yields buffered data of provided segment.
+// if keepDelta is true, non-empty delta data stays in buffer unless it is full or there is no insert data to sync.
+// record is the arrow record of yielded insert data if arrow native buffering enabled.
+// buffer stays untouched if pre-sync transform fails.
+func (wb *writeBufferBase) yieldBuffer(segmentID int64, keepDelta bool) (*storage.InsertData, arrow.Record, *storage.DeleteData, *TimeRange, *msgpb.MsgPosition, error) {
 	buffer, ok := wb.buffers[segmentID]
 	if !ok {
-		return nil, nil, nil, nil
+		return nil, nil, nil, nil, nil, nil
+	}
+
+	wb.pruneDeletes(buffer)
+	keepDelta = keepDelta && !buffer.insertBuffer.IsEmpty() && !buffer.deltaBuffer.IsEmpty() && !buffer.deltaBuffer.IsFull()
+	transformed, err := wb.transformBuffer(buffer, !keepDelta)
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+
+	record := buffer.insertBuffer.yieldRecord()
+	if transformed && record != nil {
+		// record was built before transform, let sync task convert transformed data
+		record.Release()
+		record = nil
+	}
+	if keepDelta {
+		start := buffer.insertBuffer.startPos
+		timeRange := buffer.insertBuffer.GetTimeRange()
+		insert, err := buffer.yieldInsert()
+		if err == nil {
+			return insert, record, nil, timeRange, start, nil
+		}
+		log.Warn("failed to reset insert buffer, yield delta data along with insert", zap.Int64("segmentID", segmentID), zap.Error(err))
 	}
 
 	// remove buffer and move it to sync manager
+	buffer.release()
 	delete(wb.buffers, segmentID)
 	start := buffer.EarliestPosition()
 	timeRange := buffer.GetTimeRange()
 	insert, delta := buffer.Yield()
 
-	return insert, delta, timeRange, start
+	return insert, record, delta, timeRange, start, nil
+}
+
+// pruneDeletes drops buffered deletes older than the earliest insert timestamp of segment if delete pruning enabled.
+func (wb *writeBufferBase) pruneDeletes(buffer *segmentBuffer) {
+	if !wb.deletePruning {
+		return
+	}
+	watermark, ok := wb.insertWatermarks[buffer.segmentID]
+	if !ok || buffer.deltaBuffer.IsEmpty() {
+		return
+	}
+	if pruned := buffer.deltaBuffer.Prune(watermark); pruned > 0 {
+		log.Info("prune deletes older than segment insert data", zap.Int64("segmentID", buffer.segmentID),
+			zap.Uint64("watermark", watermark), zap.Int("pruned", pruned))
+	}
+}
+
+// transformBuffer applies pre-sync transform to buffered data to be yielded, delta data is passed only if withDelta.
+// returns whether the transform is applied.
+func (wb *writeBufferBase) transformBuffer(buffer *segmentBuffer, withDelta bool) (bool, error) {
+	if wb.preSyncTransform == nil {
+		return false, nil
+	}
+	var delta *storage.DeleteData
+	if withDelta {
+		delta = buffer.deltaBuffer.Yield()
+	}
+	if err := wb.preSyncTransform(buffer.segmentID, buffer.insertBuffer.Yield(), delta); err != nil {
+		return false, errors.Wrapf(err, "pre-sync transform failed for segment %d", buffer.segmentID)
+	}
+	return true, nil
+}
+
+// resolveSegmentID returns the segment id to buffer data of provided msg segment id into.
+// if segment id allocator is provided, new segments are assigned with allocated ids.
+// **NOTE** shall be invoked within mutex protection
+func (wb *writeBufferBase) resolveSegmentID(msgSegmentID int64) int64 {
+	if wb.segmentIDAllocator == nil {
+		return msgSegmentID
+	}
+	if segmentID, ok := wb.allocatedSegments[msgSegmentID]; ok {
+		return segmentID
+	}
+	if _, ok := wb.metaCache.GetSegmentByID(msgSegmentID); ok {
+		return msgSegmentID
+	}
+
+	segmentID := wb.segmentIDAllocator()
+	wb.allocatedSegments[msgSegmentID] = segmentID
+	log.Info("allocate segment id for new segment", zap.Int64("msgSegmentID", msgSegmentID), zap.Int64("segmentID", segmentID))
+	return segmentID
 }
 
-// bufferInsert transform InsertMsg into bufferred InsertData and returns primary key field data for future usage.
-func (wb *writeBufferBase) bufferInsert(insertMsgs []*msgstream.InsertMsg, startPos, endPos *msgpb.MsgPosition) (map[int64][]storage.FieldData, error) {
-	insertGroups := lo.GroupBy(insertMsgs, func(msg *msgstream.InsertMsg) int64 { return msg.GetSegmentID() })
+// lookupSegmentID returns the segment id allocated for provided msg segment id without allocating new one.
+func (wb *writeBufferBase) lookupSegmentID(msgSegmentID int64) int64 {
+	if segmentID, ok := wb.allocatedSegments[msgSegmentID]; ok {
+		return segmentID
+	}
+	return msgSegmentID
+}
+
+// removeSegmentMappings removes msg segment id mappings to provided segments, caller shall hold the lock.
+// Data of the msg segment ids buffered afterwards goes to newly allocated segments.
+func (wb *writeBufferBase) removeSegmentMappings(segmentIDs ...int64) {
+	if len(wb.allocatedSegments) == 0 {
+		return
+	}
+	segments := typeutil.NewSet(segmentIDs...)
+	for msgSegmentID, segmentID := range wb.allocatedSegments {
+		if segments.Contain(segmentID) {
+			delete(wb.allocatedSegments, msgSegmentID)
+		}
+	}
+}
+
+// groupInsertMsgs groups insert msgs by segment id.
+func groupInsertMsgs(insertMsgs []*msgstream.InsertMsg) map[int64][]*msgstream.InsertMsg {
+	return lo.GroupBy(insertMsgs, func(msg *msgstream.InsertMsg) int64 { return msg.GetSegmentID() })
+}
+
+// flattenDeleteMsgs returns delete msgs of all groups ordered by group key.
+func flattenDeleteMsgs(deleteBySegment map[int64][]*msgstream.DeleteMsg) []*msgstream.DeleteMsg {
+	keys := lo.Keys(deleteBySegment)
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	var deleteMsgs []*msgstream.DeleteMsg
+	for _, key := range keys {
+		deleteMsgs = append(deleteMsgs, deleteBySegment[key]...)
+	}
+	return deleteMsgs
+}
+
+// bufferInsert transform InsertMsg grouped by segment id into bufferred InsertData and returns primary key field data for future usage.
+// Caller shall hold the write lock so that segment existence check and creation are atomic.
+func (wb *writeBufferBase) bufferInsert(insertGroups map[int64][]*msgstream.InsertMsg, startPos, endPos *msgpb.MsgPosition) (map[int64][]storage.FieldData, error) {
 	segmentPKData := make(map[int64][]storage.FieldData)
-	segmentPartition := lo.SliceToMap(insertMsgs, func(msg *msgstream.InsertMsg) (int64, int64) { return msg.GetSegmentID(), msg.GetPartitionID() })
 
-	for segmentID, msgs := range insertGroups {
+	// reject mismatched msgs before any segment is created or buffered
+	for msgSegmentID, msgs := range insertGroups {
+		msgSegmentID = wb.lookupSegmentID(msgSegmentID)
+		if wb.sealedSegments.Contain(msgSegmentID) {
+			log.Warn("reject insert msgs of sealed segment", zap.Int64("segmentID", msgSegmentID))
+			return nil, errors.Wrapf(ErrSegmentSealed, "segment %d", msgSegmentID)
+		}
+		for _, msg := range msgs {
+			if err := wb.validator.validate(msg); err != nil {
+				log.Warn("insert msg mismatches collection schema", zap.Int64("segmentID", msg.GetSegmentID()), zap.Error(err))
+				return nil, err
+			}
+		}
+	}
+
+	for msgSegmentID, msgs := range insertGroups {
+		// skip messages without any row, segment shall not be created for them
+		if !wb.keepEmptyInserts {
+			msgs = lo.Filter(msgs, func(msg *msgstream.InsertMsg, _ int) bool { return len(msg.GetTimestamps()) > 0 })
+		}
+		if len(msgs) == 0 {
+			log.Info("no row to buffer, skip segment", zap.Int64("segmentID", msgSegmentID))
+			continue
+		}
+
+		segmentID := wb.lookupSegmentID(msgSegmentID)
 		_, ok := wb.metaCache.GetSegmentByID(segmentID)
+		if !ok && wb.noAutoSegmentCreate {
+			log.Warn("segment not found and auto creation disabled", zap.Int64("segmentID", segmentID))
+			return nil, merr.WrapErrSegmentNotFound(segmentID, "auto segment creation disabled")
+		}
 		// new segment
 		if !ok {
+			segmentID = wb.resolveSegmentID(msgSegmentID)
 			wb.metaCache.AddSegment(&datapb.SegmentInfo{
 				ID:            segmentID,
-				PartitionID:   segmentPartition[segmentID],
+				PartitionID:   msgs[0].GetPartitionID(),
 				CollectionID:  wb.collectionID,
 				InsertChannel: wb.channelName,
 				StartPosition: startPos,
 				State:         commonpb.SegmentState_Growing,
 			}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() }, metacache.SetStartPosRecorded(false))
+			if wb.deletePruning {
+				wb.insertWatermarks[segmentID] = lo.Min(lo.FlatMap(msgs, func(msg *msgstream.InsertMsg, _ int) []uint64 { return msg.GetTimestamps() }))
+			}
 		}
 
 		segBuf := wb.getOrCreateBuffer(segmentID)
+		wb.touchPartition(segmentID)
 
+		rows := segBuf.insertBuffer.rows
 		pkData, err := segBuf.insertBuffer.Buffer(msgs, startPos, endPos)
+		wb.rowLagTracker.Buffered(segmentID, segBuf.insertBuffer.rows-rows)
 		if err != nil {
 			log.Warn("failed to buffer insert data", zap.Int64("segmentID", segmentID), zap.Error(err))
 			return nil, err
@@ -324,18 +1506,245 @@ func (wb *writeBufferBase) bufferInsert(insertMsgs []*msgstream.InsertMsg, start
 		segmentPKData[segmentID] = pkData
 		wb.metaCache.UpdateSegments(metacache.UpdateBufferedRows(segBuf.insertBuffer.rows),
 			metacache.WithSegmentIDs(segmentID))
+		if err := wb.checkSegmentsInMeta(segmentID); err != nil {
+			log.Warn("segment missing after buffering insert data", zap.Int64("segmentID", segmentID), zap.Error(err))
+			return nil, err
+		}
 	}
 
 	return segmentPKData, nil
 }
 
+// checkSequence checks seq is greater than the last accepted sequence of all segments in insert groups, caller shall hold the lock.
+// No segment id is allocated before the sequence is accepted.
+func (wb *writeBufferBase) checkSequence(insertGroups map[int64][]*msgstream.InsertMsg, seq uint64) error {
+	for msgSegmentID := range insertGroups {
+		segmentID := wb.lookupSegmentID(msgSegmentID)
+		if last, ok := wb.segmentSeqs[segmentID]; ok && seq <= last {
+			log.Warn("stale sequence rejected", zap.Int64("segmentID", segmentID), zap.Uint64("seq", seq), zap.Uint64("lastSeq", last))
+			return merr.WrapErrParameterInvalidMsg("sequence %d of segment %d not greater than last accepted %d", seq, segmentID, last)
+		}
+	}
+	return nil
+}
+
+// acceptSequence records seq as the last accepted sequence of all segments in insert groups, caller shall hold the lock.
+func (wb *writeBufferBase) acceptSequence(insertGroups map[int64][]*msgstream.InsertMsg, seq uint64) {
+	for msgSegmentID := range insertGroups {
+		wb.segmentSeqs[wb.lookupSegmentID(msgSegmentID)] = seq
+	}
+}
+
+// bufferRows buffers rows built by fill callback into segment buffer and updates its pk oracle, the segment must exist in metacache.
+// Caller shall hold no lock, rows are built & buffered with the read lock and the segment lock held,
+// so that segments of different stripes are buffered in parallel.
+func (wb *writeBufferBase) bufferRows(segmentID int64, fill func(builder InsertBuilder) error, startPos, endPos *msgpb.MsgPosition) error {
+	wb.mut.RLock()
+	// segment shall exist, so no segment id is allocated for it
+	segmentID = wb.lookupSegmentID(segmentID)
+	err := wb.checkRowsSegment(segmentID)
+	wb.mut.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	return wb.withSegmentBuffer(segmentID, func(segBuf *segmentBuffer) error {
+		// segment may be dropped or sealed after the check above
+		if err := wb.checkRowsSegment(segmentID); err != nil {
+			return err
+		}
+
+		builder, err := newInsertDataBuilder(wb.collSchema)
+		if err != nil {
+			return err
+		}
+		if err := fill(builder); err != nil {
+			log.Warn("failed to fill insert rows", zap.Int64("segmentID", segmentID), zap.Error(err))
+			return err
+		}
+		data, err := builder.build()
+		if err != nil {
+			return err
+		}
+		if data.GetRowNum() == 0 {
+			return nil
+		}
+
+		wb.touchPartition(segmentID)
+		rows := segBuf.insertBuffer.rows
+		pkData, err := segBuf.insertBuffer.bufferInsertData(data, startPos, endPos)
+		wb.rowLagTracker.Buffered(segmentID, segBuf.insertBuffer.rows-rows)
+		if err != nil {
+			log.Warn("failed to buffer insert rows", zap.Int64("segmentID", segmentID), zap.Error(err))
+			return err
+		}
+		wb.metaCache.UpdateSegments(metacache.UpdateBufferedRows(segBuf.insertBuffer.rows),
+			metacache.WithSegmentIDs(segmentID))
+
+		return wb.updatePKOracle(map[int64][]storage.FieldData{segmentID: {pkData}})
+	})
+}
+
+// checkRowsSegment checks rows could be buffered into provided segment, caller shall hold the lock.
+func (wb *writeBufferBase) checkRowsSegment(segmentID int64) error {
+	if wb.closed {
+		return ErrBufferClosed
+	}
+	if _, ok := wb.metaCache.GetSegmentByID(segmentID); !ok {
+		return merr.WrapErrSegmentNotFound(segmentID, "segment shall exist before buffering rows")
+	}
+	if wb.sealedSegments.Contain(segmentID) {
+		return errors.Wrapf(ErrSegmentSealed, "segment %d", segmentID)
+	}
+	return nil
+}
+
+// withSegmentBuffer invokes fn with buffer of provided segment, holding the read lock and the segment lock.
+// Buffer is created with the write lock held if absent, caller shall hold no lock.
+func (wb *writeBufferBase) withSegmentBuffer(segmentID int64, fn func(segBuf *segmentBuffer) error) error {
+	for {
+		wb.mut.RLock()
+		if wb.closed {
+			wb.mut.RUnlock()
+			return ErrBufferClosed
+		}
+		if segBuf, ok := wb.buffers[segmentID]; ok {
+			wb.segmentLocks.Lock(segmentID)
+			err := fn(segBuf)
+			wb.segmentLocks.Unlock(segmentID)
+			wb.mut.RUnlock()
+			return err
+		}
+		wb.mut.RUnlock()
+
+		// buffer could be synced & removed again before the read lock is re-acquired, so check it in loop
+		wb.mut.Lock()
+		if !wb.closed {
+			wb.getOrCreateBuffer(segmentID)
+		}
+		wb.mut.Unlock()
+	}
+}
+
+// advanceCheckpoint sets buffer last checkpoint to pos unless it is behind, caller shall hold the write lock.
+// Rows buffered in parallel may finish out of order, the latest consumed position shall be kept.
+func (wb *writeBufferBase) advanceCheckpoint(pos *msgpb.MsgPosition) {
+	if wb.checkpoint == nil || wb.cpComparator(pos, wb.checkpoint) >= 0 {
+		wb.checkpoint = pos
+	}
+}
+
+// updatePKOracle updates bloom filter of segments with buffered pk data.
+func (wb *writeBufferBase) updatePKOracle(pkData map[int64][]storage.FieldData) error {
+	for segmentID, dataList := range pkData {
+		segments := wb.metaCache.GetSegmentsBy(metacache.WithSegmentIDs(segmentID))
+		for _, segment := range segments {
+			for _, fieldData := range dataList {
+				err := segment.GetBloomFilterSet().UpdatePKRange(fieldData)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // bufferDelete buffers DeleteMsg into DeleteData.
+// If maxDeleteBatch configured, oversized batch is buffered in chunks and segment is synced after each full chunk.
 func (wb *writeBufferBase) bufferDelete(segmentID int64, pks []storage.PrimaryKey, tss []typeutil.Timestamp, startPos, endPos *msgpb.MsgPosition) error {
+	return wb.bufferDeleteTo(func() int64 { return segmentID }, pks, tss, startPos, endPos)
+}
+
+// bufferDeleteTo buffers deletes into segment returned by target, which is resolved again after each sync
+// triggered by delete batch limit, since the synced segment may accept no more deletes, e.g. l0 segment.
+func (wb *writeBufferBase) bufferDeleteTo(target func() int64, pks []storage.PrimaryKey, tss []typeutil.Timestamp, startPos, endPos *msgpb.MsgPosition) error {
+	segmentID := target()
+	for wb.maxDeleteBatch > 0 && len(pks) > wb.maxDeleteBatch {
+		segBuf := wb.getOrCreateBuffer(segmentID)
+		segBuf.deltaBuffer.Buffer(pks[:wb.maxDeleteBatch], tss[:wb.maxDeleteBatch], startPos, endPos)
+		pks, tss = pks[wb.maxDeleteBatch:], tss[wb.maxDeleteBatch:]
+
+		log.Info("delete batch exceeds limit, sync segment before buffering remaining",
+			zap.Int64("segmentID", segmentID), zap.Int("maxDeleteBatch", wb.maxDeleteBatch), zap.Int("remaining", len(pks)))
+		wb.syncSegments(context.Background(), []int64{segmentID})
+		segmentID = target()
+	}
+
 	segBuf := wb.getOrCreateBuffer(segmentID)
 	segBuf.deltaBuffer.Buffer(pks, tss, startPos, endPos)
+	wb.touchPartition(segmentID)
+	return nil
+}
+
+// touchPartition records the partition of provided segment is written, if buffered partitions are limited.
+func (wb *writeBufferBase) touchPartition(segmentID int64) {
+	if wb.partitionLRU == nil {
+		return
+	}
+	if segment, ok := wb.metaCache.GetSegmentByID(segmentID); ok {
+		wb.partitionLRU.Touch(segment.PartitionID())
+	}
+}
+
+// getOrCreateSpace gets or creates storage v2 space of provided segment, creation is retried with configured attempts.
+func (wb *writeBufferBase) getOrCreateSpace(ctx context.Context, segmentID int64, arrowSchema *arrow.Schema) (*milvus_storage.Space, error) {
+	// try at least once
+	attempts := wb.spaceCreateAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	var space *milvus_storage.Space
+	err := retry.Do(ctx, func() error {
+		var err error
+		space, err = wb.storagev2Cache.GetOrCreateSpace(segmentID, wb.spaceCreator(segmentID, wb.collSchema, arrowSchema))
+		return err
+	}, retry.Attempts(uint(attempts)), retry.Sleep(wb.spaceCreateDelay))
+	return space, err
+}
+
+// prepareSpace gets or creates storage v2 space of segment to sync, space is nil if storage v2 is not used.
+func (wb *writeBufferBase) prepareSpace(ctx context.Context, segmentID int64) (*milvus_storage.Space, error) {
+	if !wb.useStorageV2() {
+		return nil, nil
+	}
+	space, err := wb.getOrCreateSpace(ctx, segmentID, wb.storagev2Cache.ArrowSchema())
+	if err != nil {
+		// data stays buffered and is synced next time
+		log.Ctx(ctx).Warn("failed to get or create space, abort sync", zap.Int64("segmentID", segmentID), zap.Error(err))
+		wb.syncErrors.Failed(segmentID, err)
+		return nil, errors.Wrapf(err, "failed to get or create space of segment %d", segmentID)
+	}
+	return space, nil
+}
+
+// verifyFlush reads back insert binlogs written by sync task and checks they hold expected rows,
+// only storage v1 sync tasks are verified if post flush verifier configured.
+func (wb *writeBufferBase) verifyFlush(task syncmgr.Task, rows int64) error {
+	t, ok := task.(*syncmgr.SyncTask)
+	if wb.flushVerifier == nil || !ok || rows == 0 {
+		return nil
+	}
+	read, err := wb.flushVerifier(context.Background(), t.SegmentID(), t.InsertBinlogs())
+	if err != nil {
+		return errors.Wrapf(err, "failed to read back binlogs of segment %d", t.SegmentID())
+	}
+	if read != rows {
+		return merr.WrapErrServiceInternal(fmt.Sprintf("segment %d binlogs hold %d rows, %d rows synced", t.SegmentID(), read, rows))
+	}
 	return nil
 }
 
+func (wb *writeBufferBase) handleSyncFailure(err error) {
+	if wb.closing.Load() {
+		// error is recorded and returned by Close
+		log.Warn("sync task failed while closing write buffer", zap.String("channel", wb.channelName), zap.Error(err))
+		return
+	}
+	// TODO could change to unsub channel in the future
+	panic(err)
+}
+
 func SpaceCreatorFunc(segmentID int64, collSchema *schemapb.CollectionSchema, arrowSchema *arrow.Schema) func() (*milvus_storage.Space, error) {
 	return func() (*milvus_storage.Space, error) {
 		url := fmt.Sprintf("%s://%s:%s@%s/%d?endpoint_override=%s",
@@ -371,39 +1780,173 @@ func SpaceCreatorFunc(segmentID int64, collSchema *schemapb.CollectionSchema, ar
 	}
 }
 
-func (wb *writeBufferBase) getSyncTask(ctx context.Context, segmentID int64) syncmgr.Task {
+// getSyncTask yields segment buffer into sync task, a segment not found in meta cache is reported as ErrSegmentNotFound.
+func (wb *writeBufferBase) getSyncTask(ctx context.Context, segmentID int64) (syncmgr.Task, error) {
+	ctx, sp := wb.startSpan(ctx, "WriteBuffer-GetSyncTask", attribute.Int64("segmentID", segmentID))
+	defer sp.End()
+
 	log := log.Ctx(ctx).With(
 		zap.Int64("segmentID", segmentID),
 	)
 	segmentInfo, ok := wb.metaCache.GetSegmentByID(segmentID) // wb.metaCache.GetSegmentsBy(metacache.WithSegmentIDs(segmentID))
 	if !ok {
 		log.Warn("segment info not found in meta cache", zap.Int64("segmentID", segmentID))
-		return nil
+		return nil, merr.WrapErrSegmentNotFound(segmentID, "segment to sync not in meta cache")
 	}
 	var batchSize int64
-	var tsFrom, tsTo uint64
 
-	insert, delta, timeRange, startPos := wb.yieldBuffer(segmentID)
-	if timeRange != nil {
-		tsFrom, tsTo = timeRange.timestampMin, timeRange.timestampMax
+	// space is prepared before yielding so that buffered data stays untouched if it fails
+	space, err := wb.prepareSpace(ctx, segmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	// delta data of growing segment could be kept in buffer if delta flush is decoupled
+	keepDelta := wb.decoupleDeltaFlush && segmentInfo.State() == commonpb.SegmentState_Growing
+	insert, record, delta, timeRange, startPos, err := wb.yieldBuffer(segmentID, keepDelta)
+	if err != nil {
+		// data stays buffered and is synced next time
+		log.Warn("failed to yield segment buffer, abort sync", zap.Error(err))
+		return nil, errors.Wrapf(err, "failed to yield buffer of segment %d", segmentID)
 	}
 
 	actions := []metacache.SegmentAction{metacache.RollStats()}
 	if insert != nil {
 		batchSize = int64(insert.GetRowNum())
 	}
+	wb.totalFlushedRows.Add(batchSize)
 	actions = append(actions, metacache.StartSyncing(batchSize))
 	wb.metaCache.UpdateSegments(metacache.MergeSegmentAction(actions...), metacache.WithSegmentIDs(segmentID))
 
+	return wb.newSyncTask(ctx, segmentInfo, space, insert, record, delta, startPos, timeRange, batchSize, segmentInfo.State() == commonpb.SegmentState_Flushing), nil
+}
+
+// getOrderedSyncTasks yields segment buffer into separated insert & delta sync tasks.
+// deltaTask is nil if there is no need to split, in which case insertTask carries all buffered data.
+func (wb *writeBufferBase) getOrderedSyncTasks(ctx context.Context, segmentID int64) (insertTask syncmgr.Task, deltaTask syncmgr.Task, err error) {
+	segmentInfo, ok := wb.metaCache.GetSegmentByID(segmentID)
+	if !ok {
+		log.Ctx(ctx).Warn("segment info not found in meta cache", zap.Int64("segmentID", segmentID))
+		return nil, nil, merr.WrapErrSegmentNotFound(segmentID, "segment to sync not in meta cache")
+	}
+	buffer, ok := wb.buffers[segmentID]
+	if !ok || buffer.insertBuffer.IsEmpty() || buffer.deltaBuffer.IsEmpty() ||
+		(wb.decoupleDeltaFlush && segmentInfo.State() == commonpb.SegmentState_Growing) {
+		insertTask, err = wb.getSyncTask(ctx, segmentID)
+		return insertTask, nil, err
+	}
+
+	space, err := wb.prepareSpace(ctx, segmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := wb.transformBuffer(buffer, true); err != nil {
+		log.Ctx(ctx).Warn("failed to transform segment buffer, abort sync", zap.Int64("segmentID", segmentID), zap.Error(err))
+		return nil, nil, errors.Wrapf(err, "failed to transform buffer of segment %d", segmentID)
+	}
+	record := buffer.insertBuffer.yieldRecord()
+	if wb.preSyncTransform != nil && record != nil {
+		// record was built before transform, let sync task convert transformed data
+		record.Release()
+		record = nil
+	}
+	buffer.release()
+	delete(wb.buffers, segmentID)
+	// insert task holds the earliest position of buffer,
+	// so that checkpoint will not pass buffered delta before delta task submitted
+	startPos := buffer.EarliestPosition()
+	insertRange := buffer.insertBuffer.GetTimeRange()
+	deltaPos := buffer.deltaBuffer.startPos
+	deltaRange := buffer.deltaBuffer.GetTimeRange()
+	insert, delta := buffer.Yield()
+
+	batchSize := int64(insert.GetRowNum())
+	wb.totalFlushedRows.Add(batchSize)
+	// each sync task finishes syncing once
+	wb.metaCache.UpdateSegments(metacache.MergeSegmentAction(
+		metacache.RollStats(),
+		metacache.StartSyncing(batchSize),
+		metacache.StartSyncing(0),
+	), metacache.WithSegmentIDs(segmentID))
+
+	// segment shall be marked flushed after all data synced
+	isFlush := segmentInfo.State() == commonpb.SegmentState_Flushing
+	insertTask = wb.newSyncTask(ctx, segmentInfo, space, insert, record, nil, startPos, insertRange, batchSize, false)
+	deltaTask = wb.newSyncTask(ctx, segmentInfo, space, nil, nil, delta, deltaPos, deltaRange, 0, isFlush)
+	return insertTask, deltaTask, nil
+}
+
+// newSyncTask builds sync task of yielded data, space is the storage v2 space prepared by prepareSpace.
+func (wb *writeBufferBase) newSyncTask(ctx context.Context, segmentInfo *metacache.SegmentInfo, space *milvus_storage.Space,
+	insert *storage.InsertData, record arrow.Record, delta *storage.DeleteData,
+	startPos *msgpb.MsgPosition, timeRange *TimeRange, batchSize int64, isFlush bool,
+) syncmgr.Task {
+	log := log.Ctx(ctx).With(
+		zap.Int64("segmentID", segmentInfo.SegmentID()),
+	)
+	segmentID := segmentInfo.SegmentID()
+	var tsFrom, tsTo uint64
+	if timeRange != nil {
+		tsFrom, tsTo = quantizeTimeRange(timeRange.timestampMin, timeRange.timestampMax, wb.tsGranularity)
+	}
+
+	var bytes int64
+	if insert != nil {
+		bytes += int64(insert.GetMemorySize())
+	}
+	if delta != nil {
+		bytes += delta.Size()
+	}
+	reason := wb.syncReasons[segmentID]
+	// path prefix only applies to the flush task, which always consumes it
+	prefix, hasPrefix := wb.flushPrefixes[segmentID]
+	if isFlush {
+		delete(wb.flushPrefixes, segmentID)
+	}
+	traceID := traceIDFromContext(ctx)
+	createdAt := time.Now()
 	var syncTask syncmgr.Task
-	if params.Params.CommonCfg.EnableStorageV2.GetAsBool() {
-		arrowSchema := wb.storagev2Cache.ArrowSchema()
-		space, err := wb.storagev2Cache.GetOrCreateSpace(segmentID, SpaceCreatorFunc(segmentID, wb.collSchema, arrowSchema))
-		if err != nil {
-			log.Warn("failed to get or create space", zap.Error(err))
-			return nil
+	onFailure := func(err error) {
+		wb.pendingSyncs.Done(segmentID)
+		wb.syncErrors.Failed(segmentID, err)
+		wb.handleSyncFailure(err)
+	}
+	onSuccess := func() {
+		if err := wb.verifyFlush(syncTask, batchSize); err != nil {
+			// sync result is already committed in metacache, failure is only recorded and returned by Close,
+			// sync positions are kept so that checkpoint never passes the unverified data
+			log.Error("post flush verification failed", zap.Error(err))
+			wb.pendingSyncs.Done(segmentID)
+			wb.syncErrors.Failed(segmentID, err)
+			return
+		}
+		wb.pendingSyncs.Done(segmentID)
+		wb.syncErrors.Succeeded(segmentID)
+		if wb.syncPositions != nil {
+			wb.syncPositions.Done(segmentID, startPos)
 		}
+		wb.syncCount.Inc()
+		wb.syncLatencyNanos.Add(int64(time.Since(createdAt)))
+		wb.rowLagTracker.Synced(segmentID, batchSize)
+		if isFlush {
+			wb.cpTracker.MarkFlushed(segmentID)
+			wb.sealedSegments.Remove(segmentID)
+		}
+		if wb.flushEventSink != nil {
+			wb.flushEventSink(FlushEvent{
+				SegmentID:     segmentID,
+				Rows:          batchSize,
+				Bytes:         bytes,
+				TimestampFrom: tsFrom,
+				TimestampTo:   tsTo,
+				Reason:        reason,
+				Timestamp:     time.Now(),
+			})
+		}
+	}
 
+	if wb.useStorageV2() {
+		arrowSchema := wb.storagev2Cache.ArrowSchema()
 		task := syncmgr.NewSyncTaskV2().
 			WithInsertData(insert).
 			WithDeleteData(delta).
@@ -414,18 +1957,19 @@ func (wb *writeBufferBase) getSyncTask(ctx context.Context, segmentID int64) syn
 			WithStartPosition(startPos).
 			WithTimeRange(tsFrom, tsTo).
 			WithLevel(segmentInfo.Level()).
+			WithPriority(wb.levelPriority[int32(segmentInfo.Level())]).
+			WithTraceID(traceID).
 			WithCheckpoint(wb.checkpoint).
 			WithSchema(wb.collSchema).
 			WithBatchSize(batchSize).
 			WithMetaCache(wb.metaCache).
 			WithMetaWriter(wb.metaWriter).
 			WithArrowSchema(arrowSchema).
+			WithRecord(record).
 			WithSpace(space).
-			WithFailureCallback(func(err error) {
-				// TODO could change to unsub channel in the future
-				panic(err)
-			})
-		if segmentInfo.State() == commonpb.SegmentState_Flushing {
+			WithFailureCallback(onFailure).
+			WithSuccessCallback(onSuccess)
+		if isFlush {
 			task.WithFlush()
 		}
 		syncTask = task
@@ -440,36 +1984,106 @@ func (wb *writeBufferBase) getSyncTask(ctx context.Context, segmentID int64) syn
 			WithStartPosition(startPos).
 			WithTimeRange(tsFrom, tsTo).
 			WithLevel(segmentInfo.Level()).
+			WithPriority(wb.levelPriority[int32(segmentInfo.Level())]).
+			WithTraceID(traceID).
 			WithCheckpoint(wb.checkpoint).
 			WithSchema(wb.collSchema).
 			WithBatchSize(batchSize).
 			WithMetaCache(wb.metaCache).
 			WithMetaWriter(wb.metaWriter).
-			WithFailureCallback(func(err error) {
-				// TODO could change to unsub channel in the future
-				panic(err)
-			})
-		if segmentInfo.State() == commonpb.SegmentState_Flushing {
+			WithFailureCallback(onFailure).
+			WithSuccessCallback(onSuccess)
+		if isFlush {
 			task.WithFlush()
+			if hasPrefix {
+				task.WithPathPrefix(prefix)
+			}
 		}
 		syncTask = task
 	}
+	wb.pendingSyncs.Add(segmentID)
+	if wb.syncPositions != nil {
+		wb.syncPositions.Add(segmentID, startPos)
+	}
+	if wb.onSyncTask != nil {
+		wb.onSyncTask(segmentID)
+	}
 
 	return syncTask
 }
 
-func (wb *writeBufferBase) Close(drop bool) {
+func (wb *writeBufferBase) Close(drop bool) error {
+	wb.closeOnce.Do(func() {
+		close(wb.closeCh)
+	})
+	// mark closed first so that no background goroutine is started after waiting for them
+	wb.mut.Lock()
+	if wb.closed {
+		wb.mut.Unlock()
+		return nil
+	}
+	wb.closed = true
+	wb.mut.Unlock()
+	// background goroutines may acquire lock, e.g. auto sync, wait for them without holding it
+	wb.closeWg.Wait()
+
 	// sink all data and call Drop for meta writer
 	wb.mut.Lock()
 	defer wb.mut.Unlock()
+	wb.sizeWatcher.Close()
 	if !drop {
-		return
+		// buffered data is discarded
+		for _, buffer := range wb.buffers {
+			buffer.release()
+		}
+		return nil
+	}
+
+	if wb.dropWithoutFlush {
+		return wb.discardAndDrop()
+	}
+
+	// all buffered delta data shall be synced before dropping channel
+	wb.decoupleDeltaFlush = false
+	wb.closing.Store(true)
+
+	type pendingSink struct {
+		segmentID int64
+		future    *conc.Future[error]
+	}
+	var pending []pendingSink
+	var errs []error
+	// await sync task so that every failed segment is reported
+	await := func(sink pendingSink) {
+		// sync task error is returned as future value
+		taskErr, err := sink.future.Await()
+		if err == nil {
+			err = taskErr
+		}
+		if err == nil {
+			// failure found after task succeeded, e.g. post flush verification, is only recorded
+			err = wb.syncErrors.Get(sink.segmentID)
+		}
+		if err != nil {
+			log.Error("failed to sink segment buffer data", zap.String("channel", wb.channelName), zap.Int64("segmentID", sink.segmentID), zap.Error(err))
+			errs = append(errs, errors.Wrapf(err, "failed to sink segment %d", sink.segmentID))
+		}
 	}
 
-	var futures []*conc.Future[error]
-	for id := range wb.buffers {
-		syncTask := wb.getSyncTask(context.Background(), id)
-		if syncTask == nil {
+	for _, id := range wb.sortedSegmentIDs() {
+		// bound the number of sync tasks in flight
+		if wb.closeParallel > 0 && len(pending) >= wb.closeParallel {
+			await(pending[0])
+			pending = pending[1:]
+		}
+
+		syncTask, err := wb.getSyncTask(context.Background(), id)
+		if errors.Is(err, merr.ErrSegmentNotFound) {
+			continue
+		}
+		if err != nil {
+			log.Error("failed to build sink task of segment buffer", zap.String("channel", wb.channelName), zap.Int64("segmentID", id), zap.Error(err))
+			errs = append(errs, errors.Wrapf(err, "failed to sink segment %d", id))
 			continue
 		}
 		switch t := syncTask.(type) {
@@ -479,20 +2093,43 @@ func (wb *writeBufferBase) Close(drop bool) {
 			t.WithDrop()
 		}
 
-		f := wb.syncMgr.SyncData(context.Background(), syncTask)
-		futures = append(futures, f)
+		pending = append(pending, pendingSink{segmentID: id, future: wb.syncMgr.SyncData(context.Background(), syncTask)})
+	}
+	for _, sink := range pending {
+		await(sink)
+	}
+	// buffers of segments not found in meta are never synced
+	for _, buffer := range wb.buffers {
+		buffer.release()
+	}
+	if len(errs) > 0 {
+		// channel shall not be dropped when any buffered data not synced
+		return merr.Combine(errs...)
 	}
 
-	err := conc.AwaitAll(futures...)
+	err := wb.metaWriter.DropChannel(wb.channelName)
 	if err != nil {
-		log.Error("failed to sink write buffer data", zap.String("channel", wb.channelName), zap.Error(err))
-		// TODO change to remove channel in the future
-		panic(err)
+		log.Error("failed to drop channel", zap.String("channel", wb.channelName), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// discardAndDrop discards all buffered data without syncing and drops the channel, caller shall hold the lock.
+func (wb *writeBufferBase) discardAndDrop() error {
+	segmentIDs := lo.Keys(wb.buffers)
+	log.Warn("discard buffered data without sync before dropping channel",
+		zap.String("channel", wb.channelName), zap.Int64s("segmentIDs", segmentIDs))
+	for _, segmentID := range segmentIDs {
+		wb.buffers[segmentID].release()
+		delete(wb.buffers, segmentID)
+		wb.rowLagTracker.Remove(segmentID)
 	}
-	err = wb.metaWriter.DropChannel(wb.channelName)
+
+	err := wb.metaWriter.DropChannel(wb.channelName)
 	if err != nil {
 		log.Error("failed to drop channel", zap.String("channel", wb.channelName), zap.Error(err))
-		// TODO change to remove channel in the future
-		panic(err)
+		return err
 	}
+	return nil
 }
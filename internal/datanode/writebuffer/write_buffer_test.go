@@ -1,21 +1,38 @@
 package writebuffer
 
 import (
+	"bytes"
 	"context"
+	"math"
+	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/bits-and-blooms/bloom/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"go.uber.org/atomic"
 
 	"github.com/milvus-io/milvus-proto/go-api/v2/commonpb"
 	"github.com/milvus-io/milvus-proto/go-api/v2/msgpb"
 	"github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
 	"github.com/milvus-io/milvus/internal/allocator"
+	"github.com/milvus-io/milvus/internal/datanode/broker"
 	"github.com/milvus-io/milvus/internal/datanode/metacache"
 	"github.com/milvus-io/milvus/internal/datanode/syncmgr"
 	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/milvus-io/milvus/internal/querycoordv2/params"
+	"github.com/milvus-io/milvus/internal/storage"
 	"github.com/milvus-io/milvus/pkg/common"
+	"github.com/milvus-io/milvus/pkg/util/conc"
+	"github.com/milvus-io/milvus/pkg/util/merr"
 	"github.com/milvus-io/milvus/pkg/util/paramtable"
+	"github.com/milvus-io/milvus/pkg/util/tsoutil"
+	"github.com/milvus-io/milvus/pkg/util/typeutil"
 )
 
 type WriteBufferSuite struct {
@@ -111,6 +128,19 @@ func (s *WriteBufferSuite) TestFlushSegments() {
 	s.NoError(err)
 }
 
+func (s *WriteBufferSuite) TestFlushSegmentsMissingInMeta() {
+	s.metacache.EXPECT().UpdateSegments(mock.Anything, mock.Anything, mock.Anything).Return()
+	s.metacache.EXPECT().GetSegmentByID(int64(1001)).Return(nil, false)
+	wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+	s.fillSegmentBuffer(wb, 1001)
+
+	// flush of segment not buffered does not check metacache
+	s.NoError(wb.FlushSegments(context.Background(), []int64{1002}))
+
+	err := wb.FlushSegments(context.Background(), []int64{1001, 1002})
+	s.ErrorIs(err, ErrSegmentMetaUpdate)
+}
+
 func (s *WriteBufferSuite) TestGetCheckpoint() {
 	s.Run("use_consume_cp", func() {
 		s.wb.checkpoint = &msgpb.MsgPosition{
@@ -258,6 +288,1253 @@ func (s *WriteBufferSuite) TestGetCheckpoint() {
 	})
 }
 
+func (s *WriteBufferSuite) fillSegmentBuffer(wb *writeBufferBase, segmentID int64) {
+	buf := wb.getOrCreateBuffer(segmentID)
+	buf.insertBuffer.UpdateStatistics(10, 1024, TimeRange{timestampMin: 100, timestampMax: 200},
+		&msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	buf.deltaBuffer.Buffer([]storage.PrimaryKey{storage.NewInt64PrimaryKey(1)}, []typeutil.Timestamp{150},
+		&msgpb.MsgPosition{Timestamp: 150}, &msgpb.MsgPosition{Timestamp: 200})
+}
+
+func (s *WriteBufferSuite) TestCoupledDeltaFlush() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	segmentID := int64(1001)
+	seg := metacache.NewSegmentInfo(&datapb.SegmentInfo{ID: segmentID, State: commonpb.SegmentState_Growing}, metacache.NewBloomFilterSet())
+	s.metacache.EXPECT().GetSegmentByID(segmentID).Return(seg, true)
+	s.metacache.EXPECT().UpdateSegments(mock.Anything, mock.Anything).Return()
+
+	s.Run("coupled", func() {
+		wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+		s.fillSegmentBuffer(wb, segmentID)
+
+		task, err := wb.getSyncTask(context.Background(), segmentID)
+		s.NoError(err)
+		s.NotNil(task)
+		// delta data is yielded along with insert data
+		s.False(wb.HasSegment(segmentID))
+	})
+
+	s.Run("decoupled", func() {
+		option := &writeBufferOption{}
+		WithCoupledDeltaFlush(false)(option)
+		wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, option)
+		s.fillSegmentBuffer(wb, segmentID)
+
+		task, err := wb.getSyncTask(context.Background(), segmentID)
+		s.NoError(err)
+		s.NotNil(task)
+		// delta data stays in buffer
+		s.Require().True(wb.HasSegment(segmentID))
+		buf := wb.buffers[segmentID]
+		s.True(buf.insertBuffer.IsEmpty())
+		s.False(buf.deltaBuffer.IsEmpty())
+		s.EqualValues(150, buf.EarliestPosition().GetTimestamp())
+
+		// delta data is yielded when there is no insert data left
+		task, err = wb.getSyncTask(context.Background(), segmentID)
+		s.NoError(err)
+		s.NotNil(task)
+		s.False(wb.HasSegment(segmentID))
+	})
+}
+
+func (s *WriteBufferSuite) TestNilSchema() {
+	metaCache := metacache.NewMockMetaCache(s.T())
+	metaCache.EXPECT().Schema().Return(nil)
+
+	_, err := NewWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, WithDeletePolicy(DeletePolicyBFPkOracle))
+	s.ErrorIs(err, ErrNilSchema)
+}
+
+func (s *WriteBufferSuite) TestClosed() {
+	wb, err := NewWriteBuffer(s.channelName, s.metacache, nil, s.syncMgr, WithDeletePolicy(DeletePolicyBFPkOracle))
+	s.Require().NoError(err)
+
+	wb.Close(false)
+
+	err = wb.BufferData(nil, nil, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	s.ErrorIs(err, ErrBufferClosed)
+
+	err = wb.FlushSegments(context.Background(), []int64{1001})
+	s.ErrorIs(err, ErrBufferClosed)
+
+	_, err = wb.(*bfWriteBuffer).triggerSync(context.Background())
+	s.ErrorIs(err, ErrBufferClosed)
+
+	// close again shall be no-op
+	s.NotPanics(func() {
+		wb.Close(true)
+	})
+}
+
+func (s *WriteBufferSuite) TestLastCheckpointAdvanceCause() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	policy := wrapSelectSegmentFuncPolicy(func(_ []*segmentBuffer, _ typeutil.Timestamp) []int64 {
+		return []int64{2}
+	}, "test policy")
+	wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{
+		syncPolicies: []SyncPolicy{policy},
+	})
+	wb.checkpoint = &msgpb.MsgPosition{Timestamp: 1000}
+
+	buf1, err := newSegmentBuffer(2, s.collSchema)
+	s.Require().NoError(err)
+	buf1.insertBuffer.startPos = &msgpb.MsgPosition{Timestamp: 400}
+	buf2, err := newSegmentBuffer(3, s.collSchema)
+	s.Require().NoError(err)
+	buf2.insertBuffer.startPos = &msgpb.MsgPosition{Timestamp: 550}
+	wb.buffers[2] = buf1
+	wb.buffers[3] = buf2
+
+	seg := metacache.NewSegmentInfo(&datapb.SegmentInfo{ID: 2}, metacache.NewBloomFilterSet())
+	s.metacache.EXPECT().GetSegmentByID(int64(2)).Return(seg, true)
+	s.metacache.EXPECT().UpdateSegments(mock.Anything, mock.Anything).Return()
+	s.syncMgr.EXPECT().GetEarliestPosition(s.channelName).Return(0, nil)
+	s.syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).Return(nil)
+
+	s.EqualValues(400, wb.GetCheckpoint().GetTimestamp())
+	segmentID, reason := wb.LastCheckpointAdvanceCause()
+	s.EqualValues(0, segmentID)
+	s.Empty(reason)
+
+	wb.mut.Lock()
+	_, err = wb.triggerSync(context.Background())
+	wb.mut.Unlock()
+	s.Require().NoError(err)
+
+	// cause is up to date without polling checkpoint
+	segmentID, reason = wb.LastCheckpointAdvanceCause()
+	s.EqualValues(2, segmentID)
+	s.Equal("test policy", reason)
+	// reading cause does not observe checkpoint
+	s.EqualValues(400, wb.cpTracker.lastPosition.GetTimestamp())
+	s.EqualValues(550, wb.BufferStats().Checkpoint.GetTimestamp())
+	s.EqualValues(400, wb.cpTracker.lastPosition.GetTimestamp())
+	s.EqualValues(550, wb.GetCheckpoint().GetTimestamp())
+	segmentID, reason = wb.LastCheckpointAdvanceCause()
+	s.EqualValues(2, segmentID)
+	s.Equal("test policy", reason)
+}
+
+func (s *WriteBufferSuite) TestOrderedSync() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	segmentID := int64(1001)
+	seg := metacache.NewSegmentInfo(&datapb.SegmentInfo{ID: segmentID, State: commonpb.SegmentState_Growing}, metacache.NewBloomFilterSet())
+	s.metacache.EXPECT().GetSegmentByID(segmentID).Return(seg, true)
+	s.metacache.EXPECT().UpdateSegments(mock.Anything, mock.Anything).Return().Maybe()
+
+	option := &writeBufferOption{}
+	WithOrderedSync()(option)
+	wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, option)
+	s.fillSegmentBuffer(wb, segmentID)
+
+	var mut sync.Mutex
+	var submitted []syncmgr.Task
+	release := make(chan struct{})
+	s.syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, task syncmgr.Task) *conc.Future[error] {
+		mut.Lock()
+		defer mut.Unlock()
+		submitted = append(submitted, task)
+		if len(submitted) == 1 {
+			// insert task blocks until released
+			return conc.Go(func() (error, error) {
+				<-release
+				return nil, nil
+			})
+		}
+		return conc.Go(func() (error, error) { return nil, nil })
+	})
+	submittedCount := func() int {
+		mut.Lock()
+		defer mut.Unlock()
+		return len(submitted)
+	}
+
+	// sync returns without waiting for insert task
+	wb.mut.Lock()
+	wb.syncSegments(context.Background(), []int64{segmentID})
+	wb.mut.Unlock()
+	s.Equal(1, submittedCount())
+	// delta task shall not be submitted before insert task finishes
+	s.Never(func() bool { return submittedCount() > 1 }, 100*time.Millisecond, 10*time.Millisecond)
+	// pending delta holds checkpoint
+	s.EqualValues(150, wb.orderedDeltas.Earliest()[segmentID].GetTimestamp())
+
+	close(release)
+	s.Eventually(func() bool { return submittedCount() == 2 }, time.Second, 10*time.Millisecond)
+	s.Eventually(func() bool { return len(wb.orderedDeltas.Earliest()) == 0 }, time.Second, 10*time.Millisecond)
+
+	s.EqualValues(100, submitted[0].StartPosition().GetTimestamp())
+	s.EqualValues(150, submitted[1].StartPosition().GetTimestamp())
+	s.False(wb.HasSegment(segmentID))
+}
+
+func (s *WriteBufferSuite) TestFlushSegmentsAtomic() {
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	metaCache.AddSegment(&datapb.SegmentInfo{ID: 1001, State: commonpb.SegmentState_Growing},
+		func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	metaCache.AddSegment(&datapb.SegmentInfo{ID: 1002, State: commonpb.SegmentState_Importing},
+		func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	metaCache.UpdateSegments(metacache.UpdateImporting(true), metacache.WithSegmentIDs(1002))
+
+	wb, err := NewWriteBuffer(s.channelName, metaCache, nil, s.syncMgr, WithDeletePolicy(DeletePolicyBFPkOracle))
+	s.Require().NoError(err)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	var inconsistent atomic.Bool
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				// both segments shall transit together
+				flushing := metaCache.GetSegmentIDsBy(metacache.WithSegmentState(commonpb.SegmentState_Flushing))
+				if len(flushing) != 0 && len(flushing) != 2 {
+					inconsistent.Store(true)
+				}
+			}
+		}()
+	}
+
+	err = wb.FlushSegments(context.Background(), []int64{1001, 1002})
+	s.NoError(err)
+	close(done)
+	wg.Wait()
+
+	s.False(inconsistent.Load())
+	s.ElementsMatch([]int64{1001, 1002}, metaCache.GetSegmentIDsBy(metacache.WithSegmentState(commonpb.SegmentState_Flushing)))
+}
+
+func (s *WriteBufferSuite) TestFlushSegmentsToPrefix() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	for _, segmentID := range []int64{1001, 1002} {
+		metaCache.AddSegment(&datapb.SegmentInfo{ID: segmentID, State: commonpb.SegmentState_Growing},
+			func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	}
+	wb := newWriteBufferBase(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{})
+	s.fillSegmentBuffer(wb, 1001)
+	s.fillSegmentBuffer(wb, 1002)
+
+	err := wb.FlushSegmentsToPrefix(context.Background(), []int64{1001}, "cold-bucket")
+	s.Require().NoError(err)
+
+	syncTask, err := wb.getSyncTask(context.Background(), 1001)
+	s.Require().NoError(err)
+	task, ok := syncTask.(*syncmgr.SyncTask)
+	s.Require().True(ok)
+	s.Equal("cold-bucket", task.PathPrefix())
+	// prefix is consumed by the flush task
+	s.NotContains(wb.flushPrefixes, int64(1001))
+
+	syncTask, err = wb.getSyncTask(context.Background(), 1002)
+	s.Require().NoError(err)
+	task, ok = syncTask.(*syncmgr.SyncTask)
+	s.Require().True(ok)
+	s.Empty(task.PathPrefix())
+
+	s.Run("ordered_sync", func() {
+		metaCache.AddSegment(&datapb.SegmentInfo{ID: 1003, State: commonpb.SegmentState_Growing},
+			func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+		wb := newWriteBufferBase(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{orderedSync: true})
+		s.fillSegmentBuffer(wb, 1003)
+		s.Require().NoError(wb.FlushSegmentsToPrefix(context.Background(), []int64{1003}, "cold-bucket"))
+
+		insertTask, deltaTask, err := wb.getOrderedSyncTasks(context.Background(), 1003)
+		s.Require().NoError(err)
+		// only the flush task is written under prefix
+		s.Empty(insertTask.(*syncmgr.SyncTask).PathPrefix())
+		s.Equal("cold-bucket", deltaTask.(*syncmgr.SyncTask).PathPrefix())
+		s.NotContains(wb.flushPrefixes, int64(1003))
+	})
+
+	s.Run("storage_v2", func() {
+		wb := newWriteBufferBase(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{binlogFormatVersion: BinlogFormatV2})
+		err := wb.FlushSegmentsToPrefix(context.Background(), []int64{1001}, "cold-bucket")
+		s.ErrorIs(err, merr.ErrParameterInvalid)
+		s.Empty(wb.flushPrefixes)
+	})
+}
+
+func (s *WriteBufferSuite) TestTimeRangeGranularity() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	segmentID := int64(1001)
+	seg := metacache.NewSegmentInfo(&datapb.SegmentInfo{ID: segmentID, State: commonpb.SegmentState_Growing}, metacache.NewBloomFilterSet())
+	s.metacache.EXPECT().GetSegmentByID(segmentID).Return(seg, true)
+	s.metacache.EXPECT().UpdateSegments(mock.Anything, mock.Anything).Return()
+
+	tsFrom, tsTo := tsoutil.ComposeTS(1500, 3), tsoutil.ComposeTS(2300, 7)
+	getTimeRange := func(wb *writeBufferBase) (uint64, uint64) {
+		buf := wb.getOrCreateBuffer(segmentID)
+		buf.insertBuffer.UpdateStatistics(10, 1024, TimeRange{timestampMin: tsFrom, timestampMax: tsTo},
+			&msgpb.MsgPosition{Timestamp: tsFrom}, &msgpb.MsgPosition{Timestamp: tsTo})
+		syncTask, err := wb.getSyncTask(context.Background(), segmentID)
+		s.Require().NoError(err)
+		task, ok := syncTask.(*syncmgr.SyncTask)
+		s.Require().True(ok)
+		return task.TimeRange()
+	}
+
+	s.Run("quantized", func() {
+		option := &writeBufferOption{}
+		WithTimeRangeGranularity(time.Second)(option)
+		wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, option)
+
+		from, to := getTimeRange(wb)
+		s.Equal(tsoutil.ComposeTS(1000, 0), from)
+		s.Equal(tsoutil.ComposeTS(3000, 0)-1, to)
+	})
+
+	s.Run("disabled", func() {
+		wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+
+		from, to := getTimeRange(wb)
+		s.Equal(tsFrom, from)
+		s.Equal(tsTo, to)
+	})
+}
+
+func (s *WriteBufferSuite) TestMaxDeleteBatch() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	segmentID := int64(1001)
+	seg := metacache.NewSegmentInfo(&datapb.SegmentInfo{ID: segmentID, State: commonpb.SegmentState_Growing}, metacache.NewBloomFilterSet())
+	s.metacache.EXPECT().GetSegmentByID(segmentID).Return(seg, true)
+	s.metacache.EXPECT().UpdateSegments(mock.Anything, mock.Anything).Return()
+	s.syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).Return(conc.Go(func() (error, error) { return nil, nil })).Times(2)
+
+	option := &writeBufferOption{}
+	WithMaxDeleteBatch(10)(option)
+	wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, option)
+
+	pks := lo.RepeatBy(25, func(idx int) storage.PrimaryKey { return storage.NewInt64PrimaryKey(int64(idx)) })
+	tss := lo.RepeatBy(25, func(idx int) typeutil.Timestamp { return typeutil.Timestamp(100 + idx) })
+	err := wb.bufferDelete(segmentID, pks, tss, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	s.NoError(err)
+
+	// two full chunks synced, remaining pks stay in buffer
+	s.Require().True(wb.HasSegment(segmentID))
+	s.EqualValues(5, wb.buffers[segmentID].deltaBuffer.rows)
+}
+
+func (s *WriteBufferSuite) TestTopSegmentsByMemory() {
+	wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+	for segmentID, size := range map[int64]int64{1001: 512, 1002: 4096, 1003: 1024, 1004: 2048} {
+		buf := wb.getOrCreateBuffer(segmentID)
+		buf.insertBuffer.UpdateStatistics(10, size, TimeRange{timestampMin: 100, timestampMax: 200},
+			&msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	}
+
+	top := wb.TopSegmentsByMemory(3)
+	s.Equal([]int64{1002, 1004, 1003}, lo.Map(top, func(stats SegmentBufferStats, _ int) int64 { return stats.SegmentID }))
+	s.EqualValues(4096, top[0].MemorySize)
+	s.EqualValues(10, top[0].InsertRows)
+
+	s.Len(wb.TopSegmentsByMemory(10), 4)
+	s.Empty(wb.TopSegmentsByMemory(0))
+}
+
+func (s *WriteBufferSuite) TestBinlogFormatVersion() {
+	s.Run("pinned_v1", func() {
+		// pinned format overrides storage v2 config
+		params.Params.CommonCfg.EnableStorageV2.SwapTempValue("true")
+		defer params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+
+		segmentID := int64(1001)
+		seg := metacache.NewSegmentInfo(&datapb.SegmentInfo{ID: segmentID, State: commonpb.SegmentState_Growing}, metacache.NewBloomFilterSet())
+		s.metacache.EXPECT().GetSegmentByID(segmentID).Return(seg, true)
+		s.metacache.EXPECT().UpdateSegments(mock.Anything, mock.Anything).Return()
+
+		wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{binlogFormatVersion: BinlogFormatV1})
+		s.fillSegmentBuffer(wb, segmentID)
+
+		task, err := wb.getSyncTask(context.Background(), segmentID)
+		s.NoError(err)
+		s.IsType(&syncmgr.SyncTask{}, task)
+	})
+
+	s.Run("unsupported_version", func() {
+		_, err := NewWriteBuffer(s.channelName, s.metacache, nil, s.syncMgr, WithBinlogFormatVersion(3))
+		s.ErrorIs(err, merr.ErrParameterInvalid)
+	})
+}
+
+func (s *WriteBufferSuite) TestClearFlushTimestamp() {
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	metaCache.AddSegment(&datapb.SegmentInfo{ID: 1001, State: commonpb.SegmentState_Growing, Level: datapb.SegmentLevel_L0},
+		func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	wb := newWriteBufferBase(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{})
+	s.fillSegmentBuffer(wb, 1001)
+
+	wb.SetFlushTimestamp(150)
+	s.Contains(wb.getSegmentsToSync(200), int64(1001))
+
+	wb.ClearFlushTimestamp()
+	s.EqualValues(nonFlushTS, wb.GetFlushTimestamp())
+	s.NotContains(wb.getSegmentsToSync(200), int64(1001))
+}
+
+func (s *WriteBufferSuite) TestReconcile() {
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	for _, segmentID := range []int64{1001, 1002} {
+		metaCache.AddSegment(&datapb.SegmentInfo{ID: segmentID, State: commonpb.SegmentState_Growing},
+			func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	}
+	wb := newWriteBufferBase(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{})
+	for _, segmentID := range []int64{1001, 1002, 1003} {
+		s.fillSegmentBuffer(wb, segmentID)
+	}
+
+	metaCache.UpdateSegments(metacache.UpdateState(commonpb.SegmentState_Dropped), metacache.WithSegmentIDs(1002))
+
+	dropped := wb.Reconcile()
+	s.ElementsMatch([]int64{1002, 1003}, dropped)
+	s.True(wb.HasSegment(1001))
+	s.False(wb.HasSegment(1002))
+	s.False(wb.HasSegment(1003))
+
+	s.Empty(wb.Reconcile())
+}
+
+func (s *WriteBufferSuite) TestReconcileKeepsFlushedDeletes() {
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	metaCache.AddSegment(&datapb.SegmentInfo{ID: 1001, State: commonpb.SegmentState_Flushed},
+		func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	wb := newWriteBufferBase(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{})
+
+	// delete routed to flushed segment after its flush
+	s.Require().NoError(wb.bufferDelete(1001, []storage.PrimaryKey{storage.NewInt64PrimaryKey(1)}, []uint64{200},
+		&msgpb.MsgPosition{Timestamp: 200}, &msgpb.MsgPosition{Timestamp: 300}))
+
+	s.Empty(wb.Reconcile())
+	s.Require().True(wb.HasSegment(1001))
+	s.EqualValues(1, wb.buffers[1001].deltaBuffer.buffer.RowCount)
+}
+
+func (s *WriteBufferSuite) TestDroppedSyncCount() {
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+
+	s.Run("normal_sync", func() {
+		wb := newWriteBufferBase(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{})
+		s.fillSegmentBuffer(wb, 1001)
+		s.EqualValues(0, wb.DroppedSyncCount())
+
+		wb.syncSegments(context.Background(), []int64{1001})
+		s.EqualValues(1, wb.DroppedSyncCount())
+	})
+
+	s.Run("ordered_sync", func() {
+		wb := newWriteBufferBase(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{orderedSync: true})
+		s.fillSegmentBuffer(wb, 1001)
+		s.fillSegmentBuffer(wb, 1002)
+
+		wb.syncSegments(context.Background(), []int64{1001, 1002})
+		s.EqualValues(2, wb.DroppedSyncCount())
+	})
+}
+
+func (s *WriteBufferSuite) TestCloseDropPartialFailure() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	s.metacache.EXPECT().GetSegmentByID(mock.Anything).RunAndReturn(func(segmentID int64, _ ...metacache.SegmentFilter) (*metacache.SegmentInfo, bool) {
+		return metacache.NewSegmentInfo(&datapb.SegmentInfo{ID: segmentID, State: commonpb.SegmentState_Growing}, metacache.NewBloomFilterSet()), true
+	})
+	s.metacache.EXPECT().UpdateSegments(mock.Anything, mock.Anything).Return().Maybe()
+
+	newWriteBuffer := func(mockBroker *broker.MockBroker, failed ...int64) *writeBufferBase {
+		syncMgr := syncmgr.NewMockSyncManager(s.T())
+		syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, task syncmgr.Task) *conc.Future[error] {
+			return conc.Go(func() (error, error) {
+				if lo.Contains(failed, task.SegmentID()) {
+					return merr.WrapErrServiceInternal("mocked"), nil
+				}
+				return nil, nil
+			})
+		})
+		wb := newWriteBufferBase(s.channelName, s.metacache, nil, syncMgr, &writeBufferOption{
+			metaWriter: syncmgr.BrokerMetaWriter(mockBroker),
+		})
+		for _, segmentID := range []int64{1001, 1002, 1003} {
+			s.fillSegmentBuffer(wb, segmentID)
+		}
+		return wb
+	}
+
+	s.Run("partial_failure", func() {
+		// channel shall not be dropped
+		wb := newWriteBuffer(broker.NewMockBroker(s.T()), 1002, 1003)
+
+		err := wb.Close(true)
+		s.ErrorIs(err, merr.ErrServiceInternal)
+		s.Contains(err.Error(), "segment 1002")
+		s.Contains(err.Error(), "segment 1003")
+		s.NotContains(err.Error(), "segment 1001")
+	})
+
+	s.Run("build_task_failure", func() {
+		// channel shall not be dropped when buffer of any segment could not be yielded
+		wb := newWriteBuffer(broker.NewMockBroker(s.T()))
+		wb.preSyncTransform = func(segmentID int64, _ *storage.InsertData, _ *storage.DeleteData) error {
+			if segmentID == 1002 {
+				return merr.WrapErrServiceInternal("mocked transform")
+			}
+			return nil
+		}
+
+		err := wb.Close(true)
+		s.ErrorIs(err, merr.ErrServiceInternal)
+		s.Contains(err.Error(), "segment 1002")
+		s.EqualValues(0, wb.DroppedSyncCount())
+	})
+
+	s.Run("all_succeeded", func() {
+		mockBroker := broker.NewMockBroker(s.T())
+		mockBroker.EXPECT().DropVirtualChannel(mock.Anything, mock.Anything).Return(&datapb.DropVirtualChannelResponse{Status: merr.Success()}, nil).Once()
+		wb := newWriteBuffer(mockBroker)
+
+		s.NoError(wb.Close(true))
+	})
+}
+
+func (s *WriteBufferSuite) TestSyncFailureWhileClosing() {
+	wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+	s.Panics(func() {
+		wb.handleSyncFailure(merr.WrapErrServiceInternal("mocked"))
+	})
+
+	// failure of sink task is returned by Close
+	wb.closing.Store(true)
+	s.NotPanics(func() {
+		wb.handleSyncFailure(merr.WrapErrServiceInternal("mocked"))
+	})
+}
+
+func (s *WriteBufferSuite) TestDropWithoutFlush() {
+	mockBroker := broker.NewMockBroker(s.T())
+	mockBroker.EXPECT().DropVirtualChannel(mock.Anything, mock.Anything).Return(&datapb.DropVirtualChannelResponse{Status: merr.Success()}, nil).Once()
+	// no sync task expected, mock sync manager fails on any SyncData call
+	syncMgr := syncmgr.NewMockSyncManager(s.T())
+	option := &writeBufferOption{metaWriter: syncmgr.BrokerMetaWriter(mockBroker)}
+	WithDropWithoutFlush()(option)
+	wb := newWriteBufferBase(s.channelName, s.metacache, nil, syncMgr, option)
+	for _, segmentID := range []int64{1001, 1002} {
+		s.fillSegmentBuffer(wb, segmentID)
+	}
+
+	s.NoError(wb.Close(true))
+	s.False(wb.HasSegment(1001))
+	s.False(wb.HasSegment(1002))
+}
+
+func (s *WriteBufferSuite) TestCloseParallelism() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	s.metacache.EXPECT().GetSegmentByID(mock.Anything).RunAndReturn(func(segmentID int64, _ ...metacache.SegmentFilter) (*metacache.SegmentInfo, bool) {
+		return metacache.NewSegmentInfo(&datapb.SegmentInfo{ID: segmentID, State: commonpb.SegmentState_Growing}, metacache.NewBloomFilterSet()), true
+	})
+	s.metacache.EXPECT().UpdateSegments(mock.Anything, mock.Anything).Return().Maybe()
+
+	mockBroker := broker.NewMockBroker(s.T())
+	mockBroker.EXPECT().DropVirtualChannel(mock.Anything, mock.Anything).Return(&datapb.DropVirtualChannelResponse{Status: merr.Success()}, nil).Once()
+
+	var inflight, maxInflight, submitted atomic.Int32
+	syncMgr := syncmgr.NewMockSyncManager(s.T())
+	syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, _ syncmgr.Task) *conc.Future[error] {
+		submitted.Inc()
+		current := inflight.Inc()
+		for {
+			last := maxInflight.Load()
+			if current <= last || maxInflight.CompareAndSwap(last, current) {
+				break
+			}
+		}
+		return conc.Go(func() (error, error) {
+			time.Sleep(10 * time.Millisecond)
+			inflight.Dec()
+			return nil, nil
+		})
+	})
+
+	option := &writeBufferOption{metaWriter: syncmgr.BrokerMetaWriter(mockBroker)}
+	WithCloseParallelism(2)(option)
+	wb := newWriteBufferBase(s.channelName, s.metacache, nil, syncMgr, option)
+	for segmentID := int64(1001); segmentID <= 1006; segmentID++ {
+		s.fillSegmentBuffer(wb, segmentID)
+	}
+
+	s.NoError(wb.Close(true))
+	s.EqualValues(6, submitted.Load())
+	s.LessOrEqual(maxInflight.Load(), int32(2))
+}
+
+func (s *WriteBufferSuite) TestSortedSyncOrder() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	s.metacache.EXPECT().GetSegmentByID(mock.Anything).RunAndReturn(func(segmentID int64, _ ...metacache.SegmentFilter) (*metacache.SegmentInfo, bool) {
+		return metacache.NewSegmentInfo(&datapb.SegmentInfo{ID: segmentID, State: commonpb.SegmentState_Growing}, metacache.NewBloomFilterSet()), true
+	})
+	s.metacache.EXPECT().UpdateSegments(mock.Anything, mock.Anything).Return().Maybe()
+	segmentIDs := []int64{1005, 1002, 1006, 1001, 1004, 1003}
+
+	newWriteBuffer := func(option *writeBufferOption) (*writeBufferBase, *[]int64) {
+		var order []int64
+		syncMgr := syncmgr.NewMockSyncManager(s.T())
+		syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, task syncmgr.Task) *conc.Future[error] {
+			order = append(order, task.SegmentID())
+			return conc.Go(func() (error, error) { return nil, nil })
+		})
+		wb := newWriteBufferBase(s.channelName, s.metacache, nil, syncMgr, option)
+		for _, segmentID := range segmentIDs {
+			s.fillSegmentBuffer(wb, segmentID)
+		}
+		return wb, &order
+	}
+
+	s.Run("trigger_sync", func() {
+		// policy selecting segments in buffer order
+		option := &writeBufferOption{syncPolicies: []SyncPolicy{wrapSelectSegmentFuncPolicy(func(buffers []*segmentBuffer, _ uint64) []int64 {
+			return lo.Map(buffers, func(buf *segmentBuffer, _ int) int64 { return buf.segmentID })
+		}, "all")}}
+		wb, order := newWriteBuffer(option)
+		wb.checkpoint = &msgpb.MsgPosition{Timestamp: 200}
+
+		wb.mut.Lock()
+		_, err := wb.triggerSync(context.Background())
+		wb.mut.Unlock()
+		s.NoError(err)
+		s.Equal([]int64{1001, 1002, 1003, 1004, 1005, 1006}, *order)
+	})
+
+	s.Run("close", func() {
+		mockBroker := broker.NewMockBroker(s.T())
+		mockBroker.EXPECT().DropVirtualChannel(mock.Anything, mock.Anything).Return(&datapb.DropVirtualChannelResponse{Status: merr.Success()}, nil).Once()
+		wb, order := newWriteBuffer(&writeBufferOption{metaWriter: syncmgr.BrokerMetaWriter(mockBroker)})
+
+		s.NoError(wb.Close(true))
+		s.Equal([]int64{1001, 1002, 1003, 1004, 1005, 1006}, *order)
+	})
+}
+
+func (s *WriteBufferSuite) TestLevelPriority() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	s.metacache.EXPECT().GetSegmentByID(mock.Anything).RunAndReturn(func(segmentID int64, _ ...metacache.SegmentFilter) (*metacache.SegmentInfo, bool) {
+		level := datapb.SegmentLevel_L1
+		if segmentID == 1002 {
+			level = datapb.SegmentLevel_L0
+		}
+		return metacache.NewSegmentInfo(&datapb.SegmentInfo{ID: segmentID, State: commonpb.SegmentState_Growing, Level: level}, metacache.NewBloomFilterSet()), true
+	})
+	s.metacache.EXPECT().UpdateSegments(mock.Anything, mock.Anything).Return().Maybe()
+
+	syncOrder := func(opts ...WriteBufferOption) ([]int64, map[int64]int) {
+		var order []int64
+		priorities := make(map[int64]int)
+		syncMgr := syncmgr.NewMockSyncManager(s.T())
+		syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, task syncmgr.Task) *conc.Future[error] {
+			order = append(order, task.SegmentID())
+			priorities[task.SegmentID()] = task.(*syncmgr.SyncTask).Priority()
+			return conc.Go(func() (error, error) { return nil, nil })
+		})
+		option := defaultWBOption(s.metacache)
+		for _, opt := range opts {
+			opt(option)
+		}
+		wb := newWriteBufferBase(s.channelName, s.metacache, nil, syncMgr, option)
+		s.fillSegmentBuffer(wb, 1001)
+		s.fillSegmentBuffer(wb, 1002)
+
+		wb.mut.Lock()
+		wb.syncSegments(context.Background(), []int64{1001, 1002})
+		wb.mut.Unlock()
+		return order, priorities
+	}
+
+	s.Run("default", func() {
+		order, priorities := syncOrder()
+		s.Greater(priorities[1002], priorities[1001])
+		s.Equal([]int64{1002, 1001}, order)
+	})
+
+	s.Run("configured", func() {
+		order, priorities := syncOrder(WithLevelPriority(map[int32]int{int32(datapb.SegmentLevel_L1): 2}))
+		s.Greater(priorities[1001], priorities[1002])
+		s.Equal([]int64{1001, 1002}, order)
+	})
+}
+
+func (s *WriteBufferSuite) TestFlushSegmentsStrict() {
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	metaCache.AddSegment(&datapb.SegmentInfo{ID: 1001, State: commonpb.SegmentState_Growing}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet {
+		return metacache.NewBloomFilterSet()
+	})
+	wb := newWriteBufferBase(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{})
+
+	err := wb.FlushSegmentsStrict(context.Background(), []int64{1001, 1002, 1003})
+	s.ErrorIs(err, merr.ErrSegmentNotFound)
+	s.Contains(err.Error(), "1002")
+	s.Contains(err.Error(), "1003")
+	s.NotContains(err.Error(), "1001")
+	// known segments not flushed when rejected
+	segment, ok := metaCache.GetSegmentByID(1001)
+	s.Require().True(ok)
+	s.Equal(commonpb.SegmentState_Growing, segment.State())
+
+	s.NoError(wb.FlushSegmentsStrict(context.Background(), []int64{1001}))
+	segment, ok = metaCache.GetSegmentByID(1001)
+	s.Require().True(ok)
+	s.Equal(commonpb.SegmentState_Flushing, segment.State())
+}
+
+func (s *WriteBufferSuite) TestCheckpointWithFlushTsAndEmptyBuffers() {
+	wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+	wb.checkpoint = &msgpb.MsgPosition{Timestamp: 1000}
+	// buffer keeping position without any data
+	buf, err := newSegmentBuffer(1001, s.collSchema)
+	s.Require().NoError(err)
+	buf.deltaBuffer.startPos = &msgpb.MsgPosition{Timestamp: 400}
+	wb.buffers[1001] = buf
+
+	s.Run("no_flush_ts", func() {
+		s.syncMgr.EXPECT().GetEarliestPosition(s.channelName).Return(0, nil).Once()
+		s.EqualValues(400, wb.GetCheckpoint().GetTimestamp())
+	})
+
+	s.Run("flush_ts_set", func() {
+		wb.SetFlushTimestamp(800)
+		defer wb.ClearFlushTimestamp()
+		s.syncMgr.EXPECT().GetEarliestPosition(s.channelName).Return(0, nil).Once()
+		s.EqualValues(1000, wb.GetCheckpoint().GetTimestamp())
+	})
+
+	s.Run("flush_ts_set_with_data", func() {
+		wb.SetFlushTimestamp(800)
+		defer wb.ClearFlushTimestamp()
+		s.fillSegmentBuffer(wb, 1002)
+		defer delete(wb.buffers, 1002)
+		s.syncMgr.EXPECT().GetEarliestPosition(s.channelName).Return(0, nil).Once()
+		s.EqualValues(100, wb.GetCheckpoint().GetTimestamp())
+	})
+}
+
+func (s *WriteBufferSuite) TestCheckpointFromSyncManagerOnly() {
+	option := &writeBufferOption{}
+	WithCheckpointFromSyncManagerOnly()(option)
+	wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, option)
+	wb.checkpoint = &msgpb.MsgPosition{Timestamp: 1000}
+	s.fillSegmentBuffer(wb, 1001)
+
+	s.Run("use_sync_mgr_cp", func() {
+		s.syncMgr.EXPECT().GetEarliestPosition(s.channelName).Return(2001, &msgpb.MsgPosition{Timestamp: 500}).Once()
+		s.EqualValues(500, wb.GetCheckpoint().GetTimestamp())
+	})
+
+	s.Run("use_consume_cp", func() {
+		s.syncMgr.EXPECT().GetEarliestPosition(s.channelName).Return(0, nil).Once()
+		s.EqualValues(1000, wb.GetCheckpoint().GetTimestamp())
+	})
+}
+
+func (s *WriteBufferSuite) TestEmptySegmentIDs() {
+	wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+	s.Empty(wb.EmptySegmentIDs())
+
+	s.fillSegmentBuffer(wb, 1001)
+	// phantom segments created without data
+	wb.getOrCreateBuffer(1003)
+	wb.getOrCreateBuffer(1002)
+	s.Equal([]int64{1002, 1003}, wb.EmptySegmentIDs())
+}
+
+func (s *WriteBufferSuite) TestLookupDelete() {
+	dir := s.T().TempDir()
+	option := &writeBufferOption{}
+	WithDeleteIndexSpill(dir, 2)(option)
+	wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, option)
+
+	pks := lo.RepeatBy(10, func(idx int) storage.PrimaryKey { return storage.NewInt64PrimaryKey(int64(idx)) })
+	tss := lo.RepeatBy(10, func(idx int) uint64 { return uint64(100 + idx) })
+	wb.getOrCreateBuffer(1001).deltaBuffer.Buffer(pks, tss, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+
+	ts, ok, err := wb.LookupDelete(1001, storage.NewInt64PrimaryKey(3))
+	s.NoError(err)
+	s.True(ok)
+	s.EqualValues(103, ts)
+
+	_, ok, err = wb.LookupDelete(1001, storage.NewInt64PrimaryKey(20))
+	s.NoError(err)
+	s.False(ok)
+	_, ok, err = wb.LookupDelete(1002, storage.NewInt64PrimaryKey(3))
+	s.NoError(err)
+	s.False(ok)
+
+	// spilled files are removed once buffer yielded
+	_, _, delta, _, _, err := wb.yieldBuffer(1001, false)
+	s.Require().NoError(err)
+	s.EqualValues(10, delta.RowCount)
+	files, err := os.ReadDir(dir)
+	s.Require().NoError(err)
+	s.Empty(files)
+}
+
+func (s *WriteBufferSuite) TestMemorySplit() {
+	wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+	insertBytes, deltaBytes := wb.MemorySplit()
+	s.EqualValues(0, insertBytes)
+	s.EqualValues(0, deltaBytes)
+
+	// 1024 bytes insert & one int64 pk delete for each filled segment, varchar pk delete only for 1003
+	s.fillSegmentBuffer(wb, 1001)
+	s.fillSegmentBuffer(wb, 1002)
+	wb.getOrCreateBuffer(1003).deltaBuffer.Buffer([]storage.PrimaryKey{storage.NewVarCharPrimaryKey("pk")}, []typeutil.Timestamp{150},
+		&msgpb.MsgPosition{Timestamp: 150}, &msgpb.MsgPosition{Timestamp: 200})
+
+	insertBytes, deltaBytes = wb.MemorySplit()
+	s.EqualValues(2048, insertBytes)
+	s.EqualValues(16+16+10, deltaBytes)
+}
+
+func (s *WriteBufferSuite) TestFlushOnSchemaChange() {
+	params.Params.CommonCfg.EnableStorageV2.SwapTempValue("false")
+	s.metacache.EXPECT().GetSegmentByID(mock.Anything).RunAndReturn(func(segmentID int64, _ ...metacache.SegmentFilter) (*metacache.SegmentInfo, bool) {
+		return metacache.NewSegmentInfo(&datapb.SegmentInfo{ID: segmentID, State: commonpb.SegmentState_Growing}, metacache.NewBloomFilterSet()), true
+	})
+	s.metacache.EXPECT().UpdateSegments(mock.Anything, mock.Anything).Return().Maybe()
+	newSchema := proto.Clone(s.collSchema).(*schemapb.CollectionSchema)
+	newSchema.Fields = append(newSchema.Fields, &schemapb.FieldSchema{FieldID: 102, Name: "extra", DataType: schemapb.DataType_Int64})
+	s.metacache.EXPECT().UpdateSchema(newSchema).Return()
+
+	s.Run("flush_enabled", func() {
+		var synced []int64
+		syncMgr := syncmgr.NewMockSyncManager(s.T())
+		syncMgr.EXPECT().SyncData(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, task syncmgr.Task) *conc.Future[error] {
+			synced = append(synced, task.SegmentID())
+			return conc.Go(func() (error, error) { return nil, nil })
+		})
+		option := &writeBufferOption{}
+		WithFlushOnSchemaChange()(option)
+		wb := newWriteBufferBase(s.channelName, s.metacache, nil, syncMgr, option)
+		s.fillSegmentBuffer(wb, 1001)
+		s.fillSegmentBuffer(wb, 1002)
+
+		s.NoError(wb.UpdateSchema(newSchema))
+		s.Equal([]int64{1001, 1002}, synced)
+		s.Empty(wb.buffers)
+		// data buffered afterwards uses new schema
+		s.Same(newSchema, wb.getOrCreateBuffer(1001).insertBuffer.collSchema)
+	})
+
+	s.Run("flush_disabled", func() {
+		wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+		s.fillSegmentBuffer(wb, 1001)
+
+		// buffered insert data of old schema refuses schema change
+		s.ErrorIs(wb.UpdateSchema(newSchema), ErrSchemaChangeBuffered)
+		s.True(wb.HasSegment(1001))
+		s.NotSame(newSchema, wb.collSchema)
+	})
+
+	s.Run("delta_buffered", func() {
+		wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+		wb.getOrCreateBuffer(1001).deltaBuffer.Buffer([]storage.PrimaryKey{storage.NewInt64PrimaryKey(1)}, []typeutil.Timestamp{150},
+			&msgpb.MsgPosition{Timestamp: 150}, &msgpb.MsgPosition{Timestamp: 200})
+
+		s.NoError(wb.UpdateSchema(newSchema))
+		s.True(wb.HasSegment(1001))
+		s.Same(newSchema, wb.collSchema)
+	})
+
+	s.Run("closed", func() {
+		wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+		s.NoError(wb.Close(false))
+		s.ErrorIs(wb.UpdateSchema(newSchema), ErrBufferClosed)
+	})
+}
+
+func (s *WriteBufferSuite) TestSyncBacklog() {
+	now := time.Now()
+	syncMgr := syncmgr.NewMockSyncManager(s.T())
+	wb := newWriteBufferBase(s.channelName, s.metacache, nil, syncMgr, &writeBufferOption{})
+
+	s.Run("no_backlog", func() {
+		syncMgr.EXPECT().GetSegmentPositions(s.channelName).Return(map[int64]*msgpb.MsgPosition{}).Once()
+		s.Empty(wb.SyncBacklog())
+	})
+
+	s.Run("in_flight_segments", func() {
+		syncMgr.EXPECT().GetSegmentPositions(s.channelName).Return(map[int64]*msgpb.MsgPosition{
+			1001: {ChannelName: s.channelName, Timestamp: tsoutil.ComposeTSByTime(now.Add(-time.Minute), 0)},
+			1002: {ChannelName: s.channelName, Timestamp: tsoutil.ComposeTSByTime(now.Add(-time.Hour), 0)},
+			1003: {ChannelName: s.channelName, Timestamp: tsoutil.ComposeTSByTime(now.Add(time.Hour), 0)},
+		}).Once()
+
+		backlog := wb.SyncBacklog()
+		s.Equal([]int64{1002, 1001, 1003}, lo.Map(backlog, func(entry SyncBacklogEntry, _ int) int64 { return entry.SegmentID }))
+		s.GreaterOrEqual(backlog[0].Age, time.Hour)
+		s.GreaterOrEqual(backlog[1].Age, time.Minute)
+		s.Less(backlog[1].Age, time.Hour)
+		// position ahead of local clock
+		s.Zero(backlog[2].Age)
+	})
+}
+
+func (s *WriteBufferSuite) TestCheckpointComparator() {
+	compareMsgID := func(a, b *msgpb.MsgPosition) int {
+		if ts := compareTimestamp(a, b); ts != 0 {
+			return ts
+		}
+		return bytes.Compare(a.GetMsgID(), b.GetMsgID())
+	}
+	wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{
+		checkpointComparator: compareMsgID,
+	})
+	wb.checkpoint = &msgpb.MsgPosition{Timestamp: 1000}
+	for segmentID, msgID := range map[int64][]byte{1001: {3}, 1002: {2}, 1003: {4}} {
+		buf, err := newSegmentBuffer(segmentID, s.collSchema)
+		s.Require().NoError(err)
+		buf.insertBuffer.startPos = &msgpb.MsgPosition{Timestamp: 100, MsgID: msgID}
+		wb.buffers[segmentID] = buf
+	}
+
+	s.Run("tie_broken_in_buffers", func() {
+		s.syncMgr.EXPECT().GetEarliestPosition(s.channelName).Return(0, nil).Once()
+		checkpoint := wb.GetCheckpoint()
+		s.Equal([]byte{2}, checkpoint.GetMsgID())
+	})
+
+	s.Run("tie_broken_with_sync_manager", func() {
+		s.syncMgr.EXPECT().GetEarliestPosition(s.channelName).Return(2001, &msgpb.MsgPosition{Timestamp: 100, MsgID: []byte{1}}).Once()
+		checkpoint := wb.GetCheckpoint()
+		s.Equal([]byte{1}, checkpoint.GetMsgID())
+	})
+}
+
+func (s *WriteBufferSuite) TestWarmupBloomFilters() {
+	metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+		Schema: s.collSchema,
+		Vchan: &datapb.VchannelInfo{
+			CollectionID: s.collID,
+			ChannelName:  s.channelName,
+		},
+	}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+	metaCache.AddSegment(&datapb.SegmentInfo{ID: 1001, State: commonpb.SegmentState_Growing},
+		func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+
+	// fake storage holding persisted stats of segment 1001
+	storedPks := map[int64][]int64{1001: {1, 2, 3}}
+	loader := func(_ context.Context, segmentID int64) ([]*storage.PkStatistics, error) {
+		pks, ok := storedPks[segmentID]
+		if !ok {
+			return nil, merr.WrapErrSegmentNotFound(segmentID)
+		}
+		stats := &storage.PkStatistics{
+			PkFilter: bloom.NewWithEstimates(storage.BloomFilterSize, storage.MaxBloomFalsePositive),
+		}
+		if err := stats.UpdatePKRange(&storage.Int64FieldData{Data: pks}); err != nil {
+			return nil, err
+		}
+		return []*storage.PkStatistics{stats}, nil
+	}
+
+	s.Run("normal_warmup", func() {
+		wb := newWriteBufferBase(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{statsLoader: loader})
+		s.fillSegmentBuffer(wb, 1001)
+		// segment not in meta shall be skipped
+		s.fillSegmentBuffer(wb, 1002)
+
+		segment, ok := metaCache.GetSegmentByID(1001)
+		s.Require().True(ok)
+		s.False(segment.GetBloomFilterSet().PkExists(storage.NewInt64PrimaryKey(2)))
+
+		s.NoError(wb.WarmupBloomFilters(context.Background()))
+		for _, pk := range storedPks[1001] {
+			s.True(segment.GetBloomFilterSet().PkExists(storage.NewInt64PrimaryKey(pk)))
+		}
+	})
+
+	s.Run("no_loader", func() {
+		wb := newWriteBufferBase(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{})
+		s.Error(wb.WarmupBloomFilters(context.Background()))
+	})
+
+	s.Run("load_failed", func() {
+		wb := newWriteBufferBase(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{
+			statsLoader: func(_ context.Context, _ int64) ([]*storage.PkStatistics, error) {
+				return nil, merr.WrapErrServiceInternal("mocked")
+			},
+		})
+		s.fillSegmentBuffer(wb, 1001)
+		s.ErrorIs(wb.WarmupBloomFilters(context.Background()), merr.ErrServiceInternal)
+	})
+}
+
+func (s *WriteBufferSuite) TestSyncManagerEarliestPosition() {
+	wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+
+	pos := &msgpb.MsgPosition{ChannelName: s.channelName, Timestamp: 100}
+	s.syncMgr.EXPECT().GetEarliestPosition(s.channelName).Return(1001, pos).Once()
+	segmentID, position := wb.SyncManagerEarliestPosition()
+	s.EqualValues(1001, segmentID)
+	s.Equal(pos, position)
+
+	s.syncMgr.EXPECT().GetEarliestPosition(s.channelName).Return(0, nil).Once()
+	segmentID, position = wb.SyncManagerEarliestPosition()
+	s.EqualValues(0, segmentID)
+	s.Nil(position)
+}
+
+func (s *WriteBufferSuite) TestExportCheckpoint() {
+	wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+	wb.checkpoint = &msgpb.MsgPosition{ChannelName: s.channelName, MsgID: []byte{1, 2, 3}, Timestamp: 300}
+	s.fillSegmentBuffer(wb, 1001)
+	s.fillSegmentBuffer(wb, 1002)
+	wb.buffers[1002].insertBuffer.startPos = &msgpb.MsgPosition{ChannelName: s.channelName, MsgID: []byte{4}, Timestamp: 50}
+
+	data, err := wb.ExportCheckpoint()
+	s.Require().NoError(err)
+
+	s.Run("round_trip", func() {
+		s.metacache.EXPECT().GetSegmentByID(mock.Anything).RunAndReturn(func(segmentID int64, _ ...metacache.SegmentFilter) (*metacache.SegmentInfo, bool) {
+			return metacache.NewSegmentInfo(&datapb.SegmentInfo{ID: segmentID}, metacache.NewBloomFilterSet()), true
+		})
+		imported := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+		err := imported.ImportCheckpoint(data)
+		s.Require().NoError(err)
+
+		s.EqualValues(300, imported.checkpoint.GetTimestamp())
+		s.Equal([]byte{1, 2, 3}, imported.checkpoint.GetMsgID())
+		s.EqualValues(100, imported.buffers[1001].EarliestPosition().GetTimestamp())
+		s.EqualValues(50, imported.buffers[1002].EarliestPosition().GetTimestamp())
+
+		reexported, err := imported.ExportCheckpoint()
+		s.Require().NoError(err)
+		s.Equal(data, reexported)
+	})
+
+	s.Run("segment_not_in_meta", func() {
+		metaCache := metacache.NewMetaCache(&datapb.ChannelWatchInfo{
+			Schema: s.collSchema,
+			Vchan: &datapb.VchannelInfo{
+				CollectionID: s.collID,
+				ChannelName:  s.channelName,
+			},
+		}, func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+		metaCache.AddSegment(&datapb.SegmentInfo{ID: 1001, State: commonpb.SegmentState_Growing},
+			func(_ *datapb.SegmentInfo) *metacache.BloomFilterSet { return metacache.NewBloomFilterSet() })
+		imported := newWriteBufferBase(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{})
+		s.Require().NoError(imported.ImportCheckpoint(data))
+
+		s.EqualValues(100, imported.buffers[1001].EarliestPosition().GetTimestamp())
+		s.False(imported.HasSegment(1002))
+	})
+
+	s.Run("other_channel", func() {
+		imported := newWriteBufferBase("by-dev-rootcoord-dml_1v0", s.metacache, nil, s.syncMgr, &writeBufferOption{})
+		err := imported.ImportCheckpoint(data)
+		s.ErrorIs(err, merr.ErrParameterInvalid)
+	})
+
+	s.Run("bad_data", func() {
+		imported := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+		err := imported.ImportCheckpoint([]byte("{bad"))
+		s.Error(err)
+	})
+}
+
+func (s *WriteBufferSuite) TestFieldMemorySize() {
+	collSchema := &schemapb.CollectionSchema{
+		Name: "wb_field_size_collection",
+		Fields: []*schemapb.FieldSchema{
+			{FieldID: common.RowIDField, Name: common.RowIDFieldName, DataType: schemapb.DataType_Int64},
+			{FieldID: common.TimeStampField, Name: common.TimeStampFieldName, DataType: schemapb.DataType_Int64},
+			{FieldID: 100, DataType: schemapb.DataType_Int64, IsPrimaryKey: true, Name: "pk"},
+			{FieldID: 101, DataType: schemapb.DataType_FloatVector, Name: "vector", TypeParams: []*commonpb.KeyValuePair{
+				{Key: common.DimKey, Value: "4"},
+			}},
+			{FieldID: 102, DataType: schemapb.DataType_VarChar, Name: "text", TypeParams: []*commonpb.KeyValuePair{
+				{Key: common.MaxLengthKey, Value: "65535"},
+			}},
+		},
+	}
+	metaCache := metacache.NewMockMetaCache(s.T())
+	metaCache.EXPECT().Schema().Return(collSchema).Maybe()
+	metaCache.EXPECT().Collection().Return(s.collID).Maybe()
+	wb := newWriteBufferBase(s.channelName, metaCache, nil, s.syncMgr, &writeBufferOption{})
+
+	appendRows := func(segmentID int64, text string) {
+		buf := wb.getOrCreateBuffer(segmentID)
+		for i := 0; i < 10; i++ {
+			err := buf.insertBuffer.buffer.Append(map[storage.FieldID]interface{}{
+				common.RowIDField:     int64(i),
+				common.TimeStampField: int64(i),
+				100:                   int64(i),
+				101:                   []float32{1, 2, 3, 4},
+				102:                   text,
+			})
+			s.Require().NoError(err)
+		}
+	}
+	// varchar heavy
+	appendRows(1001, strings.Repeat("a", 1024))
+	// vector heavy
+	appendRows(1002, "")
+
+	sizes := wb.FieldMemorySize(1001)
+	s.Greater(sizes[102], sizes[101])
+	s.Greater(sizes[101], int64(0))
+
+	sizes = wb.FieldMemorySize(1002)
+	s.Greater(sizes[101], sizes[102])
+
+	s.Empty(wb.FieldMemorySize(1003))
+}
+
+func (s *WriteBufferSuite) TestInsertDeleteRatio() {
+	wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+
+	bufferDelete := func(segmentID int64, count int) {
+		pks := lo.RepeatBy(count, func(i int) storage.PrimaryKey { return storage.NewInt64PrimaryKey(int64(i)) })
+		tss := lo.RepeatBy(count, func(i int) typeutil.Timestamp { return typeutil.Timestamp(100 + i) })
+		wb.getOrCreateBuffer(segmentID).deltaBuffer.Buffer(pks, tss, &msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	}
+	bufferInsert := func(segmentID int64, rows int64) {
+		wb.getOrCreateBuffer(segmentID).insertBuffer.UpdateStatistics(rows, rows*64, TimeRange{timestampMin: 100, timestampMax: 200},
+			&msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	}
+
+	// delete heavy
+	bufferInsert(1000, 10)
+	bufferDelete(1000, 40)
+	s.InDelta(0.25, wb.InsertDeleteRatio(1000), 1e-9)
+
+	// insert heavy
+	bufferInsert(1001, 100)
+	bufferDelete(1001, 4)
+	s.InDelta(25, wb.InsertDeleteRatio(1001), 1e-9)
+
+	bufferInsert(1002, 10)
+	s.True(math.IsInf(wb.InsertDeleteRatio(1002), 1))
+
+	s.EqualValues(-1, wb.InsertDeleteRatio(1003))
+}
+
+func (s *WriteBufferSuite) TestDeleteTimeRange() {
+	wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+
+	buf := wb.getOrCreateBuffer(1000)
+	buf.deltaBuffer.Buffer([]storage.PrimaryKey{storage.NewInt64PrimaryKey(1), storage.NewInt64PrimaryKey(2)}, []typeutil.Timestamp{150, 120},
+		&msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	buf.deltaBuffer.Buffer([]storage.PrimaryKey{storage.NewInt64PrimaryKey(3)}, []typeutil.Timestamp{180},
+		&msgpb.MsgPosition{Timestamp: 200}, &msgpb.MsgPosition{Timestamp: 300})
+
+	tr, ok := wb.DeleteTimeRange(1000)
+	s.Require().True(ok)
+	s.EqualValues(120, tr.Min())
+	s.EqualValues(180, tr.Max())
+
+	// insert only segment
+	wb.getOrCreateBuffer(1001).insertBuffer.UpdateStatistics(10, 1024, TimeRange{timestampMin: 100, timestampMax: 200},
+		&msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+	tr, ok = wb.DeleteTimeRange(1001)
+	s.False(ok)
+	s.Nil(tr)
+
+	tr, ok = wb.DeleteTimeRange(1002)
+	s.False(ok)
+	s.Nil(tr)
+}
+
+func (s *WriteBufferSuite) TestOldestUnsyncedAge() {
+	wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+
+	now := time.Now()
+	nowTs := tsoutil.ComposeTSByTime(now, 0)
+	s.EqualValues(0, wb.OldestUnsyncedAge(nowTs))
+
+	oldTs := tsoutil.ComposeTSByTime(now.Add(-time.Minute), 0)
+	wb.getOrCreateBuffer(1000).insertBuffer.UpdateStatistics(10, 1024, TimeRange{timestampMin: oldTs, timestampMax: nowTs},
+		&msgpb.MsgPosition{Timestamp: oldTs}, &msgpb.MsgPosition{Timestamp: nowTs})
+	recentTs := tsoutil.ComposeTSByTime(now.Add(-time.Second), 0)
+	wb.getOrCreateBuffer(1001).deltaBuffer.Buffer([]storage.PrimaryKey{storage.NewInt64PrimaryKey(1)}, []typeutil.Timestamp{recentTs},
+		&msgpb.MsgPosition{Timestamp: recentTs}, &msgpb.MsgPosition{Timestamp: nowTs})
+
+	s.Equal(time.Minute, wb.OldestUnsyncedAge(nowTs))
+	// position after now
+	s.EqualValues(0, wb.OldestUnsyncedAge(tsoutil.ComposeTSByTime(now.Add(-2*time.Minute), 0)))
+}
+
+func (s *WriteBufferSuite) TestThresholdMultiplier() {
+	wb := newWriteBufferBase(s.channelName, s.metacache, nil, s.syncMgr, &writeBufferOption{})
+
+	buf := wb.getOrCreateBuffer(1000)
+	buf.insertBuffer.rowLimit = 10
+	buf.insertBuffer.UpdateStatistics(10, 1024, TimeRange{timestampMin: 100, timestampMax: 200},
+		&msgpb.MsgPosition{Timestamp: 100}, &msgpb.MsgPosition{Timestamp: 200})
+
+	policy := GetFullBufferPolicy()
+	buffers := []*segmentBuffer{buf}
+	s.ElementsMatch([]int64{1000}, policy.SelectSegments(buffers, 0))
+
+	wb.SetThresholdMultiplier(2)
+	s.Empty(policy.SelectSegments(buffers, 0))
+
+	// non-positive multiplier is ignored
+	wb.SetThresholdMultiplier(0)
+	s.Empty(policy.SelectSegments(buffers, 0))
+
+	buf.insertBuffer.UpdateStatistics(10, 1024, TimeRange{timestampMin: 200, timestampMax: 300},
+		&msgpb.MsgPosition{Timestamp: 200}, &msgpb.MsgPosition{Timestamp: 300})
+	s.ElementsMatch([]int64{1000}, policy.SelectSegments(buffers, 0))
+
+	wb.SetThresholdMultiplier(4)
+	s.Empty(policy.SelectSegments(buffers, 0))
+	wb.ResetThresholds()
+	s.ElementsMatch([]int64{1000}, policy.SelectSegments(buffers, 0))
+}
+
 func TestWriteBufferBase(t *testing.T) {
 	suite.Run(t, new(WriteBufferSuite))
 }
@@ -2656,6 +2656,9 @@ type dataNodeConfig struct {
 	ChannelWorkPoolSize ParamItem `refreshable:"true"`
 
 	UpdateChannelCheckpointMaxParallel ParamItem `refreshable:"true"`
+
+	// write buffer
+	WriteBufferTracingEnable ParamItem `refreshable:"true"`
 }
 
 func (p *dataNodeConfig) init(base *BaseTable) {
@@ -2881,6 +2884,15 @@ func (p *dataNodeConfig) init(base *BaseTable) {
 		DefaultValue: "1000",
 	}
 	p.UpdateChannelCheckpointMaxParallel.Init(base.mgr)
+
+	p.WriteBufferTracingEnable = ParamItem{
+		Key:          "datanode.writeBuffer.tracing.enable",
+		Version:      "2.4.0",
+		PanicIfEmpty: false,
+		DefaultValue: "false",
+		Doc:          "Whether to emit trace spans for write buffer operations",
+	}
+	p.WriteBufferTracingEnable.Init(base.mgr)
 }
 
 // /////////////////////////////////////////////////////////////////////////////
@@ -404,6 +404,8 @@ func TestComponentParam(t *testing.T) {
 		updateChannelCheckpointMaxParallel := Params.UpdateChannelCheckpointMaxParallel.GetAsInt()
 		t.Logf("updateChannelCheckpointMaxParallel: %d", updateChannelCheckpointMaxParallel)
 		assert.Equal(t, 1000, Params.UpdateChannelCheckpointMaxParallel.GetAsInt())
+
+		assert.False(t, Params.WriteBufferTracingEnable.GetAsBool())
 	})
 
 	t.Run("test indexNodeConfig", func(t *testing.T) {